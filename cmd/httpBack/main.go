@@ -5,11 +5,16 @@ import (
 	"os"
 	"test-task/internal/app"
 	"test-task/internal/config"
+	"test-task/internal/logging"
 )
 
 func main() {
 	cfg := config.MustLoad()
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		slog.Error("Failed to configure logging", "error", err)
+		os.Exit(1)
+	}
 	slog.SetDefault(logger)
 	app := app.NewApp(cfg)
 	app.Run()
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"test-task/internal/models"
 	"test-task/internal/services"
@@ -13,26 +14,77 @@ import (
 
 	"test-task/internal/config"
 	"test-task/internal/handlers"
+	"test-task/internal/metrics"
 	"test-task/internal/storage"
 )
 
+const (
+	// WriteTimeout API; маршруты с WriteDeadline (/batch) живут дольше
+	apiWriteTimeout = 10 * time.Second
+	// ключ идемпотентности "в работе" считается брошенным после самого
+	// долгого дедлайна обработчиков плюс этот запас
+	idempotencyStaleMargin = 30 * time.Second
+)
+
 type App struct {
 	cfg      *config.Config
 	server   *http.Server
 	services *Services
 	storages *Storages
+
+	// отдельный листенер /metrics, наружу через Caddy не публикуется
+	metricsServer *http.Server
+
+	workers     []Worker
+	stopWorkers context.CancelFunc
+	workersDone sync.WaitGroup
+
+	stopTracing func(ctx context.Context) error
+}
+
+// Worker - фоновая задача, работает до отмены ctx
+type Worker interface {
+	Run(ctx context.Context)
 }
 
 type Services struct {
 	TeamManag        services.TeamManager
 	UserManag        services.UserManager
 	PullRequestManag services.PullRequestManager
+	GitHubHook       services.WebhookManager
+	GitLabHook       services.WebhookManager
+	Subscriptions    services.SubscriptionManager
+	Events           *services.EventStream
+	ChatTargets      services.ChatTargetManager
+	SLA              services.SLAManager
+	StalePRs         services.StalePRManager
+	Audit            services.AuditReader
+	Auth             services.Authenticator
+	APITokens        services.APITokenManager
+	SCIM             services.SCIMManager
+	Idempotency      services.IdempotencyManager
+	Batch            services.BatchRunner
+	Search           services.Searcher
+	Outbox           services.OutboxReader
 }
 
 type Storages struct {
-	PullReq storage.PullReqStorage
-	Team    storage.TeamStorage
-	User    storage.UserStorage
+	PullReq       storage.PullReqStorage
+	Team          storage.TeamStorage
+	User          storage.UserStorage
+	CodeOwners    storage.CodeOwnersStorage
+	Webhooks      storage.WebhookDeliveryStorage
+	Subscriptions storage.SubscriptionStorage
+	Outbox        storage.OutboxStorage
+	ChatTargets   storage.ChatTargetStorage
+	SLA           storage.SLAStorage
+	Stale         storage.StaleStorage
+	Audit         storage.AuditStorage
+	Timeline      storage.TimelineStorage
+	APITokens     storage.APITokenStorage
+	SCIM          storage.SCIMStorage
+	Idempotency   storage.IdempotencyStorage
+	Search        storage.SearchStorage
 }
 
 func NewApp(cfg *config.Config) *App {
@@ -41,6 +93,10 @@ func NewApp(cfg *config.Config) *App {
 		cfg: cfg,
 	}
 
+	if err := app.initTracing(); err != nil {
+		slog.Error("Failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
 	app.initStorages()
 	app.initServices()
 	app.initHTTP()
@@ -64,21 +120,240 @@ func (a *App) initStorages() {
 	}
 
 	a.storages = &Storages{
-		PullReq: storage.NewPullRequestPostgresStorage(poolPG),
-		Team:    storage.NewTeamPostgresStorage(poolPG),
-		User:    storage.NewUserPostgresStorage(poolPG),
+		PullReq:       storage.NewPullRequestPostgresStorage(poolPG),
+		Team:          storage.NewTeamPostgresStorage(poolPG),
+		User:          storage.NewUserPostgresStorage(poolPG),
+		CodeOwners:    storage.NewCodeOwnersPostgresStorage(poolPG),
+		Webhooks:      storage.NewWebhookPostgresStorage(poolPG),
+		Subscriptions: storage.NewSubscriptionPostgresStorage(poolPG),
+		Outbox:        storage.NewOutboxPostgresStorage(poolPG),
+		ChatTargets:   storage.NewChatTargetPostgresStorage(poolPG),
+		SLA:           storage.NewSLAPostgresStorage(poolPG),
+		Stale:         storage.NewStalePostgresStorage(poolPG),
+		Audit:         storage.NewAuditPostgresStorage(poolPG),
+		Timeline:      storage.NewTimelinePostgresStorage(poolPG),
+		APITokens:     storage.NewAPITokenPostgresStorage(poolPG),
+		SCIM:          storage.NewSCIMPostgresStorage(poolPG),
+		Idempotency:   storage.NewIdempotencyPostgresStorage(poolPG, max(apiWriteTimeout, a.cfg.BatchTimeout)+idempotencyStaleMargin),
+		Search:        storage.NewSearchPostgresStorage(poolPG),
 	}
+
+	dbMetrics := storage.NewMetricsPostgresStorage(poolPG)
+	metrics.RegisterCollector(metrics.Registry, "db_pool", dbMetrics.CollectPool)
+	metrics.RegisterCollector(metrics.Registry, "domain", dbMetrics.CollectDomain)
 }
 
 func (a *App) initServices() {
+	authz := services.NewAuthorizer(a.storages.User)
+
+	outboundHooks := services.NewOutboundWebhookService(a.storages.Subscriptions, authz, services.OutboundWebhookConfig{
+		MaxAttempts:         a.cfg.OutboundWebhookMaxAttempts,
+		BaseBackoff:         a.cfg.OutboundWebhookBackoff,
+		MaxBackoff:          a.cfg.OutboundWebhookMaxBackoff,
+		Timeout:             a.cfg.OutboundWebhookTimeout,
+		PollInterval:        a.cfg.OutboundWebhookPollInterval,
+		AllowPrivateTargets: a.cfg.OutboundWebhookAllowPrivate,
+	})
+	a.workers = append(a.workers, outboundHooks)
+
+	events := services.NewEventStream(authz, a.cfg.SSEReplaySize, a.cfg.SSEClientBuffer)
+
+	chat := a.initChat(authz)
+	a.workers = append(a.workers, chat)
+
+	// без SMTP_HOST дайджест не рассылается
+	if a.cfg.SMTPHost != "" {
+		a.workers = append(a.workers, a.initDigest())
+	}
+
+	a.initOutbox(outboundHooks, events, chat)
+
+	pullRequestManag := services.NewPullRequestService(
+		a.storages.PullReq,
+		a.storages.User,
+		a.storages.Team,
+		a.storages.CodeOwners,
+		a.storages.SLA,
+		a.storages.Outbox,
+		a.storages.Audit,
+		a.storages.Timeline,
+		authz)
+
+	sla := services.NewSLAService(
+		a.storages.SLA,
+		a.storages.PullReq,
+		a.storages.Team,
+		pullRequestManag,
+		a.storages.Outbox,
+		a.storages.Audit,
+		a.storages.Timeline,
+		authz,
+		a.cfg.SLACheckInterval)
+	a.workers = append(a.workers, sla)
+
+	stalePRs := services.NewStalePRService(
+		a.storages.Stale,
+		a.storages.PullReq,
+		a.storages.Team,
+		a.storages.Outbox,
+		a.storages.Audit,
+		a.storages.Timeline,
+		authz,
+		a.cfg.StaleCheckInterval)
+	a.workers = append(a.workers, stalePRs)
+
+	if a.cfg.AuthBootstrapToken == "" {
+		slog.Warn("AUTH_BOOTSTRAP_TOKEN is empty, only tokens already in the database will be accepted")
+	}
+	var jwt *services.JWTAuthenticator
+	if a.cfg.JWTJWKS != "" {
+		jwt = services.NewJWTAuthenticator(a.storages.User, services.JWTConfig{
+			JWKSSource:      a.cfg.JWTJWKS,
+			Issuer:          a.cfg.JWTIssuer,
+			Audience:        a.cfg.JWTAudience,
+			UserClaim:       a.cfg.JWTUserClaim,
+			DefaultScopes:   a.cfg.JWTDefaultScopes,
+			AdminClaim:      a.cfg.JWTAdminClaim,
+			AdminValues:     a.cfg.JWTAdminValues,
+			Leeway:          a.cfg.JWTLeeway,
+			RefreshInterval: a.cfg.JWTJWKSRefresh,
+			Timeout:         a.cfg.JWTJWKSTimeout,
+		})
+	}
+	auth := services.NewAuthService(a.storages.APITokens, a.storages.User, a.cfg.AuthBootstrapToken, jwt)
+
+	idempotency := services.NewIdempotencyService(a.storages.Idempotency, a.cfg.IdempotencyTTL, a.cfg.IdempotencyCleanupInterval)
+	a.workers = append(a.workers, idempotency)
+
+	teamManag := services.NewTeamService(
+		a.storages.Team,
+		a.storages.User,
+		a.storages.CodeOwners,
+		a.storages.Outbox,
+		a.storages.Audit,
+		authz,
+		a.storages.PullReq,
+		pullRequestManag,
+		a.cfg.SCIMDefaultTeam)
+
+	userManag := services.NewUserService(
+		a.storages.User,
+		a.storages.Outbox,
+		a.storages.Audit,
+		authz,
+		a.storages.PullReq,
+		pullRequestManag)
+
 	a.services = &Services{
-		TeamManag: services.NewTeamService(a.storages.Team),
-		UserManag: services.NewUserService(a.storages.User),
-		PullRequestManag: services.NewPullRequestService(
-			a.storages.PullReq,
-			a.storages.User,
-			a.storages.Team),
+		TeamManag:        teamManag,
+		UserManag:        userManag,
+		PullRequestManag: pullRequestManag,
+		GitHubHook: services.NewGitHubWebhookService(
+			pullRequestManag,
+			a.storages.Webhooks,
+			a.cfg.GitHubWebhookSecret,
+			a.cfg.GitHubUserMap),
+		GitLabHook: services.NewGitLabWebhookService(
+			pullRequestManag,
+			a.storages.Webhooks,
+			a.cfg.GitLabWebhookToken,
+			a.cfg.GitLabUserMap),
+		Subscriptions: outboundHooks,
+		Events:        events,
+		ChatTargets:   chat,
+		SLA:           sla,
+		StalePRs:      stalePRs,
+		Audit:         services.NewAuditService(a.storages.Audit, a.storages.PullReq, authz),
+		Auth:          auth,
+		APITokens:     auth,
+		Idempotency:   idempotency,
+		Batch:         services.NewBatchService(a.storages.PullReq, a.cfg.BatchMaxOperations),
+		SCIM:          services.NewSCIMService(a.storages.SCIM, a.storages.User, userManag, a.storages.Audit, authz, a.cfg.SCIMDefaultTeam),
+		Search:        services.NewSearchService(a.storages.Search),
+		Outbox:        services.NewOutboxService(a.storages.Outbox, authz),
+	}
+}
+
+func (a *App) initChat(authz *services.Authorizer) *services.ChatNotificationService {
+	loc, err := time.LoadLocation(a.cfg.ChatTimezone)
+	if err != nil {
+		slog.Error("Invalid chat timezone", "timezone", a.cfg.ChatTimezone, "error", err)
+		os.Exit(1)
+	}
+
+	chat, err := services.NewChatNotificationService(a.storages.ChatTargets, authz, map[string]services.Notifier{
+		models.ChatFormatSlack:      services.NewSlackNotifier(a.cfg.ChatTimeout, a.cfg.ChatAllowPrivate),
+		models.ChatFormatMattermost: services.NewMattermostNotifier(a.cfg.ChatTimeout, a.cfg.ChatAllowPrivate, a.cfg.ChatUsername),
+	}, services.ChatNotificationConfig{
+		BatchWindow:        a.cfg.ChatBatchWindow,
+		QuietHours:         a.cfg.ChatQuietHours,
+		Location:           loc,
+		AssignedTemplate:   a.cfg.ChatAssignedTemplate,
+		ReassignedTemplate: a.cfg.ChatReassignedTemplate,
+		ReminderTemplate:   a.cfg.ChatReminderTemplate,
+		StaleTemplate:      a.cfg.ChatStaleTemplate,
+
+		AllowPrivateTargets: a.cfg.ChatAllowPrivate,
+	})
+	if err != nil {
+		slog.Error("Failed to create chat notifier", "error", err)
+		os.Exit(1)
+	}
+	return chat
+}
+
+func (a *App) initDigest() *services.DigestService {
+	loc, err := time.LoadLocation(a.cfg.DigestTimezone)
+	if err != nil {
+		slog.Error("Invalid digest timezone", "timezone", a.cfg.DigestTimezone, "error", err)
+		os.Exit(1)
+	}
+
+	mailer := services.NewSMTPMailer(services.SMTPConfig{
+		Host:     a.cfg.SMTPHost,
+		Port:     a.cfg.SMTPPort,
+		Username: a.cfg.SMTPUsername,
+		Password: a.cfg.SMTPPassword,
+		From:     a.cfg.SMTPFrom,
+		Timeout:  a.cfg.SMTPTimeout,
+	})
+
+	digest, err := services.NewDigestService(a.storages.User, a.storages.PullReq, mailer, services.DigestConfig{
+		SendAt:   a.cfg.DigestSendAt,
+		Location: loc,
+	})
+	if err != nil {
+		slog.Error("Failed to create digest service", "error", err)
+		os.Exit(1)
+	}
+	return digest
+}
+
+func (a *App) initOutbox(outboundHooks *services.OutboundWebhookService, events *services.EventStream, chat *services.ChatNotificationService) {
+	// SSE всегда слушает outbox, остальные sink'и - по конфигу
+	sinks := []OutboxSink{events}
+	for _, name := range a.cfg.OutboxSinks {
+		switch name {
+		case "log":
+			sinks = append(sinks, LogSink{})
+		case "webhook":
+			sinks = append(sinks, NewWebhookSink(outboundHooks))
+		case "chat":
+			sinks = append(sinks, chat)
+		case "nats":
+			sink, err := NewNATSSink(a.cfg.NATSURL, a.cfg.NATSSubjectPrefix)
+			if err != nil {
+				slog.Error("Failed to create NATS sink", "error", err)
+				os.Exit(1)
+			}
+			sinks = append(sinks, sink)
+		default:
+			slog.Error("Unknown outbox sink", "sink", name)
+			os.Exit(1)
+		}
 	}
+
+	a.workers = append(a.workers, NewOutboxDispatcher(a.storages.Outbox, sinks, a.cfg.OutboxPollInterval, a.cfg.OutboxMaxAttempts))
 }
 
 func (a *App) initHTTP() {
@@ -86,6 +361,19 @@ func (a *App) initHTTP() {
 		a.services.TeamManag,
 		a.services.UserManag,
 		a.services.PullRequestManag,
+		a.services.GitHubHook,
+		a.services.GitLabHook,
+		a.services.Subscriptions,
+		a.services.Events,
+		a.services.ChatTargets,
+		a.services.SLA,
+		a.services.StalePRs,
+		a.services.Audit,
+		a.services.APITokens,
+		a.services.SCIM,
+		a.services.Batch,
+		a.services.Search,
+		a.services.Outbox,
 	)
 	if err != nil {
 		slog.Error("Failed to create handler", "error", err)
@@ -98,9 +386,20 @@ func (a *App) initHTTP() {
 		Addr:         ":" + a.cfg.ServerPort,
 		Handler:      router,
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: apiWriteTimeout,
 		IdleTimeout:  30 * time.Second,
 	}
+
+	if a.cfg.MetricsPort != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metrics.Handler(metrics.Registry, a.cfg.MetricsToken))
+		a.metricsServer = &http.Server{
+			Addr:         ":" + a.cfg.MetricsPort,
+			Handler:      metricsMux,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+	}
 }
 
 func (a *App) setupRoutes(handler *handlers.Handler) http.Handler {
@@ -110,26 +409,142 @@ func (a *App) setupRoutes(handler *handlers.Handler) http.Handler {
 		"/team/add": handler.AddTeam,
 		"/team/get": handler.GetTeam,
 
+		"/team/codeowners": handler.UploadCodeOwners,
+
+		"/team/addMember":    handler.AddTeamMember,
+		"/team/removeMember": handler.RemoveTeamMember,
+
+		"/team/sla/set":         handler.SetTeamSLA,
+		"/team/sla/get":         handler.GetTeamSLA,
+		"/team/sla/escalations": handler.ListSLAEscalations,
+
+		"/team/stalePolicy/set": handler.SetStalePolicy,
+		"/team/stalePolicy/get": handler.GetStalePolicy,
+
 		"/users/setIsActive": handler.SetIsActive,
 		"/users/getReview":   handler.GetUserReviews,
+		"/users/list":        handler.ListUsers,
+
+		"/users/setDigestOptOut": handler.SetDigestOptOut,
+		"/users/setRole":         handler.SetRole,
 
 		"/pullRequest/create":   handler.CreatePR,
 		"/pullRequest/merge":    handler.MergePR,
+		"/pullRequest/close":    handler.ClosePR,
+		"/pullRequest/reopen":   handler.ReopenPR,
 		"/pullRequest/reassign": handler.ReassignReviewer,
+		"/pullRequest/review":   handler.SubmitReview,
+		"/pullRequest/history":  handler.GetPRHistory,
+		"/pullRequest/list":     handler.ListPRs,
+
+		"/pullRequest/stale/dryRun": handler.StaleDryRun,
+
+		"/webhooks/github": handler.GitHubWebhook,
+		"/webhooks/gitlab": handler.GitLabWebhook,
+
+		"/subscriptions/add":        handler.AddSubscription,
+		"/subscriptions/list":       handler.ListSubscriptions,
+		"/subscriptions/delete":     handler.DeleteSubscription,
+		"/subscriptions/deliveries": handler.ListDeliveries,
+
+		"/events/stream": handler.EventStream,
+
+		"/audit": handler.ListAudit,
+
+		"/outbox/dead": handler.ListOutboxDead,
+
+		"/auth/tokens/issue":  handler.IssueToken,
+		"/auth/tokens/list":   handler.ListTokens,
+		"/auth/tokens/revoke": handler.RevokeToken,
+
+		"/notifications/chat/set":    handler.SetChatTarget,
+		"/notifications/chat/list":   handler.ListChatTargets,
+		"/notifications/chat/delete": handler.DeleteChatTarget,
+
+		// пакету мало общего WriteTimeout
+		"/batch": handlers.WriteDeadline(a.cfg.BatchTimeout, handler.Batch),
+
+		"/search": handler.Search,
+
+		"/scim/v2/Users":       handler.SCIMUsers,
+		"/scim/v2/Users/{id}":  handler.SCIMUser,
+		"/scim/v2/Groups":      handler.SCIMGroups,
+		"/scim/v2/Groups/{id}": handler.SCIMGroup,
 	}
 	for path, handlerFunc := range apiRoutes {
 		mux.HandleFunc(path, handlerFunc)
 	}
 
-	return mux
+	// вебхуки форджей проверяются подписью, а не токеном
+	publicRoutes := map[string]bool{
+		"/webhooks/github": true,
+		"/webhooks/gitlab": true,
+	}
+
+	// лимиты после аутентификации - ключом служит токен клиента, а не только IP
+	readLimiter := services.NewRateLimiter(services.RateLimitConfig{Rate: a.cfg.RateLimitReadRPS, Burst: a.cfg.RateLimitReadBurst})
+	writeLimiter := services.NewRateLimiter(services.RateLimitConfig{Rate: a.cfg.RateLimitWriteRPS, Burst: a.cfg.RateLimitWriteBurst})
+	// повторы CI не должны ни назначать второго ревьюера, ни падать с NOT_ASSIGNED
+	idempotentRoutes := map[string]bool{
+		"/pullRequest/create":   true,
+		"/pullRequest/merge":    true,
+		"/pullRequest/close":    true,
+		"/pullRequest/reopen":   true,
+		"/pullRequest/reassign": true,
+		"/pullRequest/review":   true,
+	}
+	idempotent := handlers.Idempotency(a.services.Idempotency, idempotentRoutes, mux)
+	limited := handlers.RateLimit(readLimiter, writeLimiter, a.cfg.RateLimitTrustedProxies, idempotent)
+
+	// до аутентификации - по IP: запросы без токена или с чужим не бесплатны,
+	// каждый стоит поиска токена в базе
+	ipLimiter := services.NewRateLimiter(services.RateLimitConfig{Rate: a.cfg.RateLimitIPRPS, Burst: a.cfg.RateLimitIPBurst})
+
+	api := handlers.RequestContext(
+		handlers.LimitBody(a.cfg.HTTPMaxBodyBytes,
+			handlers.RateLimitIP(ipLimiter, a.cfg.RateLimitTrustedProxies,
+				handlers.Authenticate(a.services.Auth, publicRoutes, limited))))
+
+	root := http.NewServeMux()
+	root.Handle("/", metrics.Instrument(mux, handlers.Trace(mux, api)))
+
+	// без METRICS_PORT /metrics на порту API, мимо API-цепочки (скрейпер не
+	// расходует лимиты), но только со своим токеном: в метриках ID
+	// пользователей
+	if a.cfg.MetricsPort == "" {
+		if a.cfg.MetricsToken == "" {
+			slog.Warn("Metrics endpoint disabled: set METRICS_PORT or METRICS_TOKEN")
+		} else {
+			root.Handle("/metrics", metrics.Handler(metrics.Registry, a.cfg.MetricsToken))
+		}
+	}
+	return root
 }
 
 func (a *App) Run() {
+	a.startWorkers()
 	go a.startServer()
 	a.waitForShutdown()
 }
 
+func (a *App) startWorkers() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopWorkers = cancel
+
+	for _, w := range a.workers {
+		a.workersDone.Add(1)
+		go func(w Worker) {
+			defer a.workersDone.Done()
+			w.Run(ctx)
+		}(w)
+	}
+}
+
 func (a *App) startServer() {
+	if a.metricsServer != nil {
+		go a.startMetricsServer()
+	}
+
 	slog.Info("Server starting", "port", a.server.Addr)
 	if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		slog.Error("Server failed", "error", err)
@@ -137,6 +552,14 @@ func (a *App) startServer() {
 	}
 }
 
+// startMetricsServer - сбой листенера метрик API не останавливает
+func (a *App) startMetricsServer() {
+	slog.Info("Metrics server starting", "port", a.metricsServer.Addr)
+	if err := a.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Error("Metrics server failed", "error", err)
+	}
+}
+
 func (a *App) waitForShutdown() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -155,5 +578,24 @@ func (a *App) shutdown() {
 		os.Exit(1)
 	}
 	slog.Info("Server stopped")
+
+	if a.metricsServer != nil {
+		if err := a.metricsServer.Shutdown(ctx); err != nil {
+			slog.Error("Failed to stop metrics server", "error", err)
+		}
+	}
+
+	a.stopWorkers()
+	a.workersDone.Wait()
+	slog.Info("Background workers stopped")
+
+	// недоотправленные спаны - после воркеров, они тоже пишут трассы
+	if a.stopTracing != nil {
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFlush()
+		if err := a.stopTracing(flushCtx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}
 	time.Sleep(3 * time.Second)
 }
@@ -0,0 +1,149 @@
+package app
+
+/*
+Диспетчер outbox:
+	1. Ждёт NOTIFY от коммита или следующего опроса
+	2. Захватывает пачку доставок (событие, sink) короткой транзакцией
+	3. Вне транзакции отдаёт каждое событие своему sink
+	4. Отмечает доставку доставленной, откладывает с экспоненциальной
+	   задержкой или, после maxAttempts попыток, переводит в DEAD
+
+Каждый sink повторяется отдельно: упавший чат не заставляет второй раз
+слать вебхуки. Пока идёт доставка, ни блокировок, ни соединения из pool
+диспетчер не держит. Захват действует lease; пачка, которая не успела
+за половину lease, обрывается - недоставленное вернётся после его
+истечения (at-least-once, получатели должны быть идемпотентны по event.ID).
+*/
+import (
+	"context"
+	"log/slog"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"time"
+)
+
+type OutboxSink interface {
+	Name() string
+	Deliver(ctx context.Context, event models.Event) error
+}
+
+type OutboxDispatcher struct {
+	storage      storage.OutboxStorage
+	sinks        map[string]OutboxSink
+	sinkNames    []string
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	maxBackoff   time.Duration
+	lease        time.Duration
+}
+
+func NewOutboxDispatcher(storage storage.OutboxStorage, sinks []OutboxSink, pollInterval time.Duration, maxAttempts int) *OutboxDispatcher {
+	d := &OutboxDispatcher{
+		storage:      storage,
+		sinks:        make(map[string]OutboxSink, len(sinks)),
+		pollInterval: pollInterval,
+		batchSize:    100,
+		maxAttempts:  maxAttempts,
+		maxBackoff:   5 * time.Minute,
+		lease:        5 * time.Minute,
+	}
+	for _, sink := range sinks {
+		d.sinks[sink.Name()] = sink
+		d.sinkNames = append(d.sinkNames, sink.Name())
+	}
+	return d
+}
+
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	notify := make(chan struct{}, 1)
+	go d.storage.ListenOutbox(ctx, notify)
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for d.dispatchBatch(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchBatch возвращает true, если пачка была полной и стоит забрать ещё
+func (d *OutboxDispatcher) dispatchBatch(ctx context.Context) bool {
+	claimedAt := time.Now()
+	deliveries, err := d.claim(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to claim outbox deliveries", "error", err)
+		}
+		return false
+	}
+
+	for _, del := range deliveries {
+		if time.Since(claimedAt) > d.lease/2 {
+			slog.Warn("Outbox batch is running out of lease, leaving the rest for retry", "sink", del.Sink, "event_id", del.Event.ID)
+			return false
+		}
+
+		if err := d.deliver(ctx, del); err != nil {
+			slog.Error("Failed to update outbox delivery", "event_id", del.Event.ID, "sink", del.Sink, "error", err)
+			return false
+		}
+	}
+
+	return len(deliveries) == d.batchSize
+}
+
+func (d *OutboxDispatcher) claim(ctx context.Context) ([]models.OutboxDelivery, error) {
+	tx, err := d.storage.OutboxBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	deliveries, err := d.storage.ClaimOutboxTx(ctx, tx, d.sinkNames, d.batchSize, d.lease)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// deliver отдаёт событие sink и записывает исход; ошибка - только от хранилища
+func (d *OutboxDispatcher) deliver(ctx context.Context, del models.OutboxDelivery) error {
+	sinkErr := d.sinks[del.Sink].Deliver(ctx, del.Event)
+	if sinkErr == nil {
+		return d.storage.MarkOutboxDeliveredTx(ctx, nil, del.OutboxID, del.Sink)
+	}
+
+	var next *time.Time
+	if del.Attempts < d.maxAttempts {
+		at := time.Now().Add(d.backoff(del.Attempts))
+		next = &at
+		slog.Warn("Outbox delivery failed", "event_id", del.Event.ID, "type", del.Event.Type, "sink", del.Sink, "attempt", del.Attempts, "error", sinkErr)
+	} else {
+		slog.Error("Outbox delivery is dead", "event_id", del.Event.ID, "type", del.Event.Type, "sink", del.Sink, "attempts", del.Attempts, "error", sinkErr)
+	}
+	return d.storage.MarkOutboxFailedTx(ctx, nil, del.OutboxID, del.Sink, sinkErr.Error(), next)
+}
+
+func (d *OutboxDispatcher) backoff(attempt int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempt && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
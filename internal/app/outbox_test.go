@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutboxTx struct {
+	pgx.Tx
+	committed bool
+}
+
+func (t *fakeOutboxTx) Commit(ctx context.Context) error   { t.committed = true; return nil }
+func (t *fakeOutboxTx) Rollback(ctx context.Context) error { return nil }
+
+type outboxMark struct {
+	sink string
+	next *time.Time
+}
+
+type fakeOutboxStorage struct {
+	storage.OutboxStorage
+	tx        *fakeOutboxTx
+	claimed   []models.OutboxDelivery
+	delivered []string
+	failed    []outboxMark
+}
+
+func (f *fakeOutboxStorage) OutboxBeginTx(ctx context.Context) (pgx.Tx, error) {
+	f.tx = &fakeOutboxTx{}
+	return f.tx, nil
+}
+
+func (f *fakeOutboxStorage) ClaimOutboxTx(ctx context.Context, tx pgx.Tx, sinks []string, limit int, lease time.Duration) ([]models.OutboxDelivery, error) {
+	return f.claimed, nil
+}
+
+func (f *fakeOutboxStorage) MarkOutboxDeliveredTx(ctx context.Context, tx pgx.Tx, outboxID int64, sink string) error {
+	f.delivered = append(f.delivered, sink)
+	return nil
+}
+
+func (f *fakeOutboxStorage) MarkOutboxFailedTx(ctx context.Context, tx pgx.Tx, outboxID int64, sink string, lastError string, availableAt *time.Time) error {
+	f.failed = append(f.failed, outboxMark{sink: sink, next: availableAt})
+	return nil
+}
+
+type fakeSink struct {
+	name     string
+	err      error
+	received []string
+}
+
+func (s *fakeSink) Name() string { return s.name }
+
+func (s *fakeSink) Deliver(ctx context.Context, event models.Event) error {
+	s.received = append(s.received, event.ID)
+	return s.err
+}
+
+func TestOutboxDispatcher_PerSinkOutcome(t *testing.T) {
+	ok := &fakeSink{name: "log"}
+	broken := &fakeSink{name: "chat", err: errors.New("chat is down")}
+	event := models.Event{ID: "e1", Type: models.EventPRMerged}
+
+	store := &fakeOutboxStorage{claimed: []models.OutboxDelivery{
+		{OutboxID: 1, Sink: "log", Event: event, Attempts: 1},
+		{OutboxID: 1, Sink: "chat", Event: event, Attempts: 1},
+	}}
+	d := NewOutboxDispatcher(store, []OutboxSink{ok, broken}, time.Second, 3)
+
+	assert.False(t, d.dispatchBatch(context.Background()))
+
+	assert.True(t, store.tx.committed, "claim must be committed before delivery")
+	assert.Equal(t, []string{"e1"}, ok.received)
+	assert.Equal(t, []string{"e1"}, broken.received)
+	assert.Equal(t, []string{"log"}, store.delivered)
+	require.Len(t, store.failed, 1)
+	assert.Equal(t, "chat", store.failed[0].sink)
+	assert.NotNil(t, store.failed[0].next)
+}
+
+func TestOutboxDispatcher_DeadAfterMaxAttempts(t *testing.T) {
+	broken := &fakeSink{name: "webhook", err: errors.New("boom")}
+	store := &fakeOutboxStorage{claimed: []models.OutboxDelivery{
+		{OutboxID: 1, Sink: "webhook", Event: models.Event{ID: "e1"}, Attempts: 3},
+	}}
+	d := NewOutboxDispatcher(store, []OutboxSink{broken}, time.Second, 3)
+
+	d.dispatchBatch(context.Background())
+
+	require.Len(t, store.failed, 1)
+	assert.Nil(t, store.failed[0].next, "last attempt must move delivery to DEAD")
+}
@@ -0,0 +1,150 @@
+package app
+
+/*
+Sink'и диспетчера outbox:
+	1. log     - пишет событие в slog
+	2. webhook - ставит доставки в очередь исходящих вебхуков
+	3. nats    - публикует в NATS (минимальный клиент протокола: CONNECT, PUB, PING/PONG)
+
+NATS-клиент держит одно соединение и после каждой публикации ждёт PONG -
+так сервер подтверждает, что PUB принят. При ошибке соединение
+закрывается и поднимается заново на следующем событии.
+*/
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"test-task/internal/models"
+	"time"
+)
+
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Deliver(ctx context.Context, event models.Event) error {
+	slog.Info("Domain event", "event_id", event.ID, "type", event.Type, "occurred_at", event.OccurredAt)
+	return nil
+}
+
+type webhookEnqueuer interface {
+	Enqueue(ctx context.Context, event models.Event) error
+}
+
+type WebhookSink struct {
+	hooks webhookEnqueuer
+}
+
+func NewWebhookSink(hooks webhookEnqueuer) *WebhookSink {
+	return &WebhookSink{hooks: hooks}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Deliver(ctx context.Context, event models.Event) error {
+	return s.hooks.Enqueue(ctx, event)
+}
+
+type NATSSink struct {
+	addr          string
+	subjectPrefix string
+	timeout       time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewNATSSink(rawURL string, subjectPrefix string) (*NATSSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS url %q", rawURL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &NATSSink{addr: addr, subjectPrefix: subjectPrefix, timeout: 5 * time.Second}, nil
+}
+
+func (s *NATSSink) Name() string { return "nats" }
+
+func (s *NATSSink) Deliver(ctx context.Context, event models.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.publish(s.subjectPrefix+"."+event.Type, payload); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *NATSSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting: %q %v", strings.TrimSpace(line), err)
+	}
+
+	if _, err := fmt.Fprintf(conn, "CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"reviewer-service\"}\r\n"); err != nil {
+		conn.Close()
+		return err
+	}
+
+	s.conn = conn
+	s.reader = reader
+	return nil
+}
+
+func (s *NATSSink) publish(subject string, payload []byte) error {
+	s.conn.SetDeadline(time.Now().Add(s.timeout))
+
+	if _, err := fmt.Fprintf(s.conn, "PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload); err != nil {
+		return err
+	}
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := fmt.Fprint(s.conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", line)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"test-task/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type natsPub struct {
+	subject string
+	payload []byte
+}
+
+// fakeNATSServer понимает ровно то, что шлёт NATSSink
+func fakeNATSServer(t *testing.T) (string, <-chan natsPub) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	pubs := make(chan natsPub, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 3 && fields[0] == "PUB":
+				n, _ := strconv.Atoi(fields[2])
+				buf := make([]byte, n+2)
+				if _, err := io.ReadFull(r, buf); err != nil {
+					return
+				}
+				pubs <- natsPub{subject: fields[1], payload: buf[:n]}
+			case len(fields) == 1 && fields[0] == "PING":
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+
+	return "nats://" + ln.Addr().String(), pubs
+}
+
+func TestNATSSink_Publishes(t *testing.T) {
+	addr, pubs := fakeNATSServer(t)
+
+	sink, err := NewNATSSink(addr, "reviews")
+	require.NoError(t, err)
+
+	event := models.Event{
+		ID:         "e1",
+		Type:       models.EventPRMerged,
+		OccurredAt: time.Now().UTC(),
+		Data:       json.RawMessage(`{"pull_request":{"pull_request_id":"pr-1"}}`),
+	}
+	require.NoError(t, sink.Deliver(context.Background(), event))
+
+	select {
+	case pub := <-pubs:
+		assert.Equal(t, "reviews.pr.merged", pub.subject)
+		var got models.Event
+		require.NoError(t, json.Unmarshal(pub.payload, &got))
+		assert.Equal(t, "e1", got.ID)
+		assert.JSONEq(t, string(event.Data), string(got.Data))
+	case <-time.After(time.Second):
+		t.Fatal("no PUB received")
+	}
+}
+
+func TestNATSSink_InvalidURL(t *testing.T) {
+	_, err := NewNATSSink("http://localhost:4222", "reviews")
+	assert.Error(t, err)
+}
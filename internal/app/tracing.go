@@ -0,0 +1,64 @@
+package app
+
+/*
+Трассировка OpenTelemetry:
+	1. TRACING_EXPORTER: none (по умолчанию), otlp или stdout
+	2. otlp - OTLP/HTTP на TRACING_OTLP_ENDPOINT; пусто - стандартные
+	   OTEL_EXPORTER_OTLP_* переменные окружения
+	3. stdout - спаны в stdout для локальной отладки
+
+Контекст W3C (traceparent/baggage) из входящих заголовков принимается всегда,
+даже без экспорта - чтобы request-id и логи могли ссылаться на trace_id.
+*/
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+func (a *App) initTracing() error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch a.cfg.TracingExporter {
+	case "", "none":
+		return nil
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if a.cfg.TracingOTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(a.cfg.TracingOTLPEndpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	default:
+		return fmt.Errorf("unknown tracing exporter %q", a.cfg.TracingExporter)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s exporter: %w", a.cfg.TracingExporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(a.cfg.TracingServiceName)))
+	if err != nil {
+		return fmt.Errorf("failed to build tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(a.cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	a.stopTracing = provider.Shutdown
+	return nil
+}
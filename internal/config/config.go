@@ -2,6 +2,7 @@ package config
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
@@ -18,6 +19,92 @@ type Config struct {
 	PG_DBName                 string `env:"NAME_DB_PG" envDefault:"webdev"`
 	PG_DBSSLMode              string `env:"DB_PG_SSLMODE" envDefault:"disable"`
 	PG_PORT                   string `env:"DB_PG_PORT" envDefault:"5432"`
+
+	GitHubWebhookSecret string            `env:"GITHUB_WEBHOOK_SECRET" envDefault:""`
+	GitHubUserMap       map[string]string `env:"GITHUB_USER_MAP" envDefault:""`
+	GitLabWebhookToken  string            `env:"GITLAB_WEBHOOK_TOKEN" envDefault:""`
+	GitLabUserMap       map[string]string `env:"GITLAB_USER_MAP" envDefault:""`
+
+	OutboundWebhookMaxAttempts  int           `env:"OUTBOUND_WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+	OutboundWebhookBackoff      time.Duration `env:"OUTBOUND_WEBHOOK_BACKOFF" envDefault:"5s"`
+	OutboundWebhookMaxBackoff   time.Duration `env:"OUTBOUND_WEBHOOK_MAX_BACKOFF" envDefault:"1h"`
+	OutboundWebhookTimeout      time.Duration `env:"OUTBOUND_WEBHOOK_TIMEOUT" envDefault:"10s"`
+	OutboundWebhookPollInterval time.Duration `env:"OUTBOUND_WEBHOOK_POLL_INTERVAL" envDefault:"2s"`
+	OutboundWebhookAllowPrivate bool          `env:"OUTBOUND_WEBHOOK_ALLOW_PRIVATE" envDefault:"false"`
+
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+	OutboxSinks        []string      `env:"OUTBOX_SINKS" envDefault:"log,webhook,chat"`
+	OutboxMaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10"`
+	NATSURL            string        `env:"NATS_URL" envDefault:""`
+	NATSSubjectPrefix  string        `env:"NATS_SUBJECT_PREFIX" envDefault:"reviews"`
+
+	SSEReplaySize   int `env:"SSE_REPLAY_SIZE" envDefault:"1000"`
+	SSEClientBuffer int `env:"SSE_CLIENT_BUFFER" envDefault:"64"`
+
+	ChatBatchWindow        time.Duration `env:"CHAT_BATCH_WINDOW" envDefault:"30s"`
+	ChatQuietHours         string        `env:"CHAT_QUIET_HOURS" envDefault:""`
+	ChatTimezone           string        `env:"CHAT_TIMEZONE" envDefault:"UTC"`
+	ChatTimeout            time.Duration `env:"CHAT_TIMEOUT" envDefault:"10s"`
+	ChatUsername           string        `env:"CHAT_USERNAME" envDefault:"reviewer-bot"`
+	ChatAssignedTemplate   string        `env:"CHAT_TEMPLATE_ASSIGNED" envDefault:""`
+	ChatReassignedTemplate string        `env:"CHAT_TEMPLATE_REASSIGNED" envDefault:""`
+	ChatReminderTemplate   string        `env:"CHAT_TEMPLATE_REMINDER" envDefault:""`
+	ChatStaleTemplate      string        `env:"CHAT_TEMPLATE_STALE" envDefault:""`
+	ChatAllowPrivate       bool          `env:"CHAT_ALLOW_PRIVATE" envDefault:"false"`
+
+	SMTPHost       string        `env:"SMTP_HOST" envDefault:""`
+	SMTPPort       string        `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername   string        `env:"SMTP_USERNAME" envDefault:""`
+	SMTPPassword   string        `env:"SMTP_PASSWORD" envDefault:""`
+	SMTPFrom       string        `env:"SMTP_FROM" envDefault:""`
+	SMTPTimeout    time.Duration `env:"SMTP_TIMEOUT" envDefault:"30s"`
+	DigestSendAt   string        `env:"DIGEST_SEND_AT" envDefault:"09:00"`
+	DigestTimezone string        `env:"DIGEST_TIMEZONE" envDefault:"UTC"`
+
+	SLACheckInterval   time.Duration `env:"SLA_CHECK_INTERVAL" envDefault:"1m"`
+	StaleCheckInterval time.Duration `env:"STALE_CHECK_INTERVAL" envDefault:"1h"`
+
+	AuthBootstrapToken string `env:"AUTH_BOOTSTRAP_TOKEN" envDefault:""`
+
+	JWTJWKS          string        `env:"JWT_JWKS" envDefault:""`
+	JWTIssuer        string        `env:"JWT_ISSUER" envDefault:""`
+	JWTAudience      string        `env:"JWT_AUDIENCE" envDefault:""`
+	JWTUserClaim     string        `env:"JWT_USER_CLAIM" envDefault:"sub"`
+	JWTDefaultScopes []string      `env:"JWT_DEFAULT_SCOPES" envDefault:"read,write"`
+	JWTAdminClaim    string        `env:"JWT_ADMIN_CLAIM" envDefault:""`
+	JWTAdminValues   []string      `env:"JWT_ADMIN_VALUES" envDefault:""`
+	JWTLeeway        time.Duration `env:"JWT_LEEWAY" envDefault:"30s"`
+	JWTJWKSRefresh   time.Duration `env:"JWT_JWKS_REFRESH" envDefault:"10m"`
+	JWTJWKSTimeout   time.Duration `env:"JWT_JWKS_TIMEOUT" envDefault:"5s"`
+
+	SCIMDefaultTeam string `env:"SCIM_DEFAULT_TEAM" envDefault:"unassigned"`
+
+	HTTPMaxBodyBytes    int64   `env:"HTTP_MAX_BODY_BYTES" envDefault:"1048576"`
+	RateLimitReadRPS    float64 `env:"RATE_LIMIT_READ_RPS" envDefault:"20"`
+	RateLimitReadBurst  int     `env:"RATE_LIMIT_READ_BURST" envDefault:"40"`
+	RateLimitWriteRPS   float64 `env:"RATE_LIMIT_WRITE_RPS" envDefault:"5"`
+	RateLimitWriteBurst int     `env:"RATE_LIMIT_WRITE_BURST" envDefault:"10"`
+	RateLimitIPRPS      float64 `env:"RATE_LIMIT_IP_RPS" envDefault:"50"`
+	RateLimitIPBurst    int     `env:"RATE_LIMIT_IP_BURST" envDefault:"100"`
+	// сколько своих прокси дописывают X-Forwarded-For (за Caddy - 1)
+	RateLimitTrustedProxies int `env:"RATE_LIMIT_TRUSTED_PROXIES" envDefault:"0"`
+
+	IdempotencyTTL             time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+	IdempotencyCleanupInterval time.Duration `env:"IDEMPOTENCY_CLEANUP_INTERVAL" envDefault:"1h"`
+
+	BatchMaxOperations int           `env:"BATCH_MAX_OPERATIONS" envDefault:"100"`
+	BatchTimeout       time.Duration `env:"BATCH_TIMEOUT" envDefault:"60s"`
+
+	MetricsPort  string `env:"METRICS_PORT" envDefault:"9090"`
+	MetricsToken string `env:"METRICS_TOKEN" envDefault:""`
+
+	TracingExporter     string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	TracingOTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT" envDefault:""`
+	TracingServiceName  string  `env:"TRACING_SERVICE_NAME" envDefault:"pr-reviewer"`
+	TracingSampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+
+	LogFormat string `env:"LOG_FORMAT" envDefault:"text"`
+	LogLevel  string `env:"LOG_LEVEL" envDefault:"info"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,68 @@
+package handlers
+
+/*
+	// GET /audit?entity=pr|team|user&id=...&cursor=...&limit=...
+*/
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test-task/internal/models"
+)
+
+// GET /audit
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	entity := query.Get("entity")
+	switch entity {
+	case models.AuditEntityTeam, models.AuditEntityUser, models.AuditEntityPR:
+	default:
+		writeError(w, http.StatusBadRequest, "entity must be one of: team, user, pr")
+		return
+	}
+
+	entityID := query.Get("id")
+	if entityID == "" {
+		writeError(w, http.StatusBadRequest, "id parameter is required")
+		return
+	}
+
+	limit := 50
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 500 {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	page, err := h.Audit.List(r.Context(), entity, entityID, query.Get("cursor"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidQuery):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		case err == models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"entity":      entity,
+		"id":          entityID,
+		"entries":     page.Entries,
+		"next_cursor": page.NextCursor,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
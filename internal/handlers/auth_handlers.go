@@ -0,0 +1,102 @@
+package handlers
+
+/*
+	// POST /auth/tokens/issue
+	// GET /auth/tokens/list
+	// POST /auth/tokens/revoke
+*/
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test-task/internal/models"
+)
+
+// POST /auth/tokens/issue
+func (h *Handler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.IssueTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	token, err := h.APITokens.IssueToken(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidToken):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_TOKEN", err.Error())
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"token": token,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /auth/tokens/list
+func (h *Handler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokens, err := h.APITokens.ListTokens(r.Context())
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"tokens": tokens,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /auth/tokens/revoke
+func (h *Handler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	err := h.APITokens.RevokeToken(r.Context(), req.ID)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":      req.ID,
+		"revoked": true,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+/*
+	// POST /batch
+
+Операция - путь одиночного эндпоинта и его тело:
+	{"mode": "atomic", "operations": [{"op": "/pullRequest/create", "body": {...}}]}
+
+Каждая операция выполняется тем же обработчиком, что и одиночный запрос,
+поэтому статусы, коды ошибок и тела ответов совпадают. Записи создания PR
+в atomic-пакете копятся и уходят в базу одним pgx.Batch (см. BatchService).
+*/
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test-task/internal/models"
+	"test-task/internal/services"
+)
+
+type batchOperation struct {
+	Op   string          `json:"op"`
+	Body json.RawMessage `json:"body"`
+}
+
+type batchResult struct {
+	Index   int             `json:"index"`
+	Op      string          `json:"op"`
+	Status  int             `json:"status,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+	Skipped bool            `json:"skipped,omitempty"`
+}
+
+// batchDeferredWrites - операции, которые только добавляют строки и не
+// читают записанное предыдущими такими же операциями пакета
+var batchDeferredWrites = map[string]bool{
+	"/pullRequest/create": true,
+}
+
+func (h *Handler) batchOperations() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/team/add":             h.AddTeam,
+		"/users/setIsActive":    h.SetIsActive,
+		"/pullRequest/create":   h.CreatePR,
+		"/pullRequest/merge":    h.MergePR,
+		"/pullRequest/close":    h.ClosePR,
+		"/pullRequest/reopen":   h.ReopenPR,
+		"/pullRequest/reassign": h.ReassignReviewer,
+		"/pullRequest/review":   h.SubmitReview,
+	}
+}
+
+// POST /batch
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Mode       string           `json:"mode"`
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ops := h.batchOperations()
+	for _, op := range req.Operations {
+		if ops[op.Op] == nil {
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_BATCH", "unsupported operation "+op.Op)
+			return
+		}
+	}
+
+	results := make([]batchResult, len(req.Operations))
+	failed := 0
+	deferWrites := func(i int) bool { return batchDeferredWrites[req.Operations[i].Op] }
+	executed, committed, err := h.Batches.Run(r.Context(), req.Mode, len(req.Operations), deferWrites, func(ctx context.Context, i int) bool {
+		op := req.Operations[i]
+		res := runBatchOperation(ctx, ops[op.Op], op)
+		res.Index = i
+		results[i] = res
+		if res.Status >= http.StatusBadRequest {
+			failed++
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidBatch):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_BATCH", err.Error())
+		case err == models.ErrPRExists:
+			writeErrorResponse(w, http.StatusConflict, "PR_EXISTS", "PR id already exists")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	for i := executed; i < len(results); i++ {
+		results[i] = batchResult{Index: i, Op: req.Operations[i].Op, Skipped: true}
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = services.BatchModeIndependent
+	}
+	response := map[string]interface{}{
+		"mode":      mode,
+		"committed": committed,
+		"failed":    failed,
+		"results":   results,
+	}
+
+	// откат atomic-пакета - ответ со статусом упавшей операции
+	status := http.StatusOK
+	if !committed && executed > 0 {
+		status = results[executed-1].Status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func runBatchOperation(ctx context.Context, handler http.HandlerFunc, op batchOperation) batchResult {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, op.Op, bytes.NewReader(op.Body))
+	if err != nil {
+		return batchResult{Op: op.Op, Status: http.StatusBadRequest}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+	handler(rec, req)
+
+	return batchResult{Op: op.Op, Status: rec.status, Body: bytes.TrimSpace(rec.body.Bytes())}
+}
+
+// bufferedResponse копит ответ операции пакета в памяти
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
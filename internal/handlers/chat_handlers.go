@@ -0,0 +1,105 @@
+package handlers
+
+/*
+	// POST /notifications/chat/set
+	// GET /notifications/chat/list
+	// POST /notifications/chat/delete
+*/
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test-task/internal/models"
+)
+
+// POST /notifications/chat/set
+func (h *Handler) SetChatTarget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.SetChatTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	target, err := h.ChatTargets.SetTarget(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidChatTarget):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_CHAT_TARGET", err.Error())
+		case err == models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"target": target,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /notifications/chat/list
+func (h *Handler) ListChatTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	targets, err := h.ChatTargets.ListTargets(r.Context())
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"targets": targets,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /notifications/chat/delete
+func (h *Handler) DeleteChatTarget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Scope    string `json:"scope"`
+		TargetID string `json:"target_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Scope == "" || req.TargetID == "" {
+		writeError(w, http.StatusBadRequest, "scope and target_id are required")
+		return
+	}
+
+	if err := h.ChatTargets.DeleteTarget(r.Context(), req.Scope, req.TargetID); err != nil {
+		switch err {
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"test-task/internal/logging"
 	"test-task/internal/services"
 )
 
@@ -10,18 +11,57 @@ type Handler struct {
 	TeamManag        services.TeamManager
 	UserManag        services.UserManager
 	PullRequestManag services.PullRequestManager
+	GitHubHook       services.WebhookManager
+	GitLabHook       services.WebhookManager
+	Subscriptions    services.SubscriptionManager
+	Events           services.EventStreamer
+	ChatTargets      services.ChatTargetManager
+	SLA              services.SLAManager
+	StalePRs         services.StalePRManager
+	Audit            services.AuditReader
+	APITokens        services.APITokenManager
+	SCIM             services.SCIMManager
+	Batches          services.BatchRunner
+	Searcher         services.Searcher
+	Outbox           services.OutboxReader
 }
 
 func NewHandler(
 	TeamManag services.TeamManager,
 	UserManag services.UserManager,
 	PullRequestManag services.PullRequestManager,
+	GitHubHook services.WebhookManager,
+	GitLabHook services.WebhookManager,
+	Subscriptions services.SubscriptionManager,
+	Events services.EventStreamer,
+	ChatTargets services.ChatTargetManager,
+	SLA services.SLAManager,
+	StalePRs services.StalePRManager,
+	Audit services.AuditReader,
+	APITokens services.APITokenManager,
+	SCIM services.SCIMManager,
+	Batches services.BatchRunner,
+	Searcher services.Searcher,
+	Outbox services.OutboxReader,
 ) (*Handler, error) {
 
 	return &Handler{
 		TeamManag:        TeamManag,
 		UserManag:        UserManag,
 		PullRequestManag: PullRequestManag,
+		GitHubHook:       GitHubHook,
+		GitLabHook:       GitLabHook,
+		Subscriptions:    Subscriptions,
+		Events:           Events,
+		ChatTargets:      ChatTargets,
+		SLA:              SLA,
+		StalePRs:         StalePRs,
+		Audit:            Audit,
+		APITokens:        APITokens,
+		SCIM:             SCIM,
+		Batches:          Batches,
+		Searcher:         Searcher,
+		Outbox:           Outbox,
 	}, nil
 }
 
@@ -32,6 +72,12 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	})
 }
 
+// writeInternalError пишет настоящую причину в лог запроса, клиенту - общий 500
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Error("Request failed", "path", r.URL.Path, "error", err)
+	writeError(w, http.StatusInternalServerError, "internal server error")
+}
+
 func writeErrorResponse(w http.ResponseWriter, status int, code, message string) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -0,0 +1,68 @@
+package handlers
+
+/*
+Разбор query-параметров списков: limit, cursor, sort, order и фильтров.
+Ошибки оборачивают models.ErrInvalidQuery - обработчик отвечает 400 INVALID_QUERY.
+*/
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"test-task/internal/models"
+	"time"
+)
+
+func parsePageParams(query url.Values) (limit int, cursor string, sort string, order string, err error) {
+	if raw := query.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return 0, "", "", "", fmt.Errorf("%w: limit must be a positive integer", models.ErrInvalidQuery)
+		}
+	}
+	return limit, query.Get("cursor"), query.Get("sort"), query.Get("order"), nil
+}
+
+func parseTimeParam(query url.Values, name string) (*time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", models.ErrInvalidQuery, name)
+	}
+	return &t, nil
+}
+
+func parsePRListQuery(query url.Values) (models.PRListQuery, error) {
+	var q models.PRListQuery
+	var err error
+	if q.Limit, q.Cursor, q.Sort, q.Order, err = parsePageParams(query); err != nil {
+		return q, err
+	}
+	if q.CreatedFrom, err = parseTimeParam(query, "created_from"); err != nil {
+		return q, err
+	}
+	if q.CreatedTo, err = parseTimeParam(query, "created_to"); err != nil {
+		return q, err
+	}
+	q.Status = query.Get("status")
+	q.AuthorID = query.Get("author_id")
+	return q, nil
+}
+
+func parseUserListQuery(query url.Values) (models.UserListQuery, error) {
+	var q models.UserListQuery
+	var err error
+	if q.Limit, q.Cursor, q.Sort, q.Order, err = parsePageParams(query); err != nil {
+		return q, err
+	}
+	if raw := query.Get("is_active"); raw != "" {
+		isActive, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, fmt.Errorf("%w: is_active must be true or false", models.ErrInvalidQuery)
+		}
+		q.IsActive = &isActive
+	}
+	return q, nil
+}
@@ -0,0 +1,383 @@
+package handlers
+
+/*
+Middleware поверх всего mux:
+	0. Trace - серверный спан на запрос; родитель берётся из W3C traceparent
+	1. RequestContext - ID запроса (из X-Request-ID или новый) в контексте
+	   и в ответе, логгер запроса с request_id и trace_id, строка access-лога
+	   по завершении (5xx - уровнем ERROR)
+	2. LimitBody - предел размера тела запроса (http.MaxBytesReader)
+	3. RateLimitIP - грубый лимит по IP до аутентификации: поток запросов
+	   без токена или с неверным токеном не доходит до поиска токена в базе
+	4. Authenticate - Bearer API-токен или JWT -> Principal в контексте; скоуп
+	   выбирается по маршруту: /auth/* и /scim/* - admin, GET - read,
+	   остальное - write
+	5. RateLimit - token bucket на клиента, отдельно для чтения и записи;
+	   клиент - API-токен, субъект JWT или, без Principal, IP
+	6. Idempotency - повтор POST с тем же Idempotency-Key получает
+	   сохранённый ответ вместо повторного выполнения
+
+IP клиента за прокси берётся из X-Forwarded-For, но не левый адрес (его
+пишет сам клиент), а тот, что дописал первый из trustedProxies своих
+прокси - trustedProxies-й справа.
+
+WriteDeadline ставится на отдельные маршруты (POST /batch), которым мало
+WriteTimeout сервера.
+
+Вебхуки GitHub/GitLab проверяются подписью и в Authenticate не попадают.
+*/
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"test-task/internal/logging"
+	"test-task/internal/models"
+	"test-task/internal/services"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Trace называет спан по шаблону маршрута из mux ("POST /pullRequest/create"),
+// а не по пути - у /scim/v2/Users/{id} иначе каждый id стал бы своим именем
+func Trace(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		name := r.Method
+		attrs := []attribute.KeyValue{
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+		}
+		if _, route := mux.Handler(r); route != "" {
+			name += " " + route
+			attrs = append(attrs, attribute.String("http.route", route))
+		}
+
+		ctx, span := otel.Tracer("test-task/internal/handlers").Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+
+		rec := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+func RequestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(attribute.String("request.id", requestID))
+
+		logAttrs := []any{"request_id", requestID}
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			logAttrs = append(logAttrs, "trace_id", sc.TraceID().String())
+		}
+		logger := slog.Default().With(logAttrs...)
+
+		ctx := services.WithRequestID(r.Context(), requestID)
+		ctx = logging.WithLogger(ctx, logger)
+
+		start := time.Now()
+		rec := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		// query не пишется - в нём бывают курсоры и прочие длинные строки
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.Log(ctx, level, "HTTP request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start),
+		)
+	})
+}
+
+// WriteDeadline сдвигает срок записи ответа на timeout от начала запроса и
+// ограничивает тем же сроком контекст: операции, не успевшие к нему,
+// откатываются, а не теряют уже посчитанный ответ
+func WriteDeadline(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if timeout <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(timeout)
+		if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+			slog.WarnContext(r.Context(), "Failed to extend write deadline", "error", err)
+		}
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func LimitBody(maxBytes int64, next http.Handler) http.Handler {
+	if maxBytes <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "request body exceeds "+strconv.FormatInt(maxBytes, 10)+" bytes")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func Authenticate(auth services.Authenticator, public map[string]bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if public[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := auth.Authenticate(r.Context(), bearerToken(r))
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrUnauthorized):
+				w.Header().Set("WWW-Authenticate", `Bearer realm="reviewer"`)
+				writeErrorResponse(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing or invalid API token")
+			default:
+				writeInternalError(w, r, err)
+			}
+			return
+		}
+
+		if !principal.HasScope(requiredScope(r)) {
+			writeErrorResponse(w, http.StatusForbidden, "INSUFFICIENT_SCOPE", "token scope "+requiredScope(r)+" is required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(services.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func requiredScope(r *http.Request) string {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/auth/"), strings.HasPrefix(r.URL.Path, "/scim/"):
+		return models.ScopeAdmin
+	case isReadRequest(r):
+		return models.ScopeRead
+	default:
+		return models.ScopeWrite
+	}
+}
+
+// RateLimitIP ставится до Authenticate
+func RateLimitIP(limiter *services.RateLimiter, trustedProxies int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := limiter.Allow("ip:" + clientIP(r, trustedProxies))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeErrorResponse(w, http.StatusTooManyRequests, "RATE_LIMITED", "too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimit ставится после Authenticate, чтобы видеть Principal
+func RateLimit(read *services.RateLimiter, write *services.RateLimiter, trustedProxies int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := write
+		if isReadRequest(r) {
+			limiter = read
+		}
+
+		ok, wait := limiter.Allow(clientKey(r, trustedProxies))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeErrorResponse(w, http.StatusTooManyRequests, "RATE_LIMITED", "too many requests")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientKey(r *http.Request, trustedProxies int) string {
+	if p := services.PrincipalFrom(r.Context()); p != nil {
+		if p.TokenID != 0 {
+			return "token:" + strconv.FormatInt(p.TokenID, 10)
+		}
+		return p.Type + ":" + p.ID
+	}
+	return "ip:" + clientIP(r, trustedProxies)
+}
+
+// Idempotency действует на POST к маршрутам routes с заголовком
+// Idempotency-Key. Ответы 5xx не сохраняются: ключ освобождается, и повтор
+// выполнится заново.
+func Idempotency(idem services.IdempotencyManager, routes map[string]bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(models.IdempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost || !routes[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := clientKey(r, 0)
+		record, err := idem.Begin(r.Context(), scope, key, services.IdempotencyFingerprint(r.Method, r.URL.Path, body))
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrInvalidIdempotencyKey):
+				writeErrorResponse(w, http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY", "Idempotency-Key must be 1-255 characters")
+			case errors.Is(err, models.ErrIdempotencyKeyReused):
+				writeErrorResponse(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used with a different request")
+			case errors.Is(err, models.ErrIdempotencyInProgress):
+				writeErrorResponse(w, http.StatusConflict, "IDEMPOTENCY_IN_PROGRESS", "a request with this Idempotency-Key is still in progress")
+			default:
+				writeInternalError(w, r, err)
+			}
+			return
+		}
+
+		if record != nil {
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.Body)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// клиент мог уйти, а ключ всё равно нужно закрыть
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= http.StatusInternalServerError {
+			if err := idem.Release(ctx, scope, key); err != nil {
+				logging.FromContext(ctx).Error("Failed to release idempotency key", "key", key, "error", err)
+			}
+			return
+		}
+		if err := idem.Complete(ctx, scope, key, rec.status, w.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+			logging.FromContext(ctx).Error("Failed to store idempotent response", "key", key, "error", err)
+		}
+	})
+}
+
+// responseRecorder пишет ответ клиенту и параллельно копит его для Idempotency
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// statusWriter запоминает код ответа; Unwrap нужен http.ResponseController (SSE)
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// clientIP - адрес, который увидел первый из trustedProxies своих прокси.
+// Всё левее него в X-Forwarded-For пишет клиент и доверия не заслуживает.
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		if len(hops) > 0 {
+			// прокси меньше, чем ожидалось - все записи и так от них
+			return hops[max(len(hops)-trustedProxies, 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func isReadRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+/*
+	// GET /outbox/dead?limit=...
+*/
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"test-task/internal/models"
+)
+
+// GET /outbox/dead
+func (h *Handler) ListOutboxDead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 500 {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	deliveries, err := h.Outbox.ListDead(r.Context(), limit)
+	if err != nil {
+		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"deliveries": deliveries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
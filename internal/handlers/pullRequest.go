@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"test-task/internal/models"
 )
@@ -32,7 +33,7 @@ func (h *Handler) CreatePR(w http.ResponseWriter, r *http.Request) {
 		case models.ErrNotFound:
 			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeInternalError(w, r, err)
 		}
 		return
 	}
@@ -70,10 +71,102 @@ func (h *Handler) MergePR(w http.ResponseWriter, r *http.Request) {
 	pr, err := h.PullRequestManag.MergePR(r.Context(), req.PullRequestID)
 	if err != nil {
 		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
 		case models.ErrNotFound:
 			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case models.ErrPRClosed:
+			writeErrorResponse(w, http.StatusConflict, "PR_CLOSED", "cannot merge closed PR")
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"pr": pr,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /pullRequest/close
+func (h *Handler) ClosePR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PullRequestID string `json:"pull_request_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.PullRequestID == "" {
+		writeError(w, http.StatusBadRequest, "pull_request_id is required")
+		return
+	}
+
+	pr, err := h.PullRequestManag.ClosePR(r.Context(), req.PullRequestID)
+	if err != nil {
+		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case models.ErrPRMerged:
+			writeErrorResponse(w, http.StatusConflict, "PR_MERGED", "cannot close merged PR")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"pr": pr,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /pullRequest/reopen
+func (h *Handler) ReopenPR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PullRequestID string `json:"pull_request_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.PullRequestID == "" {
+		writeError(w, http.StatusBadRequest, "pull_request_id is required")
+		return
+	}
+
+	pr, err := h.PullRequestManag.ReopenPR(r.Context(), req.PullRequestID)
+	if err != nil {
+		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case models.ErrPRMerged:
+			writeErrorResponse(w, http.StatusConflict, "PR_MERGED", "cannot reopen merged PR")
+		default:
+			writeInternalError(w, r, err)
 		}
 		return
 	}
@@ -107,16 +200,20 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	pr, newReviewer, err := h.PullRequestManag.ReassignReviewer(r.Context(), req)
 	if err != nil {
 		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
 		case models.ErrNotFound:
 			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
 		case models.ErrPRMerged:
 			writeErrorResponse(w, http.StatusConflict, "PR_MERGED", "cannot reassign on merged PR")
+		case models.ErrPRClosed:
+			writeErrorResponse(w, http.StatusConflict, "PR_CLOSED", "cannot reassign on closed PR")
 		case models.ErrNotAssigned:
 			writeErrorResponse(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
 		case models.ErrNoCandidate:
 			writeErrorResponse(w, http.StatusConflict, "NO_CANDIDATE", "no active replacement candidate in team")
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeInternalError(w, r, err)
 		}
 		return
 	}
@@ -129,3 +226,112 @@ func (h *Handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// POST /pullRequest/review
+func (h *Handler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.ReviewDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.PullRequestID == "" || req.ReviewerID == "" {
+		writeError(w, http.StatusBadRequest, "pull_request_id and reviewer_id are required")
+		return
+	}
+
+	pr, err := h.PullRequestManag.SubmitReview(r.Context(), req)
+	if err != nil {
+		switch err {
+		case models.ErrInvalidReviewDecision:
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_REVIEW_DECISION", "decision must be approve or request_changes")
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case models.ErrPRMerged:
+			writeErrorResponse(w, http.StatusConflict, "PR_MERGED", "cannot review merged PR")
+		case models.ErrPRClosed:
+			writeErrorResponse(w, http.StatusConflict, "PR_CLOSED", "cannot review closed PR")
+		case models.ErrNotAssigned:
+			writeErrorResponse(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"pr":       pr,
+		"decision": req.Decision,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /pullRequest/history
+func (h *Handler) GetPRHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		writeError(w, http.StatusBadRequest, "pull_request_id parameter is required")
+		return
+	}
+
+	timeline, err := h.PullRequestManag.GetHistory(r.Context(), prID)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"pull_request_id": prID,
+		"timeline":        timeline,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /pullRequest/list
+func (h *Handler) ListPRs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, err := parsePRListQuery(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		return
+	}
+
+	page, err := h.PullRequestManag.ListPRs(r.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidQuery):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
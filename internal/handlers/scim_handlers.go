@@ -0,0 +1,234 @@
+package handlers
+
+/*
+	// GET, POST /scim/v2/Users?filter=userName eq "..."&startIndex=1&count=100
+	// GET, PUT, PATCH, DELETE /scim/v2/Users/{id}
+	// GET, POST /scim/v2/Groups?filter=displayName eq "..."
+	// GET, PUT, PATCH, DELETE /scim/v2/Groups/{id}
+
+Ответы и ошибки - в формате SCIM (application/scim+json), а не в общем
+формате API: их читает IdP.
+*/
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test-task/internal/logging"
+	"test-task/internal/models"
+)
+
+const scimContentType = "application/scim+json"
+
+// /scim/v2/Users
+func (h *Handler) SCIMUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		startIndex, count, ok := scimPaging(w, r)
+		if !ok {
+			return
+		}
+		list, err := h.SCIM.ListUsers(r.Context(), r.URL.Query().Get("filter"), startIndex, count)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, list)
+	case http.MethodPost:
+		var req models.SCIMUser
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid request body")
+			return
+		}
+		user, err := h.SCIM.CreateUser(r.Context(), req)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusCreated, user)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /scim/v2/Users/{id}
+func (h *Handler) SCIMUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		user, err := h.SCIM.GetUser(r.Context(), id)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, user)
+	case http.MethodPut:
+		var req models.SCIMUser
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid request body")
+			return
+		}
+		user, err := h.SCIM.ReplaceUser(r.Context(), id, req)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, user)
+	case http.MethodPatch:
+		var req models.SCIMPatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid request body")
+			return
+		}
+		user, err := h.SCIM.PatchUser(r.Context(), id, req)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, user)
+	case http.MethodDelete:
+		if err := h.SCIM.DeleteUser(r.Context(), id); err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /scim/v2/Groups
+func (h *Handler) SCIMGroups(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		startIndex, count, ok := scimPaging(w, r)
+		if !ok {
+			return
+		}
+		list, err := h.SCIM.ListGroups(r.Context(), r.URL.Query().Get("filter"), startIndex, count)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, list)
+	case http.MethodPost:
+		var req models.SCIMGroup
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid request body")
+			return
+		}
+		group, err := h.SCIM.CreateGroup(r.Context(), req)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusCreated, group)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /scim/v2/Groups/{id}
+func (h *Handler) SCIMGroup(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		group, err := h.SCIM.GetGroup(r.Context(), id)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, group)
+	case http.MethodPut:
+		var req models.SCIMGroup
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid request body")
+			return
+		}
+		group, err := h.SCIM.ReplaceGroup(r.Context(), id, req)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, group)
+	case http.MethodPatch:
+		var req models.SCIMPatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid request body")
+			return
+		}
+		group, err := h.SCIM.PatchGroup(r.Context(), id, req)
+		if err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, group)
+	case http.MethodDelete:
+		if err := h.SCIM.DeleteGroup(r.Context(), id); err != nil {
+			writeSCIMServiceError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func scimPaging(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	query := r.URL.Query()
+
+	startIndex, count := 1, models.SCIMDefaultPageSize
+	if raw := query.Get("startIndex"); raw != "" {
+		var err error
+		startIndex, err = strconv.Atoi(raw)
+		if err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+			return 0, 0, false
+		}
+	}
+	if raw := query.Get("count"); raw != "" {
+		var err error
+		count, err = strconv.Atoi(raw)
+		if err != nil {
+			writeSCIMError(w, http.StatusBadRequest, "invalidValue", "count must be an integer")
+			return 0, 0, false
+		}
+	}
+	return startIndex, count, true
+}
+
+func writeSCIMServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		writeSCIMError(w, http.StatusNotFound, "", "resource not found")
+	case errors.Is(err, models.ErrUserExists), errors.Is(err, models.ErrTeamExists):
+		writeSCIMError(w, http.StatusConflict, "uniqueness", err.Error())
+	case errors.Is(err, models.ErrInvalidSCIM):
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+	case errors.Is(err, models.ErrForbidden):
+		writeSCIMError(w, http.StatusForbidden, "", "not allowed")
+	default:
+		logging.FromContext(r.Context()).Error("Request failed", "path", r.URL.Path, "error", err)
+		writeSCIMError(w, http.StatusInternalServerError, "", "internal server error")
+	}
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType string, detail string) {
+	body := map[string]interface{}{
+		"schemas": []string{models.SCIMSchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeSCIM(w, status, body)
+}
+
+func writeSCIM(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+/*
+	// POST /team/sla/set
+	// GET /team/sla/get
+	// GET /team/sla/escalations
+*/
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test-task/internal/models"
+)
+
+// POST /team/sla/set
+func (h *Handler) SetTeamSLA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.TeamSLA
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, "team_name is required")
+		return
+	}
+
+	sla, err := h.SLA.SetTeamSLA(r.Context(), req)
+	if err != nil {
+		switch {
+		case err == models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case errors.Is(err, models.ErrInvalidSLA):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_SLA", err.Error())
+		case err == models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"sla": sla,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /team/sla/get
+func (h *Handler) GetTeamSLA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "team_name parameter is required")
+		return
+	}
+
+	sla, err := h.SLA.GetTeamSLA(r.Context(), teamName)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"sla": sla,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /team/sla/escalations
+func (h *Handler) ListSLAEscalations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "team_name parameter is required")
+		return
+	}
+
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 500 {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	escalations, err := h.SLA.ListEscalations(r.Context(), teamName, limit)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"team_name":   teamName,
+		"escalations": escalations,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
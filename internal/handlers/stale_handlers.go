@@ -0,0 +1,108 @@
+package handlers
+
+/*
+	// POST /team/stalePolicy/set
+	// GET /team/stalePolicy/get
+	// GET /pullRequest/stale/dryRun
+*/
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test-task/internal/models"
+)
+
+// POST /team/stalePolicy/set
+func (h *Handler) SetStalePolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.StalePolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, "team_name is required")
+		return
+	}
+
+	policy, err := h.StalePRs.SetPolicy(r.Context(), req)
+	if err != nil {
+		switch {
+		case err == models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case errors.Is(err, models.ErrInvalidStalePolicy):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_STALE_POLICY", err.Error())
+		case err == models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"policy": policy,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /team/stalePolicy/get
+func (h *Handler) GetStalePolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "team_name parameter is required")
+		return
+	}
+
+	policy, err := h.StalePRs.GetPolicy(r.Context(), teamName)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"policy": policy,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /pullRequest/stale/dryRun
+func (h *Handler) StaleDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	plan, err := h.StalePRs.Plan(r.Context())
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"actions":   plan.Actions,
+		"truncated": plan.Truncated,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
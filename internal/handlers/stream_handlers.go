@@ -0,0 +1,122 @@
+package handlers
+
+/*
+	// GET /events/stream
+
+Server-Sent Events. Поддерживается Last-Event-ID (заголовок или query
+last_event_id). Если часть событий уже вытеснена из буфера, первым уходит
+событие resync - клиенту стоит перечитать /users/getReview.
+Раз в sseHeartbeatInterval шлётся комментарий, чтобы прокси не рвали соединение.
+*/
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"test-task/internal/models"
+	"time"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// GET /events/stream
+func (h *Handler) EventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter := models.StreamFilter{
+		UserID:   r.URL.Query().Get("user_id"),
+		TeamName: r.URL.Query().Get("team_name"),
+	}
+	if filter.UserID == "" && filter.TeamName == "" {
+		writeError(w, http.StatusBadRequest, "user_id or team_name parameter is required")
+		return
+	}
+
+	if err := h.Events.Authorize(r.Context(), filter); err != nil {
+		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var lastEventID uint64
+	if lastID != "" {
+		var err error
+		lastEventID, err = strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	sub, replay, gap := h.Events.Subscribe(filter, lastEventID)
+	defer h.Events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if gap {
+		fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+	}
+	for _, msg := range replay {
+		if err := writeSSE(w, msg); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				// клиент не успевал читать - пусть переподключится с Last-Event-ID
+				return
+			}
+			if err := writeSSE(w, msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, msg models.StreamMessage) error {
+	data, err := json.Marshal(msg.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.Seq, msg.Event.Type, data)
+	return err
+}
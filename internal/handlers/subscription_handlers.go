@@ -0,0 +1,162 @@
+package handlers
+
+/*
+	// POST /subscriptions/add
+	// GET /subscriptions/list
+	// POST /subscriptions/delete
+	// GET /subscriptions/deliveries
+*/
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"test-task/internal/models"
+)
+
+// POST /subscriptions/add
+func (h *Handler) AddSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sub, err := h.Subscriptions.CreateSubscription(r.Context(), req)
+	if err != nil {
+		switch {
+		case err == models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case errors.Is(err, models.ErrInvalidSubscription):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_SUBSCRIPTION", err.Error())
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"subscription": sub,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /subscriptions/list
+func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	subs, err := h.Subscriptions.ListSubscriptions(r.Context())
+	if err != nil {
+		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"subscriptions": subs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /subscriptions/delete
+func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.ID == 0 {
+		writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	if err := h.Subscriptions.DeleteSubscription(r.Context(), req.ID); err != nil {
+		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /subscriptions/deliveries
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	subscriptionID, err := strconv.ParseInt(r.URL.Query().Get("subscription_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "subscription_id parameter is required")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		writeError(w, http.StatusBadRequest, "status must be PENDING, DELIVERED or DEAD")
+		return
+	}
+
+	limit := 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 500 {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+	}
+
+	deliveries, err := h.Subscriptions.ListDeliveries(r.Context(), subscriptionID, status, limit)
+	if err != nil {
+		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"subscription_id": subscriptionID,
+		"deliveries":      deliveries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
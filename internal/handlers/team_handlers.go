@@ -3,9 +3,13 @@ package handlers
 /*
 	// POST /team/add
 	// GET /team/get
+	// POST /team/codeowners
+	// POST /team/addMember
+	// POST /team/removeMember
 */
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"test-task/internal/models"
 )
@@ -39,10 +43,12 @@ func (h *Handler) AddTeam(w http.ResponseWriter, r *http.Request) {
 	createdTeam, err := h.TeamManag.CreateTeam(r.Context(), team)
 	if err != nil {
 		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
 		case models.ErrTeamExists:
 			writeErrorResponse(w, http.StatusBadRequest, "TEAM_EXISTS", "team_name already exists")
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeInternalError(w, r, err)
 		}
 		return
 	}
@@ -69,13 +75,21 @@ func (h *Handler) GetTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	team, err := h.TeamManag.GetTeam(r.Context(), teamName)
+	q, err := parseUserListQuery(r.URL.Query())
 	if err != nil {
-		switch err {
-		case models.ErrNotFound:
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		return
+	}
+
+	team, err := h.TeamManag.GetTeam(r.Context(), teamName, q)
+	if err != nil {
+		switch {
+		case err == models.ErrNotFound:
 			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case errors.Is(err, models.ErrInvalidQuery):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeInternalError(w, r, err)
 		}
 		return
 	}
@@ -83,3 +97,125 @@ func (h *Handler) GetTeam(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(team)
 }
+
+// POST /team/codeowners
+func (h *Handler) UploadCodeOwners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		TeamName string `json:"team_name"`
+		Content  string `json:"content"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, "team_name is required")
+		return
+	}
+
+	co, err := h.TeamManag.SetCodeOwners(r.Context(), req.TeamName, req.Content)
+	if err != nil {
+		switch {
+		case err == models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case errors.Is(err, models.ErrInvalidCodeOwners):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_CODEOWNERS", err.Error())
+		case err == models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"codeowners": co,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /team/addMember
+func (h *Handler) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.AddTeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" || req.Member.UserID == "" {
+		writeError(w, http.StatusBadRequest, "team_name and member.user_id are required")
+		return
+	}
+
+	team, err := h.TeamManag.AddMember(r.Context(), req)
+	if err != nil {
+		writeTeamMemberError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"team": team,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /team/removeMember
+func (h *Handler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.RemoveTeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.TeamName == "" || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "team_name and user_id are required")
+		return
+	}
+
+	team, err := h.TeamManag.RemoveMember(r.Context(), req)
+	if err != nil {
+		writeTeamMemberError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"team": team,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeTeamMemberError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case models.ErrForbidden:
+		writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+	case models.ErrNotFound:
+		writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+	case models.ErrInvalidTeamMember:
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_TEAM_MEMBER", "members cannot be removed from the default team")
+	default:
+		writeInternalError(w, r, err)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"test-task/internal/models"
 )
@@ -31,10 +32,12 @@ func (h *Handler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 	user, err := h.UserManag.SetUserActive(r.Context(), req.UserID, req.IsActive)
 	if err != nil {
 		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
 		case models.ErrNotFound:
 			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeInternalError(w, r, err)
 		}
 		return
 	}
@@ -65,20 +68,154 @@ func (h *Handler) GetUserReviews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prs, err := h.PullRequestManag.GetUserReviews(r.Context(), userID)
+	q, err := parsePRListQuery(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		return
+	}
+
+	page, err := h.PullRequestManag.GetUserReviews(r.Context(), userID, q)
+	if err != nil {
+		switch {
+		case err == models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case errors.Is(err, models.ErrInvalidQuery):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"user_id":       userID,
+		"pull_requests": page.PullRequests,
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GET /users/list
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, err := parseUserListQuery(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		return
+	}
+	q.TeamName = r.URL.Query().Get("team_name")
+
+	page, err := h.UserManag.ListUsers(r.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidQuery):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// POST /users/setDigestOptOut
+func (h *Handler) SetDigestOptOut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		UserID string `json:"user_id"`
+		OptOut bool   `json:"opt_out"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+
+	user, err := h.UserManag.SetDigestOptOut(r.Context(), req.UserID, req.OptOut)
 	if err != nil {
 		switch err {
+		case models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
 		case models.ErrNotFound:
 			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeInternalError(w, r, err)
 		}
 		return
 	}
 
 	response := map[string]interface{}{
-		"user_id":       userID,
-		"pull_requests": prs,
+		"user": map[string]interface{}{
+			"user_id":        user.UserID,
+			"username":       user.Username,
+			"team_name":      user.TeamName,
+			"is_active":      user.IsActive,
+			"digest_opt_out": user.DigestOptOut,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /users/setRole
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		UserID string `json:"user_id"`
+		Role   string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+
+	user, err := h.UserManag.SetRole(r.Context(), req.UserID, req.Role)
+	if err != nil {
+		switch {
+		case err == models.ErrForbidden:
+			writeErrorResponse(w, http.StatusForbidden, "FORBIDDEN", "not allowed to perform this action")
+		case errors.Is(err, models.ErrInvalidRole):
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_ROLE", err.Error())
+		case err == models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"user": user,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,85 @@
+package handlers
+
+/*
+	// POST /webhooks/github
+	// POST /webhooks/gitlab
+*/
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"test-task/internal/models"
+	"test-task/internal/services"
+)
+
+// POST /webhooks/github
+func (h *Handler) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		DeliveryID: r.Header.Get("X-GitHub-Delivery"),
+		Event:      r.Header.Get("X-GitHub-Event"),
+		Signature:  r.Header.Get("X-Hub-Signature-256"),
+		Body:       body,
+	}
+
+	h.handleWebhook(w, r, h.GitHubHook, delivery)
+}
+
+// POST /webhooks/gitlab
+func (h *Handler) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	delivery := models.WebhookDelivery{
+		DeliveryID: r.Header.Get("X-Gitlab-Event-UUID"),
+		Event:      r.Header.Get("X-Gitlab-Event"),
+		Signature:  r.Header.Get("X-Gitlab-Token"),
+		Body:       body,
+	}
+
+	h.handleWebhook(w, r, h.GitLabHook, delivery)
+}
+
+func (h *Handler) handleWebhook(w http.ResponseWriter, r *http.Request, hook services.WebhookManager, delivery models.WebhookDelivery) {
+	res, err := hook.HandleDelivery(r.Context(), delivery)
+	if err != nil {
+		switch err {
+		case models.ErrInvalidSignature:
+			writeErrorResponse(w, http.StatusUnauthorized, "INVALID_SIGNATURE", "webhook signature mismatch")
+		case models.ErrInvalidPayload:
+			writeErrorResponse(w, http.StatusBadRequest, "INVALID_PAYLOAD", "unsupported webhook payload")
+		case models.ErrUnmappedUser:
+			writeErrorResponse(w, http.StatusUnprocessableEntity, "UNMAPPED_USER", "author is not mapped to a user")
+		case models.ErrNotFound:
+			writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		case models.ErrPRClosed:
+			writeErrorResponse(w, http.StatusConflict, "PR_CLOSED", "PR is already closed")
+		case models.ErrPRMerged:
+			writeErrorResponse(w, http.StatusConflict, "PR_MERGED", "PR is already merged")
+		default:
+			writeInternalError(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const scrapeTimeout = 5 * time.Second
+
+// CollectFunc считает метрики в момент опроса; при ошибке не отдаётся ни одна
+type CollectFunc func(ctx context.Context) ([]prometheus.Metric, error)
+
+// RegisterCollector добавляет коллектор в реестр (в приложении - Registry).
+// Описаний метрик он не объявляет (unchecked collector) - их набор зависит
+// от данных.
+func RegisterCollector(registry prometheus.Registerer, name string, collect CollectFunc) {
+	registry.MustRegister(&safeCollector{name: name, collect: collect})
+}
+
+type safeCollector struct {
+	name    string
+	collect CollectFunc
+}
+
+func (c *safeCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *safeCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.gather() {
+		ch <- m
+	}
+}
+
+func (c *safeCollector) gather() (collected []prometheus.Metric) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Error("Metrics collector panicked", "collector", c.name, "panic", p)
+			collectorErrors.WithLabelValues(c.name).Inc()
+			collected = nil
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	collected, err := c.collect(ctx)
+	if err != nil {
+		slog.Warn("Metrics collector failed", "collector", c.name, "error", err)
+		collectorErrors.WithLabelValues(c.name).Inc()
+		return nil
+	}
+	return collected
+}
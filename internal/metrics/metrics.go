@@ -0,0 +1,64 @@
+package metrics
+
+/*
+Метрики приложения в формате Prometheus (client_golang):
+	1. Счётчики и гистограммы ниже - обновляются из кода
+	2. RegisterCollector - метрики, которые считаются в момент опроса (пул
+	   соединений, открытые PR из базы)
+	3. Handler - выдача реестра на GET /metrics
+
+Ошибка или паника коллектора не ломает ни выдачу, ни сервер: его метрики
+пропускаются, а в metrics_collector_errors_total растёт счётчик.
+*/
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Registry - реестр приложения, его отдаёт GET /metrics
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// outcome: commit, rollback, conflict (serialization failure или deadlock -
+	// транзакция откатилась; изменения PR сервис повторяет сам)
+	DBTransactions = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_transactions_total",
+		Help: "Finished database transactions by outcome.",
+	}, []string{"outcome"})
+
+	// повтор всей операции сервиса после conflict
+	DBTransactionRetries = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_transaction_retries_total",
+		Help: "Service operations retried after a serialization failure or deadlock.",
+	}, []string{"operation"})
+
+	NoCandidate = factory.NewCounter(prometheus.CounterOpts{
+		Name: "pr_reassign_no_candidate_total",
+		Help: "Reviewer reassignments that found no active replacement (NO_CANDIDATE).",
+	})
+
+	collectorErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "metrics_collector_errors_total",
+		Help: "Collector failures during a scrape.",
+	}, []string{"collector"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
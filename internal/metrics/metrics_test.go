@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// счётчики пакетные и живут между прогонами (go test -count=N) - проверяем приращение
+func TestFailingCollectorsAreSkipped(t *testing.T) {
+	open := prometheus.NewDesc("test_open", "Open.", nil, nil)
+	registry := prometheus.NewRegistry()
+	brokenErrors := testutil.ToFloat64(collectorErrors.WithLabelValues("broken"))
+	panickyErrors := testutil.ToFloat64(collectorErrors.WithLabelValues("panicky"))
+
+	RegisterCollector(registry, "broken", func(ctx context.Context) ([]prometheus.Metric, error) {
+		return nil, errors.New("db is down")
+	})
+	RegisterCollector(registry, "panicky", func(ctx context.Context) ([]prometheus.Metric, error) {
+		panic("boom")
+	})
+	RegisterCollector(registry, "ok", func(ctx context.Context) ([]prometheus.Metric, error) {
+		return []prometheus.Metric{prometheus.MustNewConstMetric(open, prometheus.GaugeValue, 7)}, nil
+	})
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	var names []string
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	assert.Equal(t, []string{"test_open"}, names)
+	assert.Equal(t, brokenErrors+1, testutil.ToFloat64(collectorErrors.WithLabelValues("broken")))
+	assert.Equal(t, panickyErrors+1, testutil.ToFloat64(collectorErrors.WithLabelValues("panicky")))
+}
+
+func TestInstrumentUsesRoutePattern(t *testing.T) {
+	series := HTTPRequests.WithLabelValues("/scim/v2/Users/{id}", http.MethodGet, "404")
+	unmatched := HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404")
+	start, unmatchedStart := testutil.ToFloat64(series), testutil.ToFloat64(unmatched)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scim/v2/Users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := Instrument(mux, mux)
+
+	for _, id := range []string{"u1", "u2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/scim/v2/Users/"+id, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	assert.Equal(t, start+2, testutil.ToFloat64(series))
+	assert.Equal(t, unmatchedStart+1, testutil.ToFloat64(unmatched))
+}
+
+func TestHandlerRequiresToken(t *testing.T) {
+	h := Handler(Registry, "secret")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "# TYPE go_goroutines gauge")
+}
@@ -8,22 +8,61 @@ type PullRequest struct {
 	AuthorID          string     `json:"author_id"`
 	Status            string     `json:"status"`
 	AssignedReviewers []string   `json:"assigned_reviewers"`
+	Project           string     `json:"project,omitempty"`
+	Tags              []string   `json:"tags,omitempty"`
+	ChangedFiles      []string   `json:"changed_files,omitempty"`
 	CreatedAt         time.Time  `json:"createdAt,omitempty"`
 	MergedAt          *time.Time `json:"mergedAt,omitempty"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+	StaleAt           *time.Time `json:"staleAt,omitempty"`
+	ClosedAt          *time.Time `json:"closedAt,omitempty"`
+	SLADueAt          *time.Time `json:"sla_due_at,omitempty"`
+	SLABreached       bool       `json:"sla_breached"`
+
+	// Заполняется только в ответе на создание: какие ревьюеры пришли из CODEOWNERS
+	CodeOwnerReviewers []CodeOwnerReviewer `json:"codeowner_reviewers,omitempty"`
 }
 type PullRequestShort struct {
 	PullRequestID   string `json:"pull_request_id"`
 	PullRequestName string `json:"pull_request_name"`
 	AuthorID        string `json:"author_id"`
 	Status          string `json:"status"`
+
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
 type CreatePRRequest struct {
+	PullRequestID   string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	Project         string   `json:"project,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	ChangedFiles    []string `json:"changed_files,omitempty"`
+}
+
+// UpdatePRRequest - синхронизация полей PR с хостингом
+type UpdatePRRequest struct {
 	PullRequestID   string `json:"pull_request_id"`
 	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	Project         string `json:"project,omitempty"`
+}
+
+const (
+	ReviewDecisionApprove        = "approve"
+	ReviewDecisionRequestChanges = "request_changes"
+)
+
+// ReviewDecisionRequest - решение ревьюера по PR
+type ReviewDecisionRequest struct {
+	PullRequestID string `json:"pull_request_id"`
+	ReviewerID    string `json:"reviewer_id"`
+	Decision      string `json:"decision"`
 }
 
 type ReassignRequest struct {
 	PullRequestID string `json:"pull_request_id"`
 	OldUserID     string `json:"old_user_id"`
+
+	// Причина для хронологии PR, по умолчанию - ручная переназначка
+	Reason string `json:"-"`
 }
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+
+	// PrincipalSystem не выдаётся токенам - его ставят себе фоновые задачи
+	// и вебхуки с проверенной подписью
+	PrincipalSystem = "system"
+)
+
+// Скоупы токенов: read - GET-запросы, write - изменения, admin - всё,
+// включая управление токенами
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// Роли пользователей
+const (
+	RoleMember = "member"
+	RoleLead   = "lead"
+	RoleAdmin  = "admin"
+)
+
+// Principal - тот, от чьего имени выполняется запрос
+type Principal struct {
+	Type    string   `json:"type"`
+	ID      string   `json:"id"`
+	Scopes  []string `json:"scopes"`
+	TokenID int64    `json:"token_id,omitempty"`
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type APIToken struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	PrincipalType string     `json:"principal_type"`
+	PrincipalID   string     `json:"principal_id"`
+	Scopes        []string   `json:"scopes"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+
+	// Открытое значение возвращается только при выпуске, в БД лежит хеш
+	Token string `json:"token,omitempty"`
+}
+
+type IssueTokenRequest struct {
+	Name          string   `json:"name"`
+	PrincipalType string   `json:"principal_type"`
+	PrincipalID   string   `json:"principal_id"`
+	Scopes        []string `json:"scopes"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type CodeOwnersRule struct {
+	Line    int      `json:"line"`
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+}
+
+type CodeOwners struct {
+	TeamName  string           `json:"team_name"`
+	Content   string           `json:"content"`
+	Rules     []CodeOwnersRule `json:"rules"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+type CodeOwnerReviewer struct {
+	UserID string          `json:"user_id"`
+	Files  []CodeOwnedFile `json:"files"`
+}
+
+// CodeOwnedFile - файл и правило, которое назначило владельца именно ему
+type CodeOwnedFile struct {
+	Path    string `json:"path"`
+	Pattern string `json:"pattern"`
+}
@@ -6,7 +6,40 @@ var (
 	ErrTeamExists  = errors.New("TEAM_EXISTS")
 	ErrPRExists    = errors.New("PR_EXISTS")
 	ErrPRMerged    = errors.New("PR_MERGED")
+	ErrPRClosed    = errors.New("PR_CLOSED")
 	ErrNotAssigned = errors.New("NOT_ASSIGNED")
 	ErrNoCandidate = errors.New("NO_CANDIDATE")
 	ErrNotFound    = errors.New("NOT_FOUND")
+
+	ErrInvalidReviewDecision = errors.New("INVALID_REVIEW_DECISION")
+
+	ErrInvalidCodeOwners = errors.New("INVALID_CODEOWNERS")
+	ErrInvalidTeamMember = errors.New("INVALID_TEAM_MEMBER")
+
+	ErrInvalidSignature = errors.New("INVALID_SIGNATURE")
+	ErrInvalidPayload   = errors.New("INVALID_PAYLOAD")
+	ErrUnmappedUser     = errors.New("UNMAPPED_USER")
+
+	ErrInvalidSubscription = errors.New("INVALID_SUBSCRIPTION")
+	ErrInvalidChatTarget   = errors.New("INVALID_CHAT_TARGET")
+
+	ErrInvalidSLA         = errors.New("INVALID_SLA")
+	ErrInvalidStalePolicy = errors.New("INVALID_STALE_POLICY")
+
+	ErrInvalidQuery = errors.New("INVALID_QUERY")
+
+	ErrUnauthorized      = errors.New("UNAUTHORIZED")
+	ErrInsufficientScope = errors.New("INSUFFICIENT_SCOPE")
+	ErrInvalidToken      = errors.New("INVALID_TOKEN")
+	ErrForbidden         = errors.New("FORBIDDEN")
+	ErrInvalidRole       = errors.New("INVALID_ROLE")
+
+	ErrUserExists  = errors.New("USER_EXISTS")
+	ErrInvalidSCIM = errors.New("INVALID_SCIM")
+
+	ErrIdempotencyKeyReused  = errors.New("IDEMPOTENCY_KEY_REUSED")
+	ErrIdempotencyInProgress = errors.New("IDEMPOTENCY_IN_PROGRESS")
+	ErrInvalidIdempotencyKey = errors.New("INVALID_IDEMPOTENCY_KEY")
+
+	ErrInvalidBatch = errors.New("INVALID_BATCH")
 )
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventReviewerAssigned   = "reviewer.assigned"
+	EventReviewerReassigned = "reviewer.reassigned"
+	EventPRMerged           = "pr.merged"
+	EventSLABreached        = "pr.sla_breached"
+	EventPRStale            = "pr.stale"
+	EventPRClosed           = "pr.closed"
+	EventPRReopened         = "pr.reopened"
+	EventUserActiveChanged  = "user.active_changed"
+	EventTeamCreated        = "team.created"
+)
+
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// ReviewEvent - data для событий о назначении ревьюеров и merge
+type ReviewEvent struct {
+	PullRequest *PullRequest `json:"pull_request"`
+	TeamName    string       `json:"team_name,omitempty"`
+	Reviewers   []string     `json:"reviewers,omitempty"`
+	OldReviewer string       `json:"old_reviewer,omitempty"`
+	NewReviewer string       `json:"new_reviewer,omitempty"`
+}
+
+// OutboxDelivery - доставка события outbox одному sink. Attempts уже
+// включает текущую попытку.
+type OutboxDelivery struct {
+	OutboxID  int64      `json:"outbox_id"`
+	Sink      string     `json:"sink"`
+	Event     Event      `json:"event"`
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error,omitempty"`
+	DeadAt    *time.Time `json:"dead_at,omitempty"`
+}
+
+// StreamMessage - событие с порядковым номером потока (id в SSE)
+type StreamMessage struct {
+	Seq   uint64
+	Event Event
+}
+
+type StreamFilter struct {
+	UserID   string
+	TeamName string
+}
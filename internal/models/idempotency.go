@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyRecord - запрос с Idempotency-Key и, после выполнения, его ответ.
+// Scope - клиент (токен или субъект), ключи разных клиентов не пересекаются.
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+
+	PRSortCreatedAt = "created_at"
+	PRSortUpdatedAt = "updated_at"
+
+	UserSortUserID   = "user_id"
+	UserSortUsername = "username"
+
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// PRListQuery - фильтры, сортировка и страница списка PR. Пустые поля не
+// фильтруют; After - позиция из курсора предыдущей страницы.
+type PRListQuery struct {
+	ReviewerID  string
+	AuthorID    string
+	Status      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        string
+	Order       string
+	Limit       int
+	Cursor      string
+	After       *PRCursor
+}
+
+// PRCursor - ключ сортировки и pull_request_id последней строки страницы
+type PRCursor struct {
+	Time time.Time
+	ID   string
+}
+
+type PRPage struct {
+	PullRequests []PullRequestShort `json:"pull_requests"`
+	NextCursor   string             `json:"next_cursor,omitempty"`
+}
+
+type UserListQuery struct {
+	TeamName string
+	IsActive *bool
+	Sort     string
+	Order    string
+	Limit    int
+	Cursor   string
+	After    *UserCursor
+}
+
+// UserCursor - ключ сортировки и user_id последней строки страницы
+type UserCursor struct {
+	Key string
+	ID  string
+}
+
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// TeamPage - команда со страницей участников
+type TeamPage struct {
+	TeamName   string `json:"team_name"`
+	Members    []User `json:"members"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+const (
+	ChatScopeUser = "user"
+	ChatScopeTeam = "team"
+
+	ChatFormatSlack      = "slack"
+	ChatFormatMattermost = "mattermost"
+)
+
+// ChatTarget - incoming webhook чата для пользователя или команды. Адрес
+// webhook'а - сам по себе секрет, наружу отдаётся только его хост.
+type ChatTarget struct {
+	Scope       string `json:"scope"`
+	TargetID    string `json:"target_id"`
+	WebhookURL  string `json:"-"`
+	WebhookHost string `json:"webhook_host,omitempty"`
+	Format      string `json:"format"`
+}
+
+type SetChatTargetRequest struct {
+	Scope      string `json:"scope"`
+	TargetID   string `json:"target_id"`
+	WebhookURL string `json:"webhook_url"`
+	Format     string `json:"format"`
+}
+
+// ChatMessage - пачка строк для одного webhook, оформляет её Notifier
+type ChatMessage struct {
+	Title string
+	Lines []string
+}
+
+// ChatTemplateData - то, что доступно в шаблонах сообщений
+type ChatTemplateData struct {
+	Reviewer        string
+	OldReviewer     string
+	PullRequestID   string
+	PullRequestName string
+	AuthorID        string
+	TeamName        string
+}
+
+// EmailMessage - письмо с текстовой и HTML-версией
+type EmailMessage struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
@@ -1,13 +1,44 @@
 package models
 
 type User struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	TeamName string `json:"team_name"`
-	IsActive bool   `json:"is_active"`
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	TeamName  string   `json:"team_name"`
+	IsActive  bool     `json:"is_active"`
+	Skills    []string `json:"skills,omitempty"`
+	PathGlobs []string `json:"path_globs,omitempty"`
+	Email     string   `json:"email,omitempty"`
+
+	DigestOptOut bool `json:"digest_opt_out,omitempty"`
+
+	// Роль задаётся только через /users/setRole, при загрузке команды не меняется
+	Role string `json:"role,omitempty"`
 }
 
 type Team struct {
 	TeamName string `json:"team_name"`
-	Members  []User `json:"members"` 
+	Members  []User `json:"members"`
+}
+
+// AddTeamMemberRequest - лид добавляет участника в свою команду
+type AddTeamMemberRequest struct {
+	TeamName string          `json:"team_name"`
+	Member   TeamMemberInput `json:"member"`
+}
+
+// TeamMemberInput - участник в AddTeamMemberRequest. Без is_active
+// существующий пользователь сохраняет активность, новый создаётся активным.
+type TeamMemberInput struct {
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	IsActive  *bool    `json:"is_active,omitempty"`
+	Skills    []string `json:"skills,omitempty"`
+	PathGlobs []string `json:"path_globs,omitempty"`
+	Email     string   `json:"email,omitempty"`
+}
+
+// RemoveTeamMemberRequest - участник уходит в команду по умолчанию
+type RemoveTeamMemberRequest struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
 }
@@ -0,0 +1,72 @@
+package models
+
+import "encoding/json"
+
+const (
+	SCIMSchemaUser      = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup     = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaList      = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatch     = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError     = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMResourceUser    = "User"
+	SCIMResourceGroup   = "Group"
+	SCIMDefaultPageSize = 100
+)
+
+// SCIMUser: id и userName - users.user_id, displayName - username,
+// команда задаётся через членство в группе
+type SCIMUser struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Active      *bool        `json:"active,omitempty"`
+	Emails      []SCIMEmail  `json:"emails,omitempty"`
+	Groups      []SCIMMember `json:"groups,omitempty"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMGroup: id и displayName - teams.name
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members,omitempty"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
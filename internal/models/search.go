@@ -0,0 +1,43 @@
+package models
+
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 50
+	MaxSearchQueryLen  = 200
+)
+
+// SearchQuery - подготовленный поисковый запрос: исходный текст для
+// триграмм, префиксный tsquery и LIKE-шаблон префикса идентификатора
+type SearchQuery struct {
+	Text    string
+	TSQuery string
+	Prefix  string
+	Limit   int
+}
+
+type PRSearchHit struct {
+	PullRequestShort
+	Rank float64 `json:"rank"`
+}
+
+type UserSearchHit struct {
+	UserID   string  `json:"user_id"`
+	Username string  `json:"username"`
+	TeamName string  `json:"team_name"`
+	IsActive bool    `json:"is_active"`
+	Rank     float64 `json:"rank"`
+}
+
+type TeamSearchHit struct {
+	TeamName string  `json:"team_name"`
+	Rank     float64 `json:"rank"`
+}
+
+// SearchResults - найденное, сгруппированное по типу сущности; внутри
+// группы по убыванию релевантности
+type SearchResults struct {
+	Query        string          `json:"query"`
+	PullRequests []PRSearchHit   `json:"pull_requests"`
+	Users        []UserSearchHit `json:"users"`
+	Teams        []TeamSearchHit `json:"teams"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	SLAPolicyNotify   = "notify"
+	SLAPolicyReassign = "reassign"
+)
+
+// TeamSLA - срок первого ревью для команды. WorkStart/WorkEnd ("09:00")
+// задают рабочее время (пн-пт), пустые - срок считается по часам подряд.
+type TeamSLA struct {
+	TeamName      string    `json:"team_name"`
+	ResponseHours int       `json:"response_hours"`
+	Policy        string    `json:"policy"`
+	WorkStart     string    `json:"work_start,omitempty"`
+	WorkEnd       string    `json:"work_end,omitempty"`
+	Timezone      string    `json:"timezone,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SLADuePR - открытый PR с истёкшим сроком SLA и команда его автора
+type SLADuePR struct {
+	PullRequest PullRequest
+	TeamName    string
+}
+
+type SLAEscalation struct {
+	ID            int64     `json:"id"`
+	PullRequestID string    `json:"pull_request_id"`
+	TeamName      string    `json:"team_name"`
+	Action        string    `json:"action"`
+	ReviewerID    string    `json:"reviewer_id,omitempty"`
+	NewReviewerID string    `json:"new_reviewer_id,omitempty"`
+	DueAt         time.Time `json:"due_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
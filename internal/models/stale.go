@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	StaleActionMark  = "mark_stale"
+	StaleActionClose = "close"
+)
+
+// StalePolicy - через StaleAfterDays дней без изменений PR помечается stale,
+// ещё через CloseAfterDays - закрывается. CloseAfterDays = 0 - не закрывать.
+type StalePolicy struct {
+	TeamName       string    `json:"team_name"`
+	StaleAfterDays int       `json:"stale_after_days"`
+	CloseAfterDays int       `json:"close_after_days"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// StaleCandidate - открытый PR, под который попадает политика его команды
+type StaleCandidate struct {
+	PullRequest PullRequest
+	TeamName    string
+	Policy      StalePolicy
+}
+
+type StaleAction struct {
+	Action            string     `json:"action"`
+	PullRequestID     string     `json:"pull_request_id"`
+	PullRequestName   string     `json:"pull_request_name"`
+	AuthorID          string     `json:"author_id"`
+	TeamName          string     `json:"team_name"`
+	AssignedReviewers []string   `json:"assigned_reviewers"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+	StaleAt           *time.Time `json:"stale_at,omitempty"`
+}
+
+// StalePlan - действия политики на текущий момент. Truncated - кандидатов
+// больше, чем вошло в план; остальные попадут в следующий проход.
+type StalePlan struct {
+	Actions   []StaleAction `json:"actions"`
+	Truncated bool          `json:"truncated"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	TimelineCreated            = "created"
+	TimelineReviewerAssigned   = "reviewer_assigned"
+	TimelineReviewerUnassigned = "reviewer_unassigned"
+	TimelineApproved           = "approved"
+	TimelineChangesRequested   = "changes_requested"
+	TimelineSLABreached        = "sla_breached"
+	TimelineMarkedStale        = "marked_stale"
+	TimelineMerged             = "merged"
+	TimelineClosed             = "closed"
+	TimelineReopened           = "reopened"
+)
+
+// Причины назначения / снятия ревьюера
+const (
+	TimelineReasonInitial      = "initial"
+	TimelineReasonReassign     = "reassign"
+	TimelineReasonSLA          = "sla"
+	TimelineReasonDeactivation = "deactivation"
+	TimelineReasonTeamChange   = "team_change"
+	TimelineReasonClosed       = "closed"
+	TimelineReasonReopened     = "reopened"
+)
+
+type TimelineEvent struct {
+	ID            int64     `json:"id"`
+	PullRequestID string    `json:"pull_request_id"`
+	Event         string    `json:"event"`
+	UserID        string    `json:"user_id,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	Actor         string    `json:"actor,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
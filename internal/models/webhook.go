@@ -0,0 +1,25 @@
+package models
+
+// WebhookDelivery - входящее событие от внешней системы (GitHub, GitLab) как есть
+type WebhookDelivery struct {
+	DeliveryID string
+	Event      string
+	Signature  string
+	Body       []byte
+}
+
+type WebhookResult struct {
+	DeliveryID    string `json:"delivery_id"`
+	Action        string `json:"action"`
+	PullRequestID string `json:"pull_request_id,omitempty"`
+}
+
+const (
+	WebhookActionCreated   = "created"
+	WebhookActionUpdated   = "updated"
+	WebhookActionMerged    = "merged"
+	WebhookActionClosed    = "closed"
+	WebhookActionReopened  = "reopened"
+	WebhookActionIgnored   = "ignored"
+	WebhookActionDuplicate = "duplicate"
+)
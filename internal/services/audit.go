@@ -0,0 +1,155 @@
+package services
+
+/*
+Журнал аудита:
+	1. Запись действия над командой, пользователем или PR в той же
+	   транзакции, что и само изменение
+	2. Чтение журнала по сущности страницами
+
+Автор действия - аутентифицированный Principal запроса либо явно заданный
+через WithAuditActor (фоновые задачи пишут от имени "system"). ID запроса
+берётся из WithRequestID.
+Before/After - состояние сущности до и после изменения, nil - не было.
+
+В Before/After бывают email и роли, поэтому журнал читает не всякий:
+команды - её лид, пользователя - он сам или лид его команды, PR - так же,
+как пользователя-автора. Админ видит всё.
+*/
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const auditSystemActor = "system"
+
+type auditActorKey struct{}
+
+type requestIDKey struct{}
+
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActor(ctx context.Context) string {
+	if actor, ok := ctx.Value(auditActorKey{}).(string); ok {
+		return actor
+	}
+	if p := PrincipalFrom(ctx); p != nil {
+		return p.Type + ":" + p.ID
+	}
+	return ""
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+func recordAudit(ctx context.Context, tx pgx.Tx, audit storage.AuditStorage, entity string, entityID string, action string, before interface{}, after interface{}) error {
+	beforeRaw, err := marshalAuditState(before)
+	if err != nil {
+		return err
+	}
+	afterRaw, err := marshalAuditState(after)
+	if err != nil {
+		return err
+	}
+
+	return audit.AddAuditTx(ctx, tx, models.AuditEntry{
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		Actor:     auditActor(ctx),
+		RequestID: RequestID(ctx),
+		Before:    beforeRaw,
+		After:     afterRaw,
+	})
+}
+
+func marshalAuditState(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	return json.Marshal(state)
+}
+
+type AuditService struct {
+	storage   storage.AuditStorage
+	prStorage storage.PullReqStorage
+	authz     *Authorizer
+}
+
+func NewAuditService(storage storage.AuditStorage, prStorage storage.PullReqStorage, authz *Authorizer) *AuditService {
+	return &AuditService{storage: storage, prStorage: prStorage, authz: authz}
+}
+
+// List - страница журнала; cursor - next_cursor предыдущей страницы
+func (s *AuditService) List(ctx context.Context, entity string, entityID string, cursor string, limit int) (*models.AuditPage, error) {
+	var beforeID int64
+	if cursor != "" {
+		var err error
+		beforeID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return nil, fmt.Errorf("%w: invalid cursor", models.ErrInvalidQuery)
+		}
+	}
+
+	tx, err := s.prStorage.PRBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.authorize(ctx, tx, entity, entityID); err != nil {
+		return nil, err
+	}
+
+	// лишняя запись показывает, есть ли следующая страница
+	entries, err := s.storage.ListAuditTx(ctx, tx, entity, entityID, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	page := &models.AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = strconv.FormatInt(page.Entries[limit-1].ID, 10)
+	}
+	if page.Entries == nil {
+		page.Entries = []models.AuditEntry{}
+	}
+	return page, nil
+}
+
+func (s *AuditService) authorize(ctx context.Context, tx pgx.Tx, entity string, entityID string) error {
+	switch entity {
+	case models.AuditEntityTeam:
+		return s.authz.RequireTeamLead(ctx, tx, entityID)
+	case models.AuditEntityUser:
+		return s.authz.RequireUserAccess(ctx, tx, entityID)
+	case models.AuditEntityPR:
+		pr, err := s.prStorage.GetPRByIDTx(ctx, tx, entityID)
+		if err == models.ErrNotFound {
+			return s.authz.RequireAdmin(ctx, tx)
+		}
+		if err != nil {
+			return err
+		}
+		return s.authz.RequireUserAccess(ctx, tx, pr.AuthorID)
+	default:
+		return s.authz.RequireAdmin(ctx, tx)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAudit struct {
+	storage.AuditStorage
+	entries []models.AuditEntry
+}
+
+func (f *fakeAudit) AddAuditTx(ctx context.Context, tx pgx.Tx, entry models.AuditEntry) error {
+	entry.ID = int64(len(f.entries) + 1)
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func (f *fakeAudit) ListAuditTx(ctx context.Context, tx pgx.Tx, entity string, entityID string, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	var res []models.AuditEntry
+	for i := len(f.entries) - 1; i >= 0 && len(res) < limit; i-- {
+		e := f.entries[i]
+		if e.Entity != entity || e.EntityID != entityID || (beforeID != 0 && e.ID >= beforeID) {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func TestRecordAudit_ActorAndRequestFromContext(t *testing.T) {
+	audit := &fakeAudit{}
+	ctx := WithRequestID(WithAuditActor(context.Background(), "alice"), "req-1")
+
+	before := &models.User{UserID: "u1", IsActive: true}
+	after := &models.User{UserID: "u1", IsActive: false}
+	require.NoError(t, recordAudit(ctx, &fakeTx{}, audit, models.AuditEntityUser, "u1", "active_changed", before, after))
+	require.NoError(t, recordAudit(ctx, &fakeTx{}, audit, models.AuditEntityUser, "u2", "created", nil, after))
+
+	require.Len(t, audit.entries, 2)
+	assert.Equal(t, "alice", audit.entries[0].Actor)
+	assert.Equal(t, "req-1", audit.entries[0].RequestID)
+	assert.JSONEq(t, `{"user_id":"u1","username":"","team_name":"","is_active":true}`, string(audit.entries[0].Before))
+	assert.Nil(t, audit.entries[1].Before)
+}
+
+func TestAuditService_ListPages(t *testing.T) {
+	audit := &fakeAudit{}
+	for _, action := range []string{"created", "reviewer_reassigned", "merged"} {
+		require.NoError(t, recordAudit(context.Background(), &fakeTx{}, audit, models.AuditEntityPR, "pr-1", action, nil, nil))
+	}
+	svc := NewAuditService(audit, &fakeReviewPRs{pr: models.PullRequest{PullRequestID: "pr-1", AuthorID: "author"}}, NewAuthorizer(&fakeRoleUsers{users: map[string]models.User{
+		"author": {UserID: "author", TeamName: "backend", Role: models.RoleMember},
+	}}))
+
+	page, err := svc.List(asUser("author"), models.AuditEntityPR, "pr-1", "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+	assert.Equal(t, "merged", page.Entries[0].Action)
+	assert.Equal(t, "2", page.NextCursor)
+
+	page, err = svc.List(asUser("author"), models.AuditEntityPR, "pr-1", page.NextCursor, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, "created", page.Entries[0].Action)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = svc.List(asUser("author"), models.AuditEntityPR, "pr-1", "abc", 2)
+	assert.True(t, errors.Is(err, models.ErrInvalidQuery))
+}
+
+func TestAuditService_ListRequiresAccess(t *testing.T) {
+	audit := &fakeAudit{}
+	prs := &fakeReviewPRs{pr: models.PullRequest{PullRequestID: "pr-1", AuthorID: "author"}}
+	svc := NewAuditService(audit, prs, NewAuthorizer(&fakeRoleUsers{users: map[string]models.User{
+		"admin":  {UserID: "admin", TeamName: "ops", Role: models.RoleAdmin},
+		"lead":   {UserID: "lead", TeamName: "backend", Role: models.RoleLead},
+		"author": {UserID: "author", TeamName: "backend", Role: models.RoleMember},
+		"other":  {UserID: "other", TeamName: "backend", Role: models.RoleMember},
+	}}))
+
+	for _, c := range []struct {
+		entity, id, caller string
+		allowed            bool
+	}{
+		{models.AuditEntityTeam, "backend", "lead", true},
+		{models.AuditEntityTeam, "backend", "author", false},
+		{models.AuditEntityUser, "author", "author", true},
+		{models.AuditEntityUser, "author", "lead", true},
+		{models.AuditEntityUser, "author", "other", false},
+		{models.AuditEntityPR, "pr-1", "author", true},
+		{models.AuditEntityPR, "pr-1", "lead", true},
+		{models.AuditEntityPR, "pr-1", "other", false},
+		{models.AuditEntityPR, "pr-gone", "lead", false},
+		{models.AuditEntityPR, "pr-gone", "admin", true},
+	} {
+		_, err := svc.List(asUser(c.caller), c.entity, c.id, "", 10)
+		if c.allowed {
+			assert.NoError(t, err, "%s %s as %s", c.entity, c.id, c.caller)
+		} else {
+			assert.Equal(t, models.ErrForbidden, err, "%s %s as %s", c.entity, c.id, c.caller)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+/*
+Аутентификация по API-токенам:
+	1. Выпуск токена пользователю или сервисному аккаунту со скоупами
+	2. Проверка токена из запроса -> Principal
+	3. Список и отзыв токенов
+
+Открытое значение токена показывается один раз при выпуске, в БД хранится
+SHA-256 хеш: токен случайный (256 бит), соль и медленный хеш не нужны.
+Bootstrap-токен из конфига - админ без записи в БД, чтобы выпустить первые
+токены. Если настроен JWT, токены вида xxx.yyy.zzz проверяет JWTAuthenticator.
+*/
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+)
+
+const (
+	apiTokenPrefix     = "rvw_"
+	bootstrapPrincipal = "bootstrap"
+)
+
+var knownScopes = map[string]bool{
+	models.ScopeRead:  true,
+	models.ScopeWrite: true,
+	models.ScopeAdmin: true,
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// SystemContext - контекст фоновой задачи или вебхука: действия выполняются
+// от имени системы, name попадает в аудит ("system:<name>")
+func SystemContext(ctx context.Context, name string) context.Context {
+	return WithPrincipal(ctx, &models.Principal{Type: models.PrincipalSystem, ID: name})
+}
+
+// PrincipalFrom - субъект запроса или системный Principal задачи;
+// nil - контекст никем не помечен, Authorizer в нём всё запрещает
+func PrincipalFrom(ctx context.Context) *models.Principal {
+	p, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return p
+}
+
+type AuthService struct {
+	storage        storage.APITokenStorage
+	userStorage    storage.UserStorage
+	bootstrapToken string
+	jwt            *JWTAuthenticator
+}
+
+// jwt - nil, если SSO не настроен
+func NewAuthService(storage storage.APITokenStorage, userStorage storage.UserStorage, bootstrapToken string, jwt *JWTAuthenticator) *AuthService {
+	return &AuthService{
+		storage:        storage,
+		userStorage:    userStorage,
+		bootstrapToken: bootstrapToken,
+		jwt:            jwt,
+	}
+}
+
+func (s *AuthService) Authenticate(ctx context.Context, rawToken string) (*models.Principal, error) {
+	if rawToken == "" {
+		return nil, models.ErrUnauthorized
+	}
+
+	if s.bootstrapToken != "" && subtle.ConstantTimeCompare([]byte(rawToken), []byte(s.bootstrapToken)) == 1 {
+		return &models.Principal{
+			Type:   models.PrincipalService,
+			ID:     bootstrapPrincipal,
+			Scopes: []string{models.ScopeAdmin},
+		}, nil
+	}
+
+	if s.jwt != nil && looksLikeJWT(rawToken) {
+		return s.jwt.Authenticate(ctx, rawToken)
+	}
+
+	token, err := s.storage.GetAPITokenByHashTx(ctx, nil, hashAPIToken(rawToken))
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, models.ErrUnauthorized
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, models.ErrUnauthorized
+	}
+
+	return &models.Principal{
+		Type:    token.PrincipalType,
+		ID:      token.PrincipalID,
+		Scopes:  token.Scopes,
+		TokenID: token.ID,
+	}, nil
+}
+
+func (s *AuthService) IssueToken(ctx context.Context, req models.IssueTokenRequest) (*models.APIToken, error) {
+	if req.Name == "" || req.PrincipalID == "" {
+		return nil, fmt.Errorf("%w: name and principal_id are required", models.ErrInvalidToken)
+	}
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", models.ErrInvalidToken)
+	}
+	for _, scope := range req.Scopes {
+		if !knownScopes[scope] {
+			return nil, fmt.Errorf("%w: unknown scope %q", models.ErrInvalidToken, scope)
+		}
+	}
+
+	switch req.PrincipalType {
+	case models.PrincipalUser:
+		if _, err := s.userStorage.GetUserTx(ctx, nil, req.PrincipalID); err != nil {
+			if err == models.ErrNotFound {
+				return nil, fmt.Errorf("%w: unknown user %q", models.ErrInvalidToken, req.PrincipalID)
+			}
+			return nil, err
+		}
+	case models.PrincipalService:
+	default:
+		return nil, fmt.Errorf("%w: principal_type must be user or service", models.ErrInvalidToken)
+	}
+
+	raw, err := newAPIToken()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.storage.CreateAPITokenTx(ctx, nil, models.APIToken{
+		Name:          req.Name,
+		PrincipalType: req.PrincipalType,
+		PrincipalID:   req.PrincipalID,
+		Scopes:        req.Scopes,
+	}, hashAPIToken(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	created.Token = raw
+	return created, nil
+}
+
+func (s *AuthService) ListTokens(ctx context.Context) ([]models.APIToken, error) {
+	tokens, err := s.storage.ListAPITokensTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []models.APIToken{}
+	}
+	return tokens, nil
+}
+
+func (s *AuthService) RevokeToken(ctx context.Context, id int64) error {
+	return s.storage.RevokeAPITokenTx(ctx, nil, id)
+}
+
+func newAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPITokens struct {
+	storage.APITokenStorage
+	byHash map[string]*models.APIToken
+}
+
+func (f *fakeAPITokens) CreateAPITokenTx(ctx context.Context, tx pgx.Tx, token models.APIToken, tokenHash string) (*models.APIToken, error) {
+	token.ID = int64(len(f.byHash) + 1)
+	f.byHash[tokenHash] = &token
+	created := token
+	return &created, nil
+}
+
+func (f *fakeAPITokens) GetAPITokenByHashTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.APIToken, error) {
+	token, ok := f.byHash[tokenHash]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return token, nil
+}
+
+func (f *fakeAPITokens) RevokeAPITokenTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	for _, token := range f.byHash {
+		if token.ID == id {
+			now := time.Now()
+			token.RevokedAt = &now
+			return nil
+		}
+	}
+	return models.ErrNotFound
+}
+
+type fakeAuthUsers struct {
+	storage.UserStorage
+}
+
+func (f *fakeAuthUsers) GetUserTx(ctx context.Context, tx pgx.Tx, userID string) (*models.User, error) {
+	if userID != "u1" {
+		return nil, models.ErrNotFound
+	}
+	return &models.User{UserID: "u1"}, nil
+}
+
+func TestAuthService_IssueAuthenticateRevoke(t *testing.T) {
+	store := &fakeAPITokens{byHash: map[string]*models.APIToken{}}
+	svc := NewAuthService(store, &fakeAuthUsers{}, "", nil)
+	ctx := context.Background()
+
+	issued, err := svc.IssueToken(ctx, models.IssueTokenRequest{
+		Name:          "ci",
+		PrincipalType: models.PrincipalUser,
+		PrincipalID:   "u1",
+		Scopes:        []string{models.ScopeRead},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, issued.Token)
+	assert.NotContains(t, store.byHash, issued.Token, "only the hash is stored")
+
+	p, err := svc.Authenticate(ctx, issued.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", p.ID)
+	assert.True(t, p.HasScope(models.ScopeRead))
+	assert.False(t, p.HasScope(models.ScopeWrite))
+
+	require.NoError(t, svc.RevokeToken(ctx, issued.ID))
+	_, err = svc.Authenticate(ctx, issued.Token)
+	assert.Equal(t, models.ErrUnauthorized, err)
+
+	_, err = svc.Authenticate(ctx, "rvw_unknown")
+	assert.Equal(t, models.ErrUnauthorized, err)
+}
+
+func TestAuthService_Bootstrap(t *testing.T) {
+	svc := NewAuthService(&fakeAPITokens{byHash: map[string]*models.APIToken{}}, &fakeAuthUsers{}, "bootstrap-secret", nil)
+
+	p, err := svc.Authenticate(context.Background(), "bootstrap-secret")
+	require.NoError(t, err)
+	assert.True(t, p.HasScope(models.ScopeAdmin))
+	assert.True(t, p.HasScope(models.ScopeWrite))
+
+	_, err = svc.Authenticate(context.Background(), "")
+	assert.Equal(t, models.ErrUnauthorized, err)
+}
+
+func TestAuthService_IssueValidation(t *testing.T) {
+	svc := NewAuthService(&fakeAPITokens{byHash: map[string]*models.APIToken{}}, &fakeAuthUsers{}, "", nil)
+
+	for _, req := range []models.IssueTokenRequest{
+		{Name: "x", PrincipalType: models.PrincipalService, PrincipalID: "ci", Scopes: []string{"root"}},
+		{Name: "x", PrincipalType: models.PrincipalService, PrincipalID: "ci"},
+		{Name: "x", PrincipalType: "robot", PrincipalID: "ci", Scopes: []string{models.ScopeRead}},
+		{Name: "x", PrincipalType: models.PrincipalUser, PrincipalID: "ghost", Scopes: []string{models.ScopeRead}},
+	} {
+		_, err := svc.IssueToken(context.Background(), req)
+		assert.True(t, errors.Is(err, models.ErrInvalidToken), "%+v: %v", req, err)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+/*
+Авторизация на уровне сервисов - действует для любого транспорта
+(HTTP, вебхуки, фоновые задачи):
+	1. Команды создаёт только админ
+	2. Лид управляет своей командой: активность участников, политики
+	   (CODEOWNERS, SLA, stale)
+	3. Переназначить ревьюера может автор PR, назначенный ревьюер или лид
+	   команды автора; смёржить - автор или лид
+
+Админ - токен со скоупом admin или пользователь с ролью admin.
+Фоновые задачи и вебхуки с проверенной подписью явно ставят себе системный
+Principal (SystemContext) - ему разрешено всё. Без Principal в контексте
+всё запрещено: забытая аутентификация не должна превращаться в права системы.
+*/
+import (
+	"context"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Authorizer struct {
+	userStorage storage.UserStorage
+}
+
+func NewAuthorizer(userStorage storage.UserStorage) *Authorizer {
+	return &Authorizer{userStorage: userStorage}
+}
+
+func (a *Authorizer) RequireAdmin(ctx context.Context, tx pgx.Tx) error {
+	p := PrincipalFrom(ctx)
+	if p == nil {
+		return models.ErrForbidden
+	}
+	if p.Type == models.PrincipalSystem {
+		return nil
+	}
+
+	user, err := a.principalUser(ctx, tx, p)
+	if err != nil {
+		return err
+	}
+	if isAdmin(p, user) {
+		return nil
+	}
+	return models.ErrForbidden
+}
+
+// RequireTeamLead - админ или лид команды teamName
+func (a *Authorizer) RequireTeamLead(ctx context.Context, tx pgx.Tx, teamName string) error {
+	p := PrincipalFrom(ctx)
+	if p == nil {
+		return models.ErrForbidden
+	}
+	if p.Type == models.PrincipalSystem {
+		return nil
+	}
+
+	user, err := a.principalUser(ctx, tx, p)
+	if err != nil {
+		return err
+	}
+	if isAdmin(p, user) || isLeadOf(user, teamName) {
+		return nil
+	}
+	return models.ErrForbidden
+}
+
+// RequireSelfOrLead - сам пользователь, лид его команды или админ
+func (a *Authorizer) RequireSelfOrLead(ctx context.Context, tx pgx.Tx, target *models.User) error {
+	p := PrincipalFrom(ctx)
+	if p != nil && p.Type == models.PrincipalUser && p.ID == target.UserID {
+		return nil
+	}
+	return a.RequireTeamLead(ctx, tx, target.TeamName)
+}
+
+// RequireUserAccess - то же, что RequireSelfOrLead, по id пользователя.
+// К неизвестному пользователю доступ только у админа.
+func (a *Authorizer) RequireUserAccess(ctx context.Context, tx pgx.Tx, userID string) error {
+	p := PrincipalFrom(ctx)
+	if p != nil && p.Type == models.PrincipalUser && p.ID == userID {
+		return nil
+	}
+
+	target, err := a.userStorage.GetUserTx(ctx, tx, userID)
+	if err == models.ErrNotFound {
+		return a.RequireAdmin(ctx, tx)
+	}
+	if err != nil {
+		return err
+	}
+	return a.RequireTeamLead(ctx, tx, target.TeamName)
+}
+
+// CanReassign - автор, назначенный ревьюер, лид команды автора или админ
+func (a *Authorizer) CanReassign(ctx context.Context, tx pgx.Tx, pr *models.PullRequest, authorTeam string) error {
+	p := PrincipalFrom(ctx)
+	if p != nil && p.Type == models.PrincipalUser && contains(pr.AssignedReviewers, p.ID) {
+		return nil
+	}
+	return a.CanMerge(ctx, tx, pr, authorTeam)
+}
+
+// CanMerge - автор, лид команды автора или админ
+func (a *Authorizer) CanMerge(ctx context.Context, tx pgx.Tx, pr *models.PullRequest, authorTeam string) error {
+	p := PrincipalFrom(ctx)
+	if p != nil && p.Type == models.PrincipalUser && p.ID == pr.AuthorID {
+		return nil
+	}
+	return a.RequireTeamLead(ctx, tx, authorTeam)
+}
+
+// principalUser - пользователь за токеном, nil для сервисных аккаунтов
+// и удалённых пользователей
+func (a *Authorizer) principalUser(ctx context.Context, tx pgx.Tx, p *models.Principal) (*models.User, error) {
+	if p.Type != models.PrincipalUser || p.HasScope(models.ScopeAdmin) {
+		return nil, nil
+	}
+
+	user, err := a.userStorage.GetUserTx(ctx, tx, p.ID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func isAdmin(p *models.Principal, user *models.User) bool {
+	return p.HasScope(models.ScopeAdmin) || (user != nil && user.Role == models.RoleAdmin)
+}
+
+func isLeadOf(user *models.User, teamName string) bool {
+	return user != nil && user.Role == models.RoleLead && user.TeamName == teamName
+}
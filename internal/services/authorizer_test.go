@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRoleUsers struct {
+	storage.UserStorage
+	users map[string]models.User
+}
+
+func (f *fakeRoleUsers) GetUserTx(ctx context.Context, tx pgx.Tx, userID string) (*models.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &user, nil
+}
+
+func asUser(userID string) context.Context {
+	return WithPrincipal(context.Background(), &models.Principal{
+		Type:   models.PrincipalUser,
+		ID:     userID,
+		Scopes: []string{models.ScopeRead, models.ScopeWrite},
+	})
+}
+
+// asAdmin - токен со скоупом admin, как у SCIM-клиента
+func asAdmin() context.Context {
+	return WithPrincipal(context.Background(), &models.Principal{
+		Type:   models.PrincipalService,
+		ID:     "ops",
+		Scopes: []string{models.ScopeAdmin},
+	})
+}
+
+func TestAuthorizer(t *testing.T) {
+	authz := NewAuthorizer(&fakeRoleUsers{users: map[string]models.User{
+		"admin":  {UserID: "admin", TeamName: "ops", Role: models.RoleAdmin},
+		"lead":   {UserID: "lead", TeamName: "backend", Role: models.RoleLead},
+		"lead2":  {UserID: "lead2", TeamName: "frontend", Role: models.RoleLead},
+		"author": {UserID: "author", TeamName: "backend", Role: models.RoleMember},
+		"rev":    {UserID: "rev", TeamName: "backend", Role: models.RoleMember},
+		"other":  {UserID: "other", TeamName: "backend", Role: models.RoleMember},
+	}})
+	pr := &models.PullRequest{PullRequestID: "pr-1", AuthorID: "author", AssignedReviewers: []string{"rev"}}
+	service := WithPrincipal(context.Background(), &models.Principal{Type: models.PrincipalService, ID: "ci", Scopes: []string{models.ScopeWrite}})
+	adminToken := WithPrincipal(context.Background(), &models.Principal{Type: models.PrincipalService, ID: "ops", Scopes: []string{models.ScopeAdmin}})
+
+	assert.Equal(t, models.ErrForbidden, authz.RequireAdmin(context.Background(), nil), "no principal")
+	assert.Equal(t, models.ErrForbidden, authz.RequireTeamLead(context.Background(), nil, "backend"), "no principal")
+	assert.Equal(t, models.ErrForbidden, authz.CanMerge(context.Background(), nil, pr, "backend"), "no principal")
+	assert.NoError(t, authz.RequireAdmin(SystemContext(context.Background(), "sla"), nil), "jobs act as system")
+	assert.NoError(t, authz.CanReassign(SystemContext(context.Background(), "sla"), nil, pr, "backend"), "jobs act as system")
+	assert.NoError(t, authz.RequireAdmin(asUser("admin"), nil))
+	assert.NoError(t, authz.RequireAdmin(adminToken, nil))
+	assert.Equal(t, models.ErrForbidden, authz.RequireAdmin(asUser("lead"), nil))
+	assert.Equal(t, models.ErrForbidden, authz.RequireAdmin(service, nil))
+
+	assert.NoError(t, authz.RequireTeamLead(asUser("lead"), nil, "backend"))
+	assert.Equal(t, models.ErrForbidden, authz.RequireTeamLead(asUser("lead2"), nil, "backend"))
+	assert.Equal(t, models.ErrForbidden, authz.RequireTeamLead(asUser("other"), nil, "backend"))
+
+	for _, userID := range []string{"author", "lead", "admin"} {
+		assert.NoError(t, authz.RequireUserAccess(asUser(userID), nil, "author"), userID)
+	}
+	assert.Equal(t, models.ErrForbidden, authz.RequireUserAccess(asUser("other"), nil, "author"))
+	assert.Equal(t, models.ErrForbidden, authz.RequireUserAccess(asUser("lead2"), nil, "author"))
+	assert.Equal(t, models.ErrForbidden, authz.RequireUserAccess(asUser("lead"), nil, "ghost"))
+	assert.NoError(t, authz.RequireUserAccess(asUser("admin"), nil, "ghost"))
+
+	for _, userID := range []string{"author", "rev", "lead", "admin"} {
+		assert.NoError(t, authz.CanReassign(asUser(userID), nil, pr, "backend"), userID)
+	}
+	assert.Equal(t, models.ErrForbidden, authz.CanReassign(asUser("other"), nil, pr, "backend"))
+	assert.Equal(t, models.ErrForbidden, authz.CanReassign(asUser("lead2"), nil, pr, "backend"))
+
+	for _, userID := range []string{"author", "lead", "admin"} {
+		assert.NoError(t, authz.CanMerge(asUser(userID), nil, pr, "backend"), userID)
+	}
+	assert.Equal(t, models.ErrForbidden, authz.CanMerge(asUser("rev"), nil, pr, "backend"))
+	assert.Equal(t, models.ErrForbidden, authz.CanMerge(service, nil, pr, "backend"))
+
+	assert.NoError(t, authz.RequireSelfOrLead(asUser("other"), nil, &models.User{UserID: "other", TeamName: "backend"}))
+	assert.Equal(t, models.ErrForbidden, authz.RequireSelfOrLead(asUser("rev"), nil, &models.User{UserID: "other", TeamName: "backend"}))
+}
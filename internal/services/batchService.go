@@ -0,0 +1,107 @@
+package services
+
+/*
+Пакетное выполнение операций (POST /batch):
+	1. independent - каждая операция в своей транзакции, ошибки не мешают
+	   остальным
+	2. atomic - все операции в одной транзакции: сервисы открывают в ней
+	   savepoint'ы (storage.WithTx), первая неудача откатывает всё и
+	   останавливает пакет
+
+В atomic-пакете записи операций, для которых deferWrites вернул true
+(создание PR: вставка PR, событие, аудит, хронология), копятся в одном
+pgx.Batch (storage.WithWriteBatch) и уходят в базу одним обращением перед
+commit. Такая операция не должна читать то, что записали предыдущие
+отложенные, поэтому перед любой другой операцией накопленное сбрасывается.
+Повтор id PR внутри пакета всплывает только при сбросе - как ErrPRExists
+для пакета целиком. В independent-режиме у каждой операции своя транзакция
+и копить записи между ними нельзя.
+
+Что именно выполняет операция, решает вызывающий (exec) - так пакет
+проходит ту же валидацию и проверки прав, что и одиночные запросы.
+Внешняя транзакция serializable, поэтому atomic-пакет лучше держать
+небольшим: чем он длиннее, тем выше шанс конфликта с соседними запросами.
+Пакет целиком должен уложиться в BATCH_TIMEOUT - по его истечении контекст
+отменяется и незакоммиченные операции откатываются.
+*/
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	BatchModeIndependent = "independent"
+	BatchModeAtomic      = "atomic"
+)
+
+type BatchService struct {
+	prStorage     storage.PullReqStorage
+	maxOperations int
+}
+
+func NewBatchService(prStorage storage.PullReqStorage, maxOperations int) *BatchService {
+	return &BatchService{
+		prStorage:     prStorage,
+		maxOperations: maxOperations,
+	}
+}
+
+// Run выполняет n операций по порядку; exec возвращает false, если операция
+// не удалась. Возвращает число выполненных операций и то, закоммичены ли они.
+func (s *BatchService) Run(ctx context.Context, mode string, n int, deferWrites func(i int) bool, exec func(ctx context.Context, i int) bool) (int, bool, error) {
+	if n == 0 || n > s.maxOperations {
+		return 0, false, fmt.Errorf("%w: between 1 and %d operations are allowed", models.ErrInvalidBatch, s.maxOperations)
+	}
+
+	switch mode {
+	case BatchModeIndependent, "":
+		for i := 0; i < n; i++ {
+			exec(ctx, i)
+		}
+		return n, true, nil
+	case BatchModeAtomic:
+	default:
+		return 0, false, fmt.Errorf("%w: mode must be independent or atomic", models.ErrInvalidBatch)
+	}
+
+	tx, err := s.prStorage.PRBeginTx(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	txCtx := storage.WithTx(ctx, tx)
+	writeCtx := storage.WithWriteBatch(txCtx)
+	for i := 0; i < n; i++ {
+		opCtx := writeCtx
+		if !deferWrites(i) {
+			if err := s.flush(writeCtx, tx); err != nil {
+				return i, false, err
+			}
+			opCtx = txCtx
+		}
+		if !exec(opCtx, i) {
+			return i + 1, false, nil
+		}
+	}
+
+	if err := s.flush(writeCtx, tx); err != nil {
+		return n, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return n, false, err
+	}
+	return n, true, nil
+}
+
+func (s *BatchService) flush(ctx context.Context, tx pgx.Tx) error {
+	err := storage.FlushWrites(ctx, tx)
+	if err != nil && isUniqueConstraintError(err) {
+		return models.ErrPRExists
+	}
+	return err
+}
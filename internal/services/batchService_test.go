@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBatchTx считает обращения к базе пакетами (SendBatch)
+type fakeBatchTx struct {
+	pgx.Tx
+	committed bool
+	sent      []int // число запросов в каждом пакете
+}
+
+func (t *fakeBatchTx) Commit(ctx context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *fakeBatchTx) Rollback(ctx context.Context) error { return nil }
+
+func (t *fakeBatchTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	t.sent = append(t.sent, b.Len())
+	return fakeBatchResults{}
+}
+
+type fakeBatchResults struct {
+	pgx.BatchResults
+}
+
+func (fakeBatchResults) Close() error { return nil }
+
+type fakeBatchPRs struct {
+	storage.PullReqStorage
+	tx *fakeBatchTx
+}
+
+func (f *fakeBatchPRs) PRBeginTx(ctx context.Context) (pgx.Tx, error) {
+	f.tx = &fakeBatchTx{}
+	return f.tx, nil
+}
+
+func noDeferredWrites(i int) bool { return false }
+
+func TestBatchIndependent(t *testing.T) {
+	batch := NewBatchService(&fakeBatchPRs{}, 10)
+
+	var ran []int
+	executed, committed, err := batch.Run(context.Background(), BatchModeIndependent, 3, noDeferredWrites, func(ctx context.Context, i int) bool {
+		ran = append(ran, i)
+		return i != 1
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, executed)
+	assert.True(t, committed)
+	assert.Equal(t, []int{0, 1, 2}, ran, "a failed operation does not stop the rest")
+}
+
+func TestBatchAtomic(t *testing.T) {
+	prs := &fakeBatchPRs{}
+	batch := NewBatchService(prs, 10)
+
+	executed, committed, err := batch.Run(context.Background(), BatchModeAtomic, 3, noDeferredWrites, func(ctx context.Context, i int) bool {
+		return i != 1
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, executed, "the batch stops at the first failure")
+	assert.False(t, committed)
+	assert.False(t, prs.tx.committed)
+
+	executed, committed, err = batch.Run(context.Background(), BatchModeAtomic, 3, noDeferredWrites, func(ctx context.Context, i int) bool {
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, executed)
+	assert.True(t, committed)
+	assert.True(t, prs.tx.committed)
+}
+
+func TestBatchAtomic_DeferredWritesAreSentTogether(t *testing.T) {
+	prs := &fakeBatchPRs{}
+	batch := NewBatchService(prs, 10)
+	// без пула: записи уходят только в отложенный пакет
+	audit := storage.NewAuditPostgresStorage(nil)
+
+	// 0-2 - создание PR, 3 - операция, которой нужны их записи, 4 - снова создание
+	deferred := func(i int) bool { return i != 3 }
+	var batched []bool
+	_, committed, err := batch.Run(context.Background(), BatchModeAtomic, 5, deferred, func(ctx context.Context, i int) bool {
+		batched = append(batched, storage.InWriteBatch(ctx))
+		if storage.InWriteBatch(ctx) {
+			require.NoError(t, audit.AddAuditTx(ctx, nil, models.AuditEntry{Entity: models.AuditEntityPR, EntityID: "pr"}))
+			require.NoError(t, audit.AddAuditTx(ctx, nil, models.AuditEntry{Entity: models.AuditEntityPR, EntityID: "pr"}))
+		}
+		return true
+	})
+	require.NoError(t, err)
+	assert.True(t, committed)
+	assert.Equal(t, []bool{true, true, true, false, true}, batched)
+	assert.Equal(t, []int{6, 2}, prs.tx.sent, "flushed before the reading operation and once before commit")
+}
+
+func TestBatchValidation(t *testing.T) {
+	batch := NewBatchService(&fakeBatchPRs{}, 2)
+	noop := func(ctx context.Context, i int) bool { return true }
+
+	_, _, err := batch.Run(context.Background(), BatchModeAtomic, 3, noDeferredWrites, noop)
+	assert.ErrorIs(t, err, models.ErrInvalidBatch)
+	_, _, err = batch.Run(context.Background(), BatchModeAtomic, 0, noDeferredWrites, noop)
+	assert.ErrorIs(t, err, models.ErrInvalidBatch)
+	_, _, err = batch.Run(context.Background(), "parallel", 1, noDeferredWrites, noop)
+	assert.ErrorIs(t, err, models.ErrInvalidBatch)
+}
@@ -0,0 +1,479 @@
+package services
+
+/*
+Уведомления о ревью в чат (Slack / Mattermost incoming webhooks):
+	1. Управление webhook'ами для пользователей и команд
+	2. Приём событий из outbox (работает как sink диспетчера): назначения
+	   и просрочка SLA - ревьюерам, устаревший PR - автору
+	3. Накопление строк по webhook'ам и сброс раз в BatchWindow одним сообщением
+	4. Тихие часы - в это время сообщения копятся и уходят после их окончания
+
+Куда уходит строка: webhook самого получателя, если задан, иначе webhook
+команды. Массовое переназначение (например, деактивация пользователя) даёт
+одно сообщение на webhook за окно, а не по сообщению на каждый PR.
+
+Webhook команды настраивает её лид, личный - сам пользователь или лид его
+команды, админ - любой. Список показывает только доступные вызывающему
+webhook'и и без самих адресов (в них токен чата). Адрес в приватной или
+loopback-сети отклоняется сразу, а резолвящийся в неё - при отправке
+(AllowPrivateTargets отключает обе проверки).
+
+Накопленное живёт в памяти процесса: при рестарте посреди окна строки
+теряются. Повтор события из outbox отсекается по event.ID - событие считается
+увиденным только после того, как его строки легли в очередь, так что ошибка
+поиска webhook'ов оставляет его на повтор диспетчеру. Пачка, которую не
+удалось отправить chatMaxAttempts раз подряд, выбрасывается.
+*/
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"text/template"
+	"time"
+)
+
+const (
+	DefaultChatAssignedTemplate   = "{{.Reviewer}} назначен(а) ревьюером на {{.PullRequestName}} ({{.PullRequestID}}), автор {{.AuthorID}}"
+	DefaultChatReassignedTemplate = "{{.Reviewer}} заменяет {{.OldReviewer}} на {{.PullRequestName}} ({{.PullRequestID}})"
+	DefaultChatStaleTemplate      = "{{.AuthorID}}, PR {{.PullRequestName}} ({{.PullRequestID}}) давно не менялся и помечен как устаревший"
+	DefaultChatReminderTemplate   = "{{.Reviewer}}, истёк срок ревью {{.PullRequestName}} ({{.PullRequestID}}), автор {{.AuthorID}}"
+
+	chatMaxLines    = 50
+	chatSeenLimit   = 1000
+	chatMaxAttempts = 5
+)
+
+type ChatNotificationConfig struct {
+	BatchWindow        time.Duration
+	QuietHours         string
+	Location           *time.Location
+	AssignedTemplate   string
+	ReassignedTemplate string
+	ReminderTemplate   string
+	StaleTemplate      string
+
+	AllowPrivateTargets bool
+}
+
+type chatBatch struct {
+	format   string
+	url      string
+	lines    []string
+	attempts int
+}
+
+type ChatNotificationService struct {
+	storage     storage.ChatTargetStorage
+	authz       *Authorizer
+	notifiers   map[string]Notifier
+	assigned    *template.Template
+	reassigned  *template.Template
+	reminder    *template.Template
+	stale       *template.Template
+	batchWindow time.Duration
+	quiet       quietHours
+	allowPriv   bool
+	now         func() time.Time
+
+	mu       sync.Mutex
+	pending  map[string]*chatBatch
+	seen     map[string]struct{}
+	seenList []string
+}
+
+func NewChatNotificationService(storage storage.ChatTargetStorage, authz *Authorizer, notifiers map[string]Notifier, cfg ChatNotificationConfig) (*ChatNotificationService, error) {
+	if cfg.AssignedTemplate == "" {
+		cfg.AssignedTemplate = DefaultChatAssignedTemplate
+	}
+	if cfg.ReassignedTemplate == "" {
+		cfg.ReassignedTemplate = DefaultChatReassignedTemplate
+	}
+	if cfg.ReminderTemplate == "" {
+		cfg.ReminderTemplate = DefaultChatReminderTemplate
+	}
+	if cfg.StaleTemplate == "" {
+		cfg.StaleTemplate = DefaultChatStaleTemplate
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+
+	assigned, err := template.New("assigned").Parse(cfg.AssignedTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid assigned template: %w", err)
+	}
+	reassigned, err := template.New("reassigned").Parse(cfg.ReassignedTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reassigned template: %w", err)
+	}
+	reminder, err := template.New("reminder").Parse(cfg.ReminderTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reminder template: %w", err)
+	}
+	stale, err := template.New("stale").Parse(cfg.StaleTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stale template: %w", err)
+	}
+	quiet, err := parseQuietHours(cfg.QuietHours, cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChatNotificationService{
+		storage:     storage,
+		authz:       authz,
+		notifiers:   notifiers,
+		assigned:    assigned,
+		reassigned:  reassigned,
+		reminder:    reminder,
+		stale:       stale,
+		batchWindow: cfg.BatchWindow,
+		quiet:       quiet,
+		allowPriv:   cfg.AllowPrivateTargets,
+		now:         time.Now,
+		pending:     make(map[string]*chatBatch),
+		seen:        make(map[string]struct{}),
+	}, nil
+}
+
+func (s *ChatNotificationService) SetTarget(ctx context.Context, req models.SetChatTargetRequest) (*models.ChatTarget, error) {
+	if req.Scope != models.ChatScopeUser && req.Scope != models.ChatScopeTeam {
+		return nil, fmt.Errorf("%w: scope must be user or team", models.ErrInvalidChatTarget)
+	}
+	if req.TargetID == "" {
+		return nil, fmt.Errorf("%w: target_id is required", models.ErrInvalidChatTarget)
+	}
+	u, err := url.Parse(req.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: webhook_url must be an absolute http(s) url", models.ErrInvalidChatTarget)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !s.allowPriv && !isPublicIP(ip) {
+		return nil, fmt.Errorf("%w: webhook_url must not point to a private or loopback address", models.ErrInvalidChatTarget)
+	}
+	if req.Format == "" {
+		req.Format = models.ChatFormatSlack
+	}
+	if _, ok := s.notifiers[req.Format]; !ok {
+		return nil, fmt.Errorf("%w: unsupported format %q", models.ErrInvalidChatTarget, req.Format)
+	}
+
+	if err := s.requireTargetAccess(ctx, req.Scope, req.TargetID); err != nil {
+		return nil, err
+	}
+
+	target := models.ChatTarget{
+		Scope:      req.Scope,
+		TargetID:   req.TargetID,
+		WebhookURL: req.WebhookURL,
+		Format:     req.Format,
+	}
+	if err := s.storage.SetChatTargetTx(ctx, nil, target); err != nil {
+		return nil, err
+	}
+	target.WebhookHost = u.Host
+	return &target, nil
+}
+
+func (s *ChatNotificationService) DeleteTarget(ctx context.Context, scope string, targetID string) error {
+	if err := s.requireTargetAccess(ctx, scope, targetID); err != nil {
+		return err
+	}
+	return s.storage.DeleteChatTargetTx(ctx, nil, scope, targetID)
+}
+
+// ListTargets - webhook'и, которыми вызывающий может управлять
+func (s *ChatNotificationService) ListTargets(ctx context.Context) ([]models.ChatTarget, error) {
+	targets, err := s.storage.ListChatTargetsTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []models.ChatTarget{}
+	for _, t := range targets {
+		err := s.requireTargetAccess(ctx, t.Scope, t.TargetID)
+		if err == models.ErrForbidden {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(t.WebhookURL); err == nil {
+			t.WebhookHost = u.Host
+		}
+		visible = append(visible, t)
+	}
+	return visible, nil
+}
+
+func (s *ChatNotificationService) requireTargetAccess(ctx context.Context, scope string, targetID string) error {
+	switch scope {
+	case models.ChatScopeTeam:
+		return s.authz.RequireTeamLead(ctx, nil, targetID)
+	case models.ChatScopeUser:
+		return s.authz.RequireUserAccess(ctx, nil, targetID)
+	default:
+		return s.authz.RequireAdmin(ctx, nil)
+	}
+}
+
+func (s *ChatNotificationService) Name() string { return "chat" }
+
+func (s *ChatNotificationService) Deliver(ctx context.Context, event models.Event) error {
+	if event.Type != models.EventReviewerAssigned &&
+		event.Type != models.EventReviewerReassigned &&
+		event.Type != models.EventSLABreached &&
+		event.Type != models.EventPRStale {
+		return nil
+	}
+	if s.isSeen(event.ID) {
+		return nil
+	}
+
+	var review models.ReviewEvent
+	if err := json.Unmarshal(event.Data, &review); err != nil {
+		return err
+	}
+	if review.PullRequest == nil {
+		return nil
+	}
+
+	lines := make(map[string]string)
+	data := models.ChatTemplateData{
+		OldReviewer:     review.OldReviewer,
+		PullRequestID:   review.PullRequest.PullRequestID,
+		PullRequestName: review.PullRequest.PullRequestName,
+		AuthorID:        review.PullRequest.AuthorID,
+		TeamName:        review.TeamName,
+	}
+
+	tmpl := s.assigned
+	reviewers := review.Reviewers
+	switch event.Type {
+	case models.EventReviewerReassigned:
+		tmpl = s.reassigned
+		reviewers = []string{review.NewReviewer}
+	case models.EventSLABreached:
+		tmpl = s.reminder
+	case models.EventPRStale:
+		tmpl = s.stale
+		reviewers = []string{review.PullRequest.AuthorID}
+	}
+	for _, reviewer := range reviewers {
+		data.Reviewer = reviewer
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return fmt.Errorf("failed to render chat template: %w", err)
+		}
+		lines[reviewer] = b.String()
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return s.queueLines(ctx, event.ID, review.TeamName, lines)
+}
+
+// queueLines раскладывает строки (user_id -> текст) по webhook'ам:
+// личный webhook пользователя, иначе webhook команды. Событие отмечается
+// увиденным под тем же замком, что и постановка строк.
+func (s *ChatNotificationService) queueLines(ctx context.Context, eventID string, teamName string, lines map[string]string) error {
+	userIDs := make([]string, 0, len(lines))
+	for userID := range lines {
+		userIDs = append(userIDs, userID)
+	}
+
+	targets, err := s.storage.FindChatTargetsTx(ctx, nil, userIDs, teamName)
+	if err != nil {
+		return err
+	}
+
+	var teamTarget *models.ChatTarget
+	userTargets := make(map[string]models.ChatTarget)
+	for i, t := range targets {
+		if t.Scope == models.ChatScopeTeam {
+			teamTarget = &targets[i]
+		} else {
+			userTargets[t.TargetID] = t
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// параллельная доставка того же события успела раньше
+	if _, ok := s.seen[eventID]; ok {
+		return nil
+	}
+	s.markSeen(eventID)
+
+	for _, userID := range userIDs {
+		target, ok := userTargets[userID]
+		if !ok {
+			if teamTarget == nil {
+				continue
+			}
+			target = *teamTarget
+		}
+		s.appendLines(target.Format, target.WebhookURL, lines[userID])
+	}
+
+	return nil
+}
+
+func (s *ChatNotificationService) appendLines(format string, webhookURL string, lines ...string) {
+	key := format + "|" + webhookURL
+	batch, ok := s.pending[key]
+	if !ok {
+		batch = &chatBatch{format: format, url: webhookURL}
+		s.pending[key] = batch
+	}
+	batch.lines = append(batch.lines, lines...)
+}
+
+func (s *ChatNotificationService) isSeen(eventID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.seen[eventID]
+	return ok
+}
+
+// markSeen вызывается под s.mu
+func (s *ChatNotificationService) markSeen(eventID string) {
+	s.seen[eventID] = struct{}{}
+	s.seenList = append(s.seenList, eventID)
+	if len(s.seenList) > chatSeenLimit {
+		delete(s.seen, s.seenList[0])
+		s.seenList = s.seenList[1:]
+	}
+}
+
+// Run крутится до отмены ctx
+func (s *ChatNotificationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.batchWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// последняя попытка отправить накопленное при остановке
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.Flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			s.Flush(ctx)
+		}
+	}
+}
+
+// Flush отправляет накопленные пачки. В тихие часы ничего не делает,
+// неотправленные пачки возвращаются в очередь до следующего сброса,
+// после chatMaxAttempts неудач - выбрасываются.
+func (s *ChatNotificationService) Flush(ctx context.Context) {
+	if s.quiet.active(s.now()) {
+		return
+	}
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]*chatBatch)
+	s.mu.Unlock()
+
+	for _, batch := range pending {
+		if err := s.send(ctx, batch); err != nil {
+			batch.attempts++
+			if batch.attempts >= chatMaxAttempts {
+				slog.Error("Dropping chat notification after repeated failures", "format", batch.format, "lines", len(batch.lines), "attempts", batch.attempts, "error", err)
+				continue
+			}
+			slog.Warn("Failed to send chat notification", "format", batch.format, "lines", len(batch.lines), "attempts", batch.attempts, "error", err)
+			s.requeue(batch)
+		}
+	}
+}
+
+// requeue возвращает неотправленную пачку в очередь перед строками,
+// накопленными за время отправки
+func (s *ChatNotificationService) requeue(batch *chatBatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := batch.format + "|" + batch.url
+	if newer, ok := s.pending[key]; ok {
+		batch.lines = append(batch.lines, newer.lines...)
+	}
+	s.pending[key] = batch
+}
+
+func (s *ChatNotificationService) send(ctx context.Context, batch *chatBatch) error {
+	notifier, ok := s.notifiers[batch.format]
+	if !ok {
+		return fmt.Errorf("no notifier for format %q", batch.format)
+	}
+
+	msg := models.ChatMessage{Lines: batch.lines}
+	if len(batch.lines) > 1 {
+		msg.Title = fmt.Sprintf("Обновления ревью: %d", len(batch.lines))
+	}
+	if len(msg.Lines) > chatMaxLines {
+		msg.Lines = append(msg.Lines[:chatMaxLines:chatMaxLines], fmt.Sprintf("... и ещё %d", len(batch.lines)-chatMaxLines))
+	}
+
+	return notifier.Send(ctx, batch.url, msg)
+}
+
+// quietHours - интервал [start, end) в минутах от полуночи, может переходить через полночь
+type quietHours struct {
+	start, end int
+	loc        *time.Location
+	enabled    bool
+}
+
+// parseQuietHours разбирает "22:00-08:00", пустая строка - тихих часов нет
+func parseQuietHours(raw string, loc *time.Location) (quietHours, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return quietHours{}, nil
+	}
+
+	from, to, ok := strings.Cut(raw, "-")
+	if !ok {
+		return quietHours{}, fmt.Errorf("invalid quiet hours %q: expected HH:MM-HH:MM", raw)
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return quietHours{}, fmt.Errorf("invalid quiet hours %q: %w", raw, err)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return quietHours{}, fmt.Errorf("invalid quiet hours %q: %w", raw, err)
+	}
+
+	q := quietHours{
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+		loc:   loc,
+	}
+	q.enabled = q.start != q.end
+	return q, nil
+}
+
+func (q quietHours) active(t time.Time) bool {
+	if !q.enabled {
+		return false
+	}
+	t = t.In(q.loc)
+	m := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
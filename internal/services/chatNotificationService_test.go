@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"test-task/internal/models"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChatTargets struct {
+	targets []models.ChatTarget
+	findErr error
+}
+
+func (f *fakeChatTargets) SetChatTargetTx(ctx context.Context, tx pgx.Tx, target models.ChatTarget) error {
+	f.targets = append(f.targets, target)
+	return nil
+}
+
+func (f *fakeChatTargets) DeleteChatTargetTx(ctx context.Context, tx pgx.Tx, scope string, targetID string) error {
+	return nil
+}
+
+func (f *fakeChatTargets) ListChatTargetsTx(ctx context.Context, tx pgx.Tx) ([]models.ChatTarget, error) {
+	return f.targets, nil
+}
+
+func (f *fakeChatTargets) FindChatTargetsTx(ctx context.Context, tx pgx.Tx, userIDs []string, teamName string) ([]models.ChatTarget, error) {
+	if f.findErr != nil {
+		return nil, f.findErr
+	}
+	var found []models.ChatTarget
+	for _, t := range f.targets {
+		if t.Scope == models.ChatScopeTeam && t.TargetID == teamName {
+			found = append(found, t)
+		}
+		if t.Scope == models.ChatScopeUser && contains(userIDs, t.TargetID) {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// chatStub - локальный incoming webhook, запоминает тексты сообщений
+type chatStub struct {
+	mu    sync.Mutex
+	texts []string
+	users []string
+	srv   *httptest.Server
+}
+
+func newChatStub(t *testing.T) *chatStub {
+	stub := &chatStub{}
+	stub.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		stub.mu.Lock()
+		stub.texts = append(stub.texts, payload["text"])
+		stub.users = append(stub.users, payload["username"])
+		stub.mu.Unlock()
+	}))
+	t.Cleanup(stub.srv.Close)
+	return stub
+}
+
+func newTestChat(t *testing.T, store *fakeChatTargets, quiet string) *ChatNotificationService {
+	authz := NewAuthorizer(&fakeRoleUsers{users: map[string]models.User{
+		"admin": {UserID: "admin", TeamName: "ops", Role: models.RoleAdmin},
+		"lead":  {UserID: "lead", TeamName: "backend", Role: models.RoleLead},
+		"u2":    {UserID: "u2", TeamName: "backend", Role: models.RoleMember},
+		"u3":    {UserID: "u3", TeamName: "backend", Role: models.RoleMember},
+	}})
+	// заглушки webhook'ов слушают loopback
+	svc, err := NewChatNotificationService(store, authz, map[string]Notifier{
+		models.ChatFormatSlack:      NewSlackNotifier(time.Second, true),
+		models.ChatFormatMattermost: NewMattermostNotifier(time.Second, true, "reviewer-bot"),
+	}, ChatNotificationConfig{BatchWindow: time.Minute, QuietHours: quiet, AllowPrivateTargets: true})
+	require.NoError(t, err)
+	return svc
+}
+
+func assignedEvent(t *testing.T, id string, prID string, reviewers ...string) models.Event {
+	data, err := json.Marshal(models.ReviewEvent{
+		PullRequest: &models.PullRequest{PullRequestID: prID, PullRequestName: "Fix " + prID, AuthorID: "u1"},
+		TeamName:    "backend",
+		Reviewers:   reviewers,
+	})
+	require.NoError(t, err)
+	return models.Event{ID: id, Type: models.EventReviewerAssigned, Data: data}
+}
+
+func TestChatNotification_BatchesPerWebhook(t *testing.T) {
+	team := newChatStub(t)
+	personal := newChatStub(t)
+	store := &fakeChatTargets{targets: []models.ChatTarget{
+		{Scope: models.ChatScopeTeam, TargetID: "backend", WebhookURL: team.srv.URL, Format: models.ChatFormatSlack},
+		{Scope: models.ChatScopeUser, TargetID: "u3", WebhookURL: personal.srv.URL, Format: models.ChatFormatMattermost},
+	}}
+	svc := newTestChat(t, store, "")
+	ctx := context.Background()
+
+	require.NoError(t, svc.Deliver(ctx, assignedEvent(t, "e1", "pr-1", "u2", "u3")))
+	require.NoError(t, svc.Deliver(ctx, assignedEvent(t, "e2", "pr-2", "u2")))
+	// повтор из outbox не должен задвоить строку
+	require.NoError(t, svc.Deliver(ctx, assignedEvent(t, "e2", "pr-2", "u2")))
+
+	svc.Flush(ctx)
+
+	require.Len(t, team.texts, 1)
+	assert.Contains(t, team.texts[0], "*Обновления ревью: 2*")
+	assert.Contains(t, team.texts[0], "• u2 назначен(а) ревьюером на Fix pr-1 (pr-1), автор u1")
+	assert.Contains(t, team.texts[0], "• u2 назначен(а) ревьюером на Fix pr-2 (pr-2), автор u1")
+
+	require.Len(t, personal.texts, 1)
+	assert.Equal(t, "- u3 назначен(а) ревьюером на Fix pr-1 (pr-1), автор u1", personal.texts[0])
+	assert.Equal(t, "reviewer-bot", personal.users[0])
+
+	svc.Flush(ctx)
+	assert.Len(t, team.texts, 1)
+}
+
+func TestChatNotification_QuietHoursHoldMessages(t *testing.T) {
+	team := newChatStub(t)
+	store := &fakeChatTargets{targets: []models.ChatTarget{
+		{Scope: models.ChatScopeTeam, TargetID: "backend", WebhookURL: team.srv.URL, Format: models.ChatFormatSlack},
+	}}
+	svc := newTestChat(t, store, "22:00-08:00")
+	ctx := context.Background()
+
+	now := time.Date(2025, 1, 1, 23, 30, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	require.NoError(t, svc.Deliver(ctx, assignedEvent(t, "e1", "pr-1", "u2")))
+	svc.Flush(ctx)
+	assert.Empty(t, team.texts)
+
+	now = time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)
+	svc.Flush(ctx)
+	require.Len(t, team.texts, 1)
+	assert.Equal(t, "• u2 назначен(а) ревьюером на Fix pr-1 (pr-1), автор u1", team.texts[0])
+}
+
+func TestChatNotification_FailedSendIsRetried(t *testing.T) {
+	fail := true
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		texts = append(texts, payload["text"])
+	}))
+	defer srv.Close()
+
+	store := &fakeChatTargets{targets: []models.ChatTarget{
+		{Scope: models.ChatScopeUser, TargetID: "u2", WebhookURL: srv.URL, Format: models.ChatFormatSlack},
+	}}
+	svc := newTestChat(t, store, "")
+	ctx := context.Background()
+
+	require.NoError(t, svc.Deliver(ctx, assignedEvent(t, "e1", "pr-1", "u2")))
+	svc.Flush(ctx)
+	assert.Empty(t, texts)
+
+	fail = false
+	svc.Flush(ctx)
+	require.Len(t, texts, 1)
+}
+
+func TestChatNotification_FailedSendIsDroppedAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	store := &fakeChatTargets{targets: []models.ChatTarget{
+		{Scope: models.ChatScopeUser, TargetID: "u2", WebhookURL: srv.URL, Format: models.ChatFormatSlack},
+	}}
+	svc := newTestChat(t, store, "")
+	ctx := context.Background()
+
+	require.NoError(t, svc.Deliver(ctx, assignedEvent(t, "e1", "pr-1", "u2")))
+	for i := 0; i < chatMaxAttempts+2; i++ {
+		svc.Flush(ctx)
+	}
+	assert.Equal(t, chatMaxAttempts, calls)
+	assert.Empty(t, svc.pending)
+}
+
+func TestChatNotification_LookupFailureKeepsEventForRetry(t *testing.T) {
+	stub := newChatStub(t)
+	store := &fakeChatTargets{
+		targets: []models.ChatTarget{
+			{Scope: models.ChatScopeUser, TargetID: "u2", WebhookURL: stub.srv.URL, Format: models.ChatFormatSlack},
+		},
+		findErr: assert.AnError,
+	}
+	svc := newTestChat(t, store, "")
+	ctx := context.Background()
+
+	event := assignedEvent(t, "e1", "pr-1", "u2")
+	require.Error(t, svc.Deliver(ctx, event))
+
+	store.findErr = nil
+	require.NoError(t, svc.Deliver(ctx, event))
+	require.NoError(t, svc.Deliver(ctx, event))
+	svc.Flush(ctx)
+	require.Len(t, stub.texts, 1)
+}
+
+func TestParseQuietHours(t *testing.T) {
+	q, err := parseQuietHours("09:00-17:30", time.UTC)
+	require.NoError(t, err)
+	assert.True(t, q.active(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, q.active(time.Date(2025, 1, 1, 17, 30, 0, 0, time.UTC)))
+
+	q, err = parseQuietHours("", time.UTC)
+	require.NoError(t, err)
+	assert.False(t, q.active(time.Now()))
+
+	_, err = parseQuietHours("22:00", time.UTC)
+	assert.Error(t, err)
+}
+
+func TestChatTargets_Authorization(t *testing.T) {
+	store := &fakeChatTargets{}
+	svc := newTestChat(t, store, "")
+	teamHook := models.SetChatTargetRequest{Scope: models.ChatScopeTeam, TargetID: "backend", WebhookURL: "https://hooks.slack.com/services/T0/B0/secret"}
+
+	target, err := svc.SetTarget(asUser("lead"), teamHook)
+	require.NoError(t, err)
+	assert.Equal(t, "hooks.slack.com", target.WebhookHost)
+	body, err := json.Marshal(target)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "secret")
+
+	_, err = svc.SetTarget(asUser("u2"), teamHook)
+	assert.Equal(t, models.ErrForbidden, err)
+	_, err = svc.SetTarget(asUser("u2"), models.SetChatTargetRequest{Scope: models.ChatScopeUser, TargetID: "u3", WebhookURL: "https://hooks.slack.com/x"})
+	assert.Equal(t, models.ErrForbidden, err)
+	_, err = svc.SetTarget(asUser("u2"), models.SetChatTargetRequest{Scope: models.ChatScopeUser, TargetID: "u2", WebhookURL: "https://hooks.slack.com/x"})
+	require.NoError(t, err)
+	assert.Equal(t, models.ErrForbidden, svc.DeleteTarget(asUser("u3"), models.ChatScopeTeam, "backend"))
+
+	// участник видит только свой webhook, лид - и командный, и своих участников
+	targets, err := svc.ListTargets(asUser("u2"))
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, "u2", targets[0].TargetID)
+	targets, err = svc.ListTargets(asUser("lead"))
+	require.NoError(t, err)
+	assert.Len(t, targets, 2)
+	targets, err = svc.ListTargets(asUser("u3"))
+	require.NoError(t, err)
+	assert.Empty(t, targets)
+
+	svc.allowPriv = false
+	_, err = svc.SetTarget(asUser("lead"), models.SetChatTargetRequest{Scope: models.ChatScopeTeam, TargetID: "backend", WebhookURL: "http://127.0.0.1:8080/hook"})
+	assert.ErrorIs(t, err, models.ErrInvalidChatTarget)
+}
+
+func TestChatNotifier_RejectsPrivateAddress(t *testing.T) {
+	stub := newChatStub(t)
+
+	err := NewSlackNotifier(time.Second, false).Send(context.Background(), stub.srv.URL, models.ChatMessage{Lines: []string{"hi"}})
+	assert.ErrorIs(t, err, errPrivateTarget)
+	assert.Empty(t, stub.texts)
+}
@@ -0,0 +1,145 @@
+package services
+
+/*
+CODEOWNERS в формате GitHub/GitLab:
+	1. Разбор файла в список правил (pattern + owners)
+	2. Поиск владельцев для изменённых файлов
+
+Семантика паттернов как у gitignore:
+	- без "/" (кроме завершающего) - совпадает на любой глубине
+	- с "/" в начале или в середине - от корня репозитория
+	- завершающий "/" - каталог и всё его содержимое
+	- паттерн без глоба в последнем сегменте ("docs", "/db/migrations")
+	  совпадает и с файлом, и с каталогом со всем содержимым
+	- паттерн с глобом в последнем сегменте ("*.css") - только с файлом
+
+Для каждого файла побеждает последнее совпавшее правило. Правило без
+владельцев снимает владение. Владелец - user_id, ведущий "@" отбрасывается.
+Секции GitLab ("[Docs]") пропускаются, "!" и "[...]" не поддерживаются.
+*/
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+	"test-task/internal/models"
+)
+
+func ParseCodeOwners(content string) ([]models.CodeOwnersRule, error) {
+	var rules []models.CodeOwnersRule
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		pattern := strings.ReplaceAll(fields[0], `\#`, "#")
+		if strings.HasPrefix(pattern, "!") || strings.ContainsAny(pattern, "[]") {
+			return nil, fmt.Errorf("%w: line %d: unsupported pattern %q", models.ErrInvalidCodeOwners, lineNum, pattern)
+		}
+
+		owners := make([]string, 0, len(fields)-1)
+		for _, owner := range fields[1:] {
+			owner = strings.TrimPrefix(owner, "@")
+			if owner == "" {
+				return nil, fmt.Errorf("%w: line %d: empty owner", models.ErrInvalidCodeOwners, lineNum)
+			}
+			owners = append(owners, owner)
+		}
+
+		rules = append(rules, models.CodeOwnersRule{
+			Line:    lineNum,
+			Pattern: pattern,
+			Owners:  owners,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCodeOwners, err)
+	}
+
+	return rules, nil
+}
+
+// stripComment отрезает комментарий, не трогая экранированный "\#"
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// codeOwnersFor возвращает владельцев в порядке первого появления и,
+// для каждого владельца, файлы вместе с правилом, совпавшим с каждым из них
+func codeOwnersFor(rules []models.CodeOwnersRule, changedFiles []string) []models.CodeOwnerReviewer {
+	var result []models.CodeOwnerReviewer
+	index := make(map[string]int)
+
+	for _, file := range changedFiles {
+		var matched *models.CodeOwnersRule
+		for i := range rules {
+			if matchCodeOwnersPattern(rules[i].Pattern, file) {
+				matched = &rules[i]
+			}
+		}
+		if matched == nil {
+			continue
+		}
+
+		owned := models.CodeOwnedFile{Path: file, Pattern: matched.Pattern}
+		for _, owner := range matched.Owners {
+			if i, ok := index[owner]; ok {
+				result[i].Files = append(result[i].Files, owned)
+				continue
+			}
+			index[owner] = len(result)
+			result = append(result, models.CodeOwnerReviewer{
+				UserID: owner,
+				Files:  []models.CodeOwnedFile{owned},
+			})
+		}
+	}
+
+	return result
+}
+
+func matchCodeOwnersPattern(pattern, file string) bool {
+	file = strings.TrimPrefix(file, "/")
+
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		// "/" - весь репозиторий
+		return true
+	}
+
+	expr := globToRegexp(pattern)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	lastSegment := pattern[strings.LastIndex(pattern, "/")+1:]
+	switch {
+	case dirOnly:
+		expr += "/.*"
+	case !strings.ContainsAny(lastSegment, "*?"):
+		// имя без глоба может быть каталогом
+		expr += "(?:/.*)?"
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(file)
+}
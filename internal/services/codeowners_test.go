@@ -0,0 +1,99 @@
+package services
+
+import (
+	"test-task/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCodeOwners = `
+# общий владелец
+*                 @lead
+
+[Frontend]
+*.css             @alice
+/web/             @bob @alice
+docs/             @carol
+/db/migrations    @dba
+db/migrations/legacy/
+\#notes.md        @carol
+`
+
+func TestParseCodeOwners(t *testing.T) {
+	rules, err := ParseCodeOwners(testCodeOwners)
+	require.NoError(t, err)
+	require.Len(t, rules, 7)
+
+	assert.Equal(t, models.CodeOwnersRule{Line: 3, Pattern: "*", Owners: []string{"lead"}}, rules[0])
+	assert.Equal(t, []string{"bob", "alice"}, rules[2].Owners)
+	assert.Empty(t, rules[5].Owners)
+	assert.Equal(t, "#notes.md", rules[6].Pattern)
+
+	_, err = ParseCodeOwners("!*.go @alice")
+	assert.ErrorIs(t, err, models.ErrInvalidCodeOwners)
+}
+
+func TestCodeOwnersFor_LastMatchWins(t *testing.T) {
+	rules, err := ParseCodeOwners(testCodeOwners)
+	require.NoError(t, err)
+
+	cases := []struct {
+		file string
+		want []string
+	}{
+		{"main.go", []string{"lead"}},
+		{"src/app/main.css", []string{"alice"}},
+		{"web/index.html", []string{"bob", "alice"}},
+		{"web/main.css", []string{"bob", "alice"}},
+		{"src/docs/readme.md", []string{"carol"}},
+		{"db/migrations/001.sql", []string{"dba"}},
+		{"db/migrations/legacy/001.sql", nil},
+		{"#notes.md", []string{"carol"}},
+	}
+
+	for _, c := range cases {
+		var got []string
+		for _, owner := range codeOwnersFor(rules, []string{c.file}) {
+			got = append(got, owner.UserID)
+		}
+		assert.Equal(t, c.want, got, c.file)
+	}
+}
+
+func TestCodeOwnersFor_GroupsFilesByOwner(t *testing.T) {
+	rules, err := ParseCodeOwners(testCodeOwners)
+	require.NoError(t, err)
+
+	owners := codeOwnersFor(rules, []string{"a.css", "b.css", "web/x.js"})
+	require.Len(t, owners, 2)
+	assert.Equal(t, "alice", owners[0].UserID)
+	assert.Equal(t, []models.CodeOwnedFile{
+		{Path: "a.css", Pattern: "*.css"},
+		{Path: "b.css", Pattern: "*.css"},
+		{Path: "web/x.js", Pattern: "/web/"},
+	}, owners[0].Files)
+	assert.Equal(t, "bob", owners[1].UserID)
+	assert.Equal(t, []models.CodeOwnedFile{{Path: "web/x.js", Pattern: "/web/"}}, owners[1].Files)
+}
+
+func TestMatchCodeOwnersPattern_DirectorySuffix(t *testing.T) {
+	cases := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"*.css", "a.css", true},
+		{"*.css", "a.css/inner.txt", false},
+		{"docs/*", "docs/a.md", true},
+		{"docs/*", "docs/sub/a.md", false},
+		{"docs", "docs/sub/a.md", true},
+		{"/db/migrations", "db/migrations/001.sql", true},
+		{"build/", "build", false},
+		{"build/", "src/build/out.o", true},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, matchCodeOwnersPattern(c.pattern, c.file), "%s ~ %s", c.pattern, c.file)
+	}
+}
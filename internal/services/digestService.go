@@ -0,0 +1,231 @@
+package services
+
+/*
+Ежедневный email-дайджест ревью:
+	1. Раз в день в DigestConfig.SendAt (по DigestConfig.Location) собирает
+	   для каждого получателя OPEN PR, где он назначен ревьюером
+	2. Рендерит письмо по text- и html-шаблонам
+	3. Отправляет через Mailer
+
+Получатели - активные пользователи с email, не отказавшиеся от дайджеста
+(POST /users/setDigestOptOut). Пустой дайджест не отправляется.
+День рассылки захватывается в digest_runs: при нескольких экземплярах
+сервиса письма уходит один раз.
+*/
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"strings"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/digest.txt.tmpl templates/digest.html.tmpl
+var digestTemplates embed.FS
+
+type DigestConfig struct {
+	SendAt   string
+	Location *time.Location
+	Subject  string
+}
+
+type digestItem struct {
+	PullRequestID   string
+	PullRequestName string
+	AuthorID        string
+	Age             string
+}
+
+type digestData struct {
+	User  models.User
+	Date  string
+	Items []digestItem
+}
+
+type DigestService struct {
+	userStorage storage.UserStorage
+	prStorage   storage.PullReqStorage
+	mailer      Mailer
+	text        *template.Template
+	html        *htmltemplate.Template
+	sendHour    int
+	sendMinute  int
+	loc         *time.Location
+	subject     string
+	now         func() time.Time
+}
+
+func NewDigestService(userStorage storage.UserStorage, prStorage storage.PullReqStorage, mailer Mailer, cfg DigestConfig) (*DigestService, error) {
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = "Ревью, которые ждут вас"
+	}
+
+	sendAt, err := time.Parse("15:04", cfg.SendAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest send time %q: %w", cfg.SendAt, err)
+	}
+
+	text, err := template.ParseFS(digestTemplates, "templates/digest.txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.ParseFS(digestTemplates, "templates/digest.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DigestService{
+		userStorage: userStorage,
+		prStorage:   prStorage,
+		mailer:      mailer,
+		text:        text,
+		html:        html,
+		sendHour:    sendAt.Hour(),
+		sendMinute:  sendAt.Minute(),
+		loc:         cfg.Location,
+		subject:     cfg.Subject,
+		now:         time.Now,
+	}, nil
+}
+
+// Run крутится до отмены ctx
+func (s *DigestService) Run(ctx context.Context) {
+	for {
+		next := s.nextRun(s.now())
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		claimed, err := s.userStorage.ClaimDigestRunTx(ctx, nil, next)
+		if err != nil {
+			slog.Error("Failed to claim digest run", "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		sent, err := s.SendDigests(ctx)
+		if err != nil {
+			slog.Error("Failed to send some review digests", "sent", sent, "error", err)
+			continue
+		}
+		slog.Info("Review digests sent", "count", sent)
+	}
+}
+
+func (s *DigestService) nextRun(now time.Time) time.Time {
+	now = now.In(s.loc)
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.sendHour, s.sendMinute, 0, 0, s.loc)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// SendDigests отправляет дайджесты всем получателям и возвращает число писем.
+// Ошибка сборки или отправки одному получателю не останавливает остальных -
+// повтора в этот день не будет; ошибки возвращаются вместе.
+func (s *DigestService) SendDigests(ctx context.Context) (int, error) {
+	users, err := s.userStorage.ListDigestRecipientsTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	sent := 0
+	var errs []error
+	for _, user := range users {
+		msg, err := s.buildDigest(ctx, user, now)
+		if err != nil {
+			slog.Warn("Failed to build review digest", "user_id", user.UserID, "error", err)
+			errs = append(errs, fmt.Errorf("digest for %s: %w", user.UserID, err))
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+
+		if err := s.mailer.Send(ctx, *msg); err != nil {
+			slog.Warn("Failed to send review digest", "user_id", user.UserID, "error", err)
+			errs = append(errs, fmt.Errorf("digest for %s: %w", user.UserID, err))
+			continue
+		}
+		sent++
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+func (s *DigestService) buildDigest(ctx context.Context, user models.User, now time.Time) (*models.EmailMessage, error) {
+	prs, err := s.prStorage.GetPRsByReviewerTx(ctx, nil, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := digestData{User: user, Date: now.In(s.loc).Format("02.01.2006")}
+	for _, pr := range prs {
+		if pr.Status != "OPEN" {
+			continue
+		}
+		item := digestItem{
+			PullRequestID:   pr.PullRequestID,
+			PullRequestName: pr.PullRequestName,
+			AuthorID:        pr.AuthorID,
+		}
+		if pr.CreatedAt != nil {
+			item.Age = formatAge(now.Sub(*pr.CreatedAt))
+		}
+		data.Items = append(data.Items, item)
+	}
+	if len(data.Items) == 0 {
+		return nil, nil
+	}
+
+	var text, html bytes.Buffer
+	if err := s.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+	if err := s.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	return &models.EmailMessage{
+		To:      user.Email,
+		Subject: fmt.Sprintf("%s: %d", s.subject, len(data.Items)),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func formatAge(d time.Duration) string {
+	if d < time.Hour {
+		return "меньше часа"
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d/time.Hour) % 24
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%d д", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d ч", hours))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDigestUsers struct {
+	storage.UserStorage
+	recipients []models.User
+}
+
+func (f *fakeDigestUsers) ListDigestRecipientsTx(ctx context.Context, tx pgx.Tx) ([]models.User, error) {
+	return f.recipients, nil
+}
+
+type fakeDigestPRs struct {
+	storage.PullReqStorage
+	byReviewer map[string][]models.PullRequestShort
+	failFor    map[string]error
+}
+
+func (f *fakeDigestPRs) GetPRsByReviewerTx(ctx context.Context, tx pgx.Tx, userID string) ([]models.PullRequestShort, error) {
+	if err := f.failFor[userID]; err != nil {
+		return nil, err
+	}
+	return f.byReviewer[userID], nil
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpStub - минимальный SMTP-сервер в процессе: принимает письма без аутентификации
+type smtpStub struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	stub := &smtpStub{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return stub
+}
+
+func (s *smtpStub) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var msg smtpMessage
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		upper := strings.ToUpper(cmd)
+
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			msg = smtpMessage{from: strings.Trim(cmd[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestDigestService_SendsOpenReviewsOverSMTP(t *testing.T) {
+	stub := newSMTPStub(t)
+	host, port, err := net.SplitHostPort(stub.ln.Addr().String())
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	opened := now.Add(-50 * time.Hour)
+
+	users := &fakeDigestUsers{recipients: []models.User{
+		{UserID: "u2", Username: "Bob", Email: "bob@example.com", IsActive: true},
+		{UserID: "u3", Username: "Carol", Email: "carol@example.com", IsActive: true},
+	}}
+	prs := &fakeDigestPRs{byReviewer: map[string][]models.PullRequestShort{
+		"u2": {
+			{PullRequestID: "pr-1", PullRequestName: "Add <search>", AuthorID: "u1", Status: "OPEN", CreatedAt: &opened},
+			{PullRequestID: "pr-2", PullRequestName: "Old one", AuthorID: "u1", Status: "MERGED", CreatedAt: &opened},
+		},
+		"u3": {
+			{PullRequestID: "pr-3", PullRequestName: "Merged", AuthorID: "u1", Status: "MERGED", CreatedAt: &opened},
+		},
+	}}
+
+	mailer := NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "reviews@example.com"})
+	svc, err := NewDigestService(users, prs, mailer, DigestConfig{SendAt: "09:00"})
+	require.NoError(t, err)
+	svc.now = func() time.Time { return now }
+
+	sent, err := svc.SendDigests(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	received := stub.received()
+	require.Len(t, received, 1)
+	got := received[0]
+	assert.Equal(t, "reviews@example.com", got.from)
+	assert.Equal(t, []string{"bob@example.com"}, got.to)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(got.data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Ревью, которые ждут вас: 1", subject)
+
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+
+	textPart, err := mr.NextPart()
+	require.NoError(t, err)
+	text, _ := io.ReadAll(textPart)
+	assert.Contains(t, string(text), "Add <search> (pr-1)")
+	assert.Contains(t, string(text), "автор: u1, открыт 2 д 2 ч назад")
+	assert.NotContains(t, string(text), "pr-2")
+
+	htmlPart, err := mr.NextPart()
+	require.NoError(t, err)
+	html, _ := io.ReadAll(htmlPart)
+	assert.Contains(t, string(html), "Add &lt;search&gt;")
+}
+
+func TestDigestService_OneFailureDoesNotStopOthers(t *testing.T) {
+	stub := newSMTPStub(t)
+	host, port, err := net.SplitHostPort(stub.ln.Addr().String())
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	users := &fakeDigestUsers{recipients: []models.User{
+		{UserID: "u2", Email: "bob@example.com", IsActive: true},
+		{UserID: "u3", Email: "carol@example.com", IsActive: true},
+	}}
+	queryErr := errors.New("query failed")
+	prs := &fakeDigestPRs{
+		byReviewer: map[string][]models.PullRequestShort{
+			"u3": {{PullRequestID: "pr-3", PullRequestName: "Open", AuthorID: "u1", Status: "OPEN", CreatedAt: &now}},
+		},
+		failFor: map[string]error{"u2": queryErr},
+	}
+
+	mailer := NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "reviews@example.com"})
+	svc, err := NewDigestService(users, prs, mailer, DigestConfig{SendAt: "09:00"})
+	require.NoError(t, err)
+	svc.now = func() time.Time { return now }
+
+	sent, err := svc.SendDigests(context.Background())
+	assert.ErrorIs(t, err, queryErr)
+	assert.Equal(t, 1, sent)
+	require.Len(t, stub.received(), 1)
+	assert.Equal(t, []string{"carol@example.com"}, stub.received()[0].to)
+}
+
+func TestSMTPMailer_StalledServerTimesOut(t *testing.T) {
+	// принимает соединение и молчит - приветствия 220 не будет
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	mailer := NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "reviews@example.com", Timeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	err = mailer.Send(context.Background(), models.EmailMessage{To: "bob@example.com", Subject: "s", Text: "t"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := NewSMTPMailer(SMTPConfig{Host: host, Port: port, From: "reviews@example.com", Timeout: time.Hour})
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start = time.Now()
+	err = slow.Send(ctx, models.EmailMessage{To: "bob@example.com", Subject: "s", Text: "t"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestDigestService_NextRun(t *testing.T) {
+	svc, err := NewDigestService(nil, nil, nil, DigestConfig{SendAt: "09:30"})
+	require.NoError(t, err)
+
+	before := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 10, 9, 30, 0, 0, time.UTC), svc.nextRun(before))
+
+	after := time.Date(2025, 3, 10, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 11, 9, 30, 0, 0, time.UTC), svc.nextRun(after))
+}
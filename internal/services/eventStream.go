@@ -0,0 +1,188 @@
+package services
+
+/*
+Поток событий о ревью для SSE:
+	1. Приём событий из outbox (работает как sink диспетчера)
+	2. Кольцевой буфер последних событий для Last-Event-ID
+	3. Раздача подписчикам с фильтром по пользователю или команде
+
+Порядковый номер (Seq) живёт в памяти процесса и сбрасывается при рестарте.
+Каждый подписчик получает свой канал ограниченного размера: если клиент не
+успевает читать и канал заполнился, подписка закрывается - клиент
+переподключится с Last-Event-ID и дочитает пропущенное из буфера.
+С несколькими экземплярами сервиса поток содержит только события, которые
+разослал диспетчер этого экземпляра.
+
+Подписаться на поток пользователя может он сам, лид его команды или админ,
+на поток команды - её лид или админ.
+*/
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"test-task/internal/models"
+)
+
+var streamEventTypes = map[string]bool{
+	models.EventReviewerAssigned:   true,
+	models.EventReviewerReassigned: true,
+	models.EventPRMerged:           true,
+	models.EventSLABreached:        true,
+	models.EventPRStale:            true,
+	models.EventPRClosed:           true,
+	models.EventPRReopened:         true,
+}
+
+type streamItem struct {
+	msg    models.StreamMessage
+	review models.ReviewEvent
+}
+
+type StreamSubscription struct {
+	C      <-chan models.StreamMessage
+	ch     chan models.StreamMessage
+	filter models.StreamFilter
+}
+
+type EventStream struct {
+	authz        *Authorizer
+	mu           sync.Mutex
+	seq          uint64
+	buffer       []streamItem
+	next         int
+	replaySize   int
+	clientBuffer int
+	subs         map[*StreamSubscription]struct{}
+}
+
+func NewEventStream(authz *Authorizer, replaySize int, clientBuffer int) *EventStream {
+	return &EventStream{
+		authz:        authz,
+		buffer:       make([]streamItem, 0, replaySize),
+		replaySize:   replaySize,
+		clientBuffer: clientBuffer,
+		subs:         make(map[*StreamSubscription]struct{}),
+	}
+}
+
+func (s *EventStream) Name() string { return "sse" }
+
+func (s *EventStream) Deliver(ctx context.Context, event models.Event) error {
+	if !streamEventTypes[event.Type] {
+		return nil
+	}
+
+	var review models.ReviewEvent
+	if err := json.Unmarshal(event.Data, &review); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// outbox доставляет at-least-once - повтор уже разосланного события пропускаем
+	for _, item := range s.buffer {
+		if item.msg.Event.ID == event.ID {
+			return nil
+		}
+	}
+
+	s.seq++
+	item := streamItem{msg: models.StreamMessage{Seq: s.seq, Event: event}, review: review}
+	if len(s.buffer) < s.replaySize {
+		s.buffer = append(s.buffer, item)
+	} else if s.replaySize > 0 {
+		s.buffer[s.next] = item
+		s.next = (s.next + 1) % s.replaySize
+	}
+
+	for sub := range s.subs {
+		if !matchesStream(sub.filter, review) {
+			continue
+		}
+		select {
+		case sub.ch <- item.msg:
+		default:
+			s.drop(sub)
+		}
+	}
+
+	return nil
+}
+
+// Authorize проверяет, что Principal из ctx может читать поток с этим фильтром
+func (s *EventStream) Authorize(ctx context.Context, filter models.StreamFilter) error {
+	if filter.TeamName != "" {
+		if err := s.authz.RequireTeamLead(ctx, nil, filter.TeamName); err != nil {
+			return err
+		}
+	}
+	if filter.UserID != "" {
+		return s.authz.RequireUserAccess(ctx, nil, filter.UserID)
+	}
+	return nil
+}
+
+// Subscribe возвращает подписку и события после lastEventID из буфера.
+// gap = true, если часть событий после lastEventID уже вытеснена из буфера.
+func (s *EventStream) Subscribe(filter models.StreamFilter, lastEventID uint64) (*StreamSubscription, []models.StreamMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan models.StreamMessage, s.clientBuffer)
+	sub := &StreamSubscription{C: ch, ch: ch, filter: filter}
+	s.subs[sub] = struct{}{}
+
+	if lastEventID == 0 {
+		return sub, nil, false
+	}
+
+	var replay []models.StreamMessage
+	ordered := append(append([]streamItem{}, s.buffer[s.next:]...), s.buffer[:s.next]...)
+	for _, item := range ordered {
+		if item.msg.Seq > lastEventID && matchesStream(filter, item.review) {
+			replay = append(replay, item.msg)
+		}
+	}
+
+	gap := lastEventID > s.seq
+	if len(ordered) > 0 && ordered[0].msg.Seq > lastEventID+1 {
+		gap = true
+	}
+	if len(ordered) == 0 && lastEventID < s.seq {
+		gap = true
+	}
+
+	return sub, replay, gap
+}
+
+func (s *EventStream) Unsubscribe(sub *StreamSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(sub)
+}
+
+func (s *EventStream) drop(sub *StreamSubscription) {
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func matchesStream(filter models.StreamFilter, review models.ReviewEvent) bool {
+	if filter.TeamName != "" && review.TeamName != filter.TeamName {
+		return false
+	}
+	if filter.UserID == "" {
+		return true
+	}
+
+	if review.OldReviewer == filter.UserID || review.NewReviewer == filter.UserID {
+		return true
+	}
+	if contains(review.Reviewers, filter.UserID) {
+		return true
+	}
+	pr := review.PullRequest
+	return pr != nil && (pr.AuthorID == filter.UserID || contains(pr.AssignedReviewers, filter.UserID))
+}
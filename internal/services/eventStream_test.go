@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"test-task/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reviewEvent(t *testing.T, id string, eventType string, review models.ReviewEvent) models.Event {
+	data, err := json.Marshal(review)
+	require.NoError(t, err)
+	return models.Event{ID: id, Type: eventType, Data: data}
+}
+
+func assigned(t *testing.T, id string, team string, reviewers ...string) models.Event {
+	return reviewEvent(t, id, models.EventReviewerAssigned, models.ReviewEvent{
+		PullRequest: &models.PullRequest{PullRequestID: "pr-" + id, AuthorID: "author", AssignedReviewers: reviewers},
+		TeamName:    team,
+		Reviewers:   reviewers,
+	})
+}
+
+func TestEventStream_FilterAndDedup(t *testing.T) {
+	stream := NewEventStream(nil, 10, 10)
+	ctx := context.Background()
+
+	u1, _, _ := stream.Subscribe(models.StreamFilter{UserID: "u1"}, 0)
+	backend, _, _ := stream.Subscribe(models.StreamFilter{TeamName: "backend"}, 0)
+
+	require.NoError(t, stream.Deliver(ctx, assigned(t, "e1", "backend", "u1", "u2")))
+	require.NoError(t, stream.Deliver(ctx, assigned(t, "e2", "frontend", "u3")))
+	require.NoError(t, stream.Deliver(ctx, assigned(t, "e1", "backend", "u1", "u2")))
+	require.NoError(t, stream.Deliver(ctx, models.Event{ID: "e3", Type: models.EventTeamCreated, Data: []byte(`{}`)}))
+
+	require.Len(t, u1.C, 1)
+	assert.Equal(t, "e1", (<-u1.C).Event.ID)
+	require.Len(t, backend.C, 1)
+	msg := <-backend.C
+	assert.Equal(t, uint64(1), msg.Seq)
+}
+
+func TestEventStream_ReplayFromLastEventID(t *testing.T) {
+	stream := NewEventStream(nil, 3, 10)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, stream.Deliver(ctx, assigned(t, fmt.Sprintf("e%d", i), "backend", "u1")))
+	}
+
+	_, replay, gap := stream.Subscribe(models.StreamFilter{UserID: "u1"}, 3)
+	assert.False(t, gap)
+	require.Len(t, replay, 2)
+	assert.Equal(t, uint64(4), replay[0].Seq)
+	assert.Equal(t, uint64(5), replay[1].Seq)
+
+	// события 2 и 3 уже вытеснены из буфера на 3 элемента
+	_, replay, gap = stream.Subscribe(models.StreamFilter{UserID: "u1"}, 1)
+	assert.True(t, gap)
+	assert.Len(t, replay, 3)
+
+	// id из будущего - например, клиент пережил рестарт сервера
+	_, replay, gap = stream.Subscribe(models.StreamFilter{UserID: "u1"}, 100)
+	assert.True(t, gap)
+	assert.Empty(t, replay)
+}
+
+func TestEventStream_SlowClientIsDropped(t *testing.T) {
+	stream := NewEventStream(nil, 10, 2)
+	ctx := context.Background()
+
+	slow, _, _ := stream.Subscribe(models.StreamFilter{UserID: "u1"}, 0)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, stream.Deliver(ctx, assigned(t, fmt.Sprintf("e%d", i), "backend", "u1")))
+	}
+
+	<-slow.C
+	<-slow.C
+	_, ok := <-slow.C
+	assert.False(t, ok, "subscription must be closed after overflow")
+
+	// повторная отписка закрытой подписки безопасна
+	stream.Unsubscribe(slow)
+}
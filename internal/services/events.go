@@ -0,0 +1,48 @@
+package services
+
+/*
+Доменные события сервиса:
+	1. Сборка события (id, тип, время, data в JSON)
+	2. Запись события в outbox в той же транзакции, что и изменение состояния
+
+Событие уходит наружу только после коммита (его забирает диспетчер outbox),
+а при откате исчезает вместе с изменением.
+*/
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func newEvent(eventType string, data interface{}) (models.Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return models.Event{}, err
+	}
+
+	return models.Event{
+		ID:         hex.EncodeToString(id),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       raw,
+	}, nil
+}
+
+func recordEvent(ctx context.Context, tx pgx.Tx, outbox storage.OutboxStorage, eventType string, data interface{}) error {
+	event, err := newEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+	return outbox.AddEventTx(ctx, tx, event)
+}
@@ -0,0 +1,107 @@
+package services
+
+/*
+Подбор ревьюеров по экспертизе:
+	1. Совпадение навыков пользователя с тегами PR
+	2. Совпадение path_globs пользователя с изменёнными файлами
+
+Глобы: "*" - любой сегмент пути без "/", "**" - любое число каталогов,
+"?" - один символ. Сравнение навыков регистронезависимое.
+Глоб компилируется один раз на подбор и проверяется на всех файлах PR.
+Глобального кеша нет: шаблоны приходят от пользователей, и кеш по ним
+рос бы без ограничений.
+*/
+import (
+	"regexp"
+	"strings"
+	"test-task/internal/models"
+)
+
+func isExpert(user models.User, tags []string, changedFiles []string) bool {
+	for _, skill := range user.Skills {
+		for _, tag := range tags {
+			if strings.EqualFold(strings.TrimSpace(skill), strings.TrimSpace(tag)) {
+				return true
+			}
+		}
+	}
+	if len(changedFiles) == 0 {
+		return false
+	}
+	for _, pattern := range user.PathGlobs {
+		re := compileGlob(pattern)
+		if re == nil {
+			continue
+		}
+		for _, file := range changedFiles {
+			if re.MatchString(strings.TrimPrefix(file, "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pickReviewers выбирает до limit кандидатов, гарантируя хотя бы одного эксперта,
+// если он есть. Эксперты идут первыми, порядок внутри групп сохраняется.
+func pickReviewers(candidates []models.User, tags []string, changedFiles []string, limit int) []string {
+	var experts, others []string
+	for _, member := range candidates {
+		if isExpert(member, tags, changedFiles) {
+			experts = append(experts, member.UserID)
+		} else {
+			others = append(others, member.UserID)
+		}
+	}
+
+	var reviewers []string
+	for _, id := range append(experts, others...) {
+		if len(reviewers) >= limit {
+			break
+		}
+		reviewers = append(reviewers, id)
+	}
+	return reviewers
+}
+
+func matchGlob(pattern, name string) bool {
+	re := compileGlob(pattern)
+	return re != nil && re.MatchString(strings.TrimPrefix(name, "/"))
+}
+
+// compileGlob возвращает nil для пустого или некомпилируемого шаблона
+func compileGlob(pattern string) *regexp.Regexp {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
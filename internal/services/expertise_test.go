@@ -0,0 +1,59 @@
+package services
+
+import (
+	"test-task/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.css", "main.css", true},
+		{"*.css", "web/main.css", false},
+		{"web/**", "web/styles/main.css", true},
+		{"**/*.sql", "db/migrations/001.sql", true},
+		{"**/*.sql", "001.sql", true},
+		{"/db/*.sql", "db/001.sql", true},
+		{"db/?.sql", "db/1.sql", true},
+		{"db/?.sql", "db/10.sql", false},
+		{"", "main.go", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.want, matchGlob(c.pattern, c.name), "%s vs %s", c.pattern, c.name)
+	}
+}
+
+func TestPickReviewers_PrefersExperts(t *testing.T) {
+	candidates := []models.User{
+		{UserID: "dba1", Skills: []string{"sql"}},
+		{UserID: "dba2", Skills: []string{"sql"}},
+		{UserID: "front", Skills: []string{"Frontend"}},
+		{UserID: "css", PathGlobs: []string{"web/**/*.css"}},
+	}
+
+	t.Run("by tag", func(t *testing.T) {
+		got := pickReviewers(candidates, []string{"frontend"}, nil, 2)
+		assert.Equal(t, []string{"front", "dba1"}, got)
+	})
+
+	t.Run("by path", func(t *testing.T) {
+		got := pickReviewers(candidates, nil, []string{"web/app/main.css"}, 2)
+		assert.Equal(t, []string{"css", "dba1"}, got)
+	})
+
+	t.Run("tag and path", func(t *testing.T) {
+		got := pickReviewers(candidates, []string{"frontend"}, []string{"web/main.css"}, 2)
+		assert.Equal(t, []string{"front", "css"}, got)
+	})
+
+	t.Run("no experts keeps order", func(t *testing.T) {
+		got := pickReviewers(candidates, []string{"k8s"}, nil, 2)
+		assert.Equal(t, []string{"dba1", "dba2"}, got)
+	})
+}
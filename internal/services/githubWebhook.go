@@ -0,0 +1,130 @@
+package services
+
+/*
+Вебхук GitHub (событие pull_request):
+	1. Проверка X-Hub-Signature-256 (HMAC-SHA256 тела)
+	2. opened -> CreatePR, reopened -> ReopenPR
+	3. closed с merged=true (или merged) -> MergePR
+	4. closed с merged=false -> ClosePR (ревьюеры освобождаются)
+	5. Остальные действия и события - игнорируются
+
+id PR в сервисе - "<owner>/<repo>#<number>", проект - "<owner>/<repo>",
+метки PR становятся тегами.
+Пустой секрет означает, что вебхук выключен - любая подпись неверна.
+*/
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+)
+
+type GitHubWebhookService struct {
+	secret  string
+	applier *forgeEventApplier
+	deduper *webhookDeduper
+}
+
+func NewGitHubWebhookService(
+	prManager PullRequestManager,
+	deliveries storage.WebhookDeliveryStorage,
+	secret string,
+	users map[string]string,
+) *GitHubWebhookService {
+	return &GitHubWebhookService{
+		secret:  secret,
+		applier: &forgeEventApplier{prManager: prManager, users: newForgeUsers(users)},
+		deduper: &webhookDeduper{source: "github", deliveries: deliveries},
+	}
+}
+
+type githubPullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func (s *GitHubWebhookService) HandleDelivery(ctx context.Context, d models.WebhookDelivery) (*models.WebhookResult, error) {
+	if !s.validSignature(d.Signature, d.Body) {
+		return nil, models.ErrInvalidSignature
+	}
+
+	if d.Event != "pull_request" {
+		return &models.WebhookResult{DeliveryID: d.DeliveryID, Action: models.WebhookActionIgnored}, nil
+	}
+
+	var event githubPullRequestEvent
+	if err := json.Unmarshal(d.Body, &event); err != nil {
+		return nil, models.ErrInvalidPayload
+	}
+	if event.Repository.FullName == "" || event.PullRequest.Number == 0 {
+		return nil, models.ErrInvalidPayload
+	}
+
+	// подпись проверена - дальше действует система
+	ctx = SystemContext(ctx, "github")
+	return s.deduper.process(ctx, d.DeliveryID, func(ctx context.Context) (*models.WebhookResult, error) {
+		return s.applier.apply(ctx, s.toForgeEvent(event))
+	})
+}
+
+func (s *GitHubWebhookService) toForgeEvent(event githubPullRequestEvent) forgePREvent {
+	res := forgePREvent{
+		PRID:        fmt.Sprintf("%s#%d", event.Repository.FullName, event.PullRequest.Number),
+		Title:       event.PullRequest.Title,
+		AuthorLogin: event.PullRequest.User.Login,
+		Project:     event.Repository.FullName,
+	}
+	for _, label := range event.PullRequest.Labels {
+		res.Tags = append(res.Tags, label.Name)
+	}
+
+	switch {
+	case event.Action == "opened":
+		res.Action = forgeActionOpen
+	case event.Action == "reopened":
+		res.Action = forgeActionReopen
+	case event.Action == "merged" || event.Action == "closed" && event.PullRequest.Merged:
+		res.Action = forgeActionMerge
+	case event.Action == "closed":
+		res.Action = forgeActionClose
+	}
+
+	return res
+}
+
+func (s *GitHubWebhookService) validSignature(header string, body []byte) bool {
+	if s.secret == "" {
+		return false
+	}
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
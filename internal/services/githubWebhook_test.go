@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGitHubSecret = "It's a Secret to Everybody"
+
+type fakePRManager struct {
+	PullRequestManager
+	created  []models.CreatePRRequest
+	updated  []models.UpdatePRRequest
+	merged   []string
+	closed   []string
+	reopened []string
+	prs      map[string]bool
+	finished map[string]error // PR, закрытые или слитые в обход вебхука
+}
+
+func (f *fakePRManager) CreatePR(ctx context.Context, req models.CreatePRRequest) (*models.PullRequest, error) {
+	if f.prs == nil {
+		f.prs = make(map[string]bool)
+	}
+	if f.prs[req.PullRequestID] {
+		return nil, models.ErrPRExists
+	}
+	f.prs[req.PullRequestID] = true
+	f.created = append(f.created, req)
+	return &models.PullRequest{PullRequestID: req.PullRequestID}, nil
+}
+
+func (f *fakePRManager) MergePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	if !f.prs[prID] {
+		return nil, models.ErrNotFound
+	}
+	if err := f.finished[prID]; err != nil {
+		return nil, err
+	}
+	f.merged = append(f.merged, prID)
+	return &models.PullRequest{PullRequestID: prID, Status: "MERGED"}, nil
+}
+
+func (f *fakePRManager) UpdatePR(ctx context.Context, req models.UpdatePRRequest) (*models.PullRequest, error) {
+	if !f.prs[req.PullRequestID] {
+		return nil, models.ErrNotFound
+	}
+	f.updated = append(f.updated, req)
+	return &models.PullRequest{PullRequestID: req.PullRequestID, PullRequestName: req.PullRequestName}, nil
+}
+
+func (f *fakePRManager) ClosePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	if !f.prs[prID] {
+		return nil, models.ErrNotFound
+	}
+	if err := f.finished[prID]; err != nil {
+		return nil, err
+	}
+	f.closed = append(f.closed, prID)
+	return &models.PullRequest{PullRequestID: prID, Status: "CLOSED"}, nil
+}
+
+func (f *fakePRManager) ReopenPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	if !f.prs[prID] {
+		return nil, models.ErrNotFound
+	}
+	f.reopened = append(f.reopened, prID)
+	return &models.PullRequest{PullRequestID: prID, Status: "OPEN"}, nil
+}
+
+func (f *fakePRManager) SubmitReview(ctx context.Context, req models.ReviewDecisionRequest) (*models.PullRequest, error) {
+	return nil, models.ErrNotFound
+}
+
+// fakeDeliveries - захват виден только после Commit транзакции доставки
+type fakeDeliveries struct {
+	claimed map[string]bool
+}
+
+type fakeDeliveryTx struct {
+	pgx.Tx
+	deliveries *fakeDeliveries
+	pending    []string
+}
+
+func (t *fakeDeliveryTx) Commit(ctx context.Context) error {
+	for _, key := range t.pending {
+		t.deliveries.claimed[key] = true
+	}
+	t.pending = nil
+	return nil
+}
+
+func (t *fakeDeliveryTx) Rollback(ctx context.Context) error {
+	t.pending = nil
+	return nil
+}
+
+func (f *fakeDeliveries) DeliveryBeginTx(ctx context.Context) (pgx.Tx, error) {
+	if f.claimed == nil {
+		f.claimed = make(map[string]bool)
+	}
+	return &fakeDeliveryTx{deliveries: f}, nil
+}
+
+func (f *fakeDeliveries) ClaimDeliveryTx(ctx context.Context, tx pgx.Tx, source string, deliveryID string) (bool, error) {
+	key := source + "/" + deliveryID
+	if f.claimed[key] {
+		return false, nil
+	}
+	dtx := tx.(*fakeDeliveryTx)
+	dtx.pending = append(dtx.pending, key)
+	return true, nil
+}
+
+func githubDelivery(t *testing.T, fixture string, deliveryID string) models.WebhookDelivery {
+	body, err := os.ReadFile(filepath.Join("testdata", "github", fixture))
+	require.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte(testGitHubSecret))
+	mac.Write(body)
+
+	return models.WebhookDelivery{
+		DeliveryID: deliveryID,
+		Event:      "pull_request",
+		Signature:  "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+		Body:       body,
+	}
+}
+
+func newTestGitHubService() (*GitHubWebhookService, *fakePRManager) {
+	prs := &fakePRManager{}
+	svc := NewGitHubWebhookService(prs, &fakeDeliveries{}, testGitHubSecret, map[string]string{"octocat": "u1"})
+	return svc, prs
+}
+
+func TestGitHubWebhook_OpenedThenMerged(t *testing.T) {
+	svc, prs := newTestGitHubService()
+	ctx := context.Background()
+
+	res, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_opened.json", "d-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionCreated, res.Action)
+	assert.Equal(t, "acme/billing#42", res.PullRequestID)
+
+	require.Len(t, prs.created, 1)
+	assert.Equal(t, models.CreatePRRequest{
+		PullRequestID:   "acme/billing#42",
+		PullRequestName: "Add invoice search",
+		AuthorID:        "u1",
+		Project:         "acme/billing",
+		Tags:            []string{"sql", "backend"},
+	}, prs.created[0])
+
+	res, err = svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_closed_merged.json", "d-2"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionMerged, res.Action)
+	assert.Equal(t, []string{"acme/billing#42"}, prs.merged)
+}
+
+func TestGitHubWebhook_DuplicateDelivery(t *testing.T) {
+	svc, prs := newTestGitHubService()
+	ctx := context.Background()
+
+	_, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_opened.json", "d-1"))
+	require.NoError(t, err)
+
+	res, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_opened.json", "d-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionDuplicate, res.Action)
+	assert.Len(t, prs.created, 1)
+}
+
+func TestGitHubWebhook_ClosedThenReopened(t *testing.T) {
+	svc, prs := newTestGitHubService()
+	ctx := context.Background()
+
+	_, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_opened.json", "d-1"))
+	require.NoError(t, err)
+
+	res, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_closed.json", "d-2"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionClosed, res.Action)
+	assert.Equal(t, []string{"acme/billing#42"}, prs.closed)
+	assert.Empty(t, prs.merged)
+
+	res, err = svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_reopened.json", "d-3"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionReopened, res.Action)
+	assert.Equal(t, []string{"acme/billing#42"}, prs.reopened)
+	assert.Len(t, prs.created, 1)
+}
+
+func TestGitHubWebhook_ReopenedUnknownPRIsCreated(t *testing.T) {
+	svc, prs := newTestGitHubService()
+
+	res, err := svc.HandleDelivery(context.Background(), githubDelivery(t, "pull_request_reopened.json", "d-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionCreated, res.Action)
+	require.Len(t, prs.created, 1)
+	assert.Equal(t, "acme/billing#42", prs.created[0].PullRequestID)
+}
+
+func TestGitHubWebhook_IgnoredActions(t *testing.T) {
+	svc, prs := newTestGitHubService()
+	ctx := context.Background()
+
+	res, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_closed.json", "d-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionIgnored, res.Action)
+	assert.Empty(t, prs.closed)
+
+	d := githubDelivery(t, "pull_request_opened.json", "d-2")
+	d.Event = "push"
+	res, err = svc.HandleDelivery(ctx, d)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionIgnored, res.Action)
+	assert.Empty(t, prs.created)
+}
+
+func TestGitHubWebhook_BadSignature(t *testing.T) {
+	svc, prs := newTestGitHubService()
+
+	d := githubDelivery(t, "pull_request_opened.json", "d-1")
+	d.Body = append(d.Body, ' ')
+	_, err := svc.HandleDelivery(context.Background(), d)
+	assert.ErrorIs(t, err, models.ErrInvalidSignature)
+
+	d = githubDelivery(t, "pull_request_opened.json", "d-1")
+	d.Signature = ""
+	_, err = svc.HandleDelivery(context.Background(), d)
+	assert.ErrorIs(t, err, models.ErrInvalidSignature)
+
+	assert.Empty(t, prs.created)
+}
+
+func TestGitHubWebhook_UnmappedUserReleasesDelivery(t *testing.T) {
+	svc, prs := newTestGitHubService()
+	ctx := context.Background()
+
+	_, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_opened_unmapped.json", "d-1"))
+	assert.ErrorIs(t, err, models.ErrUnmappedUser)
+
+	svc.applier.users["stranger"] = "u2"
+	res, err := svc.HandleDelivery(ctx, githubDelivery(t, "pull_request_opened_unmapped.json", "d-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionCreated, res.Action)
+	assert.Equal(t, "u2", prs.created[0].AuthorID)
+}
+
+type txCheckingPRs struct {
+	*fakePRManager
+	inTx bool
+}
+
+func (f *txCheckingPRs) CreatePR(ctx context.Context, req models.CreatePRRequest) (*models.PullRequest, error) {
+	f.inTx = storage.InTx(ctx)
+	return f.fakePRManager.CreatePR(ctx, req)
+}
+
+func TestGitHubWebhook_ClaimAndChangeShareTransaction(t *testing.T) {
+	prs := &txCheckingPRs{fakePRManager: &fakePRManager{}}
+	deliveries := &fakeDeliveries{}
+	svc := NewGitHubWebhookService(prs, deliveries, testGitHubSecret, map[string]string{"octocat": "u1"})
+
+	_, err := svc.HandleDelivery(context.Background(), githubDelivery(t, "pull_request_opened.json", "d-1"))
+	require.NoError(t, err)
+	assert.True(t, prs.inTx, "PR is created in the delivery transaction")
+	assert.True(t, deliveries.claimed["github/d-1"])
+}
@@ -0,0 +1,128 @@
+package services
+
+/*
+Вебхук GitLab (Merge Request Hook):
+	1. Проверка X-Gitlab-Token (сравнение с секретом за постоянное время)
+	2. open -> CreatePR, reopen -> ReopenPR
+	3. update -> UpdatePR (название, путь проекта)
+	4. merge -> MergePR, close -> ClosePR (ревьюеры освобождаются)
+	5. Остальные действия и события - игнорируются
+
+id PR в сервисе - "<namespace>/<project>!<iid>", проект - path_with_namespace,
+метки MR становятся тегами. Идемпотентность - по X-Gitlab-Event-UUID.
+Автор MR - object_attributes.author_id, а не user (тот, кто совершил
+действие). Username автора в Merge Request Hook есть, только если действие
+совершил он сам; иначе в событии лишь числовой id, а ходить за username в
+API GitLab вебхук не должен. Поэтому GITLAB_USER_MAP принимает оба ключа,
+и для авторов, чьи MR открывает или переоткрывает кто-то другой, нужен
+числовой id (username:u1,17:u1).
+Пустой токен означает, что вебхук выключен.
+*/
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+)
+
+type GitLabWebhookService struct {
+	token   string
+	applier *forgeEventApplier
+	deduper *webhookDeduper
+}
+
+func NewGitLabWebhookService(
+	prManager PullRequestManager,
+	deliveries storage.WebhookDeliveryStorage,
+	token string,
+	users map[string]string,
+) *GitLabWebhookService {
+	return &GitLabWebhookService{
+		token:   token,
+		applier: &forgeEventApplier{prManager: prManager, users: newForgeUsers(users)},
+		deduper: &webhookDeduper{source: "gitlab", deliveries: deliveries},
+	}
+}
+
+type gitlabMergeRequestEvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID      int    `json:"iid"`
+		Title    string `json:"title"`
+		Action   string `json:"action"`
+		AuthorID int    `json:"author_id"`
+	} `json:"object_attributes"`
+	Labels []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
+}
+
+func (s *GitLabWebhookService) HandleDelivery(ctx context.Context, d models.WebhookDelivery) (*models.WebhookResult, error) {
+	if s.token == "" || subtle.ConstantTimeCompare([]byte(d.Signature), []byte(s.token)) != 1 {
+		return nil, models.ErrInvalidSignature
+	}
+
+	if d.Event != "Merge Request Hook" {
+		return &models.WebhookResult{DeliveryID: d.DeliveryID, Action: models.WebhookActionIgnored}, nil
+	}
+
+	var event gitlabMergeRequestEvent
+	if err := json.Unmarshal(d.Body, &event); err != nil {
+		return nil, models.ErrInvalidPayload
+	}
+	if event.ObjectKind != "merge_request" || event.Project.PathWithNamespace == "" || event.ObjectAttributes.IID == 0 {
+		return nil, models.ErrInvalidPayload
+	}
+
+	// токен проверен - дальше действует система
+	ctx = SystemContext(ctx, "gitlab")
+	return s.deduper.process(ctx, d.DeliveryID, func(ctx context.Context) (*models.WebhookResult, error) {
+		return s.applier.apply(ctx, s.toForgeEvent(event))
+	})
+}
+
+func (s *GitLabWebhookService) toForgeEvent(event gitlabMergeRequestEvent) forgePREvent {
+	res := forgePREvent{
+		PRID:        fmt.Sprintf("%s!%d", event.Project.PathWithNamespace, event.ObjectAttributes.IID),
+		Title:       event.ObjectAttributes.Title,
+		AuthorLogin: gitlabAuthorLogin(event),
+		Project:     event.Project.PathWithNamespace,
+	}
+	for _, label := range event.Labels {
+		res.Tags = append(res.Tags, label.Title)
+	}
+
+	switch event.ObjectAttributes.Action {
+	case "open":
+		res.Action = forgeActionOpen
+	case "reopen":
+		res.Action = forgeActionReopen
+	case "update":
+		res.Action = forgeActionUpdate
+	case "merge":
+		res.Action = forgeActionMerge
+	case "close":
+		res.Action = forgeActionClose
+	}
+
+	return res
+}
+
+// gitlabAuthorLogin - ключ автора MR для GITLAB_USER_MAP
+func gitlabAuthorLogin(event gitlabMergeRequestEvent) string {
+	authorID := event.ObjectAttributes.AuthorID
+	if authorID == 0 || event.User.ID == authorID {
+		return event.User.Username
+	}
+	return strconv.Itoa(authorID)
+}
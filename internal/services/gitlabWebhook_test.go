@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"test-task/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGitLabToken = "gl-hook-token"
+
+func gitlabDelivery(t *testing.T, fixture string, uuid string) models.WebhookDelivery {
+	body, err := os.ReadFile(filepath.Join("testdata", "gitlab", fixture))
+	require.NoError(t, err)
+
+	return models.WebhookDelivery{
+		DeliveryID: uuid,
+		Event:      "Merge Request Hook",
+		Signature:  testGitLabToken,
+		Body:       body,
+	}
+}
+
+func TestGitLabWebhook_Lifecycle(t *testing.T) {
+	prs := &fakePRManager{}
+	svc := NewGitLabWebhookService(prs, &fakeDeliveries{}, testGitLabToken, map[string]string{"dscully": "u7"})
+	ctx := context.Background()
+
+	steps := []struct {
+		fixture string
+		want    string
+	}{
+		{"merge_request_open.json", models.WebhookActionCreated},
+		{"merge_request_update.json", models.WebhookActionUpdated},
+		{"merge_request_close.json", models.WebhookActionClosed},
+		{"merge_request_reopen.json", models.WebhookActionReopened},
+		{"merge_request_merge.json", models.WebhookActionMerged},
+	}
+
+	for i, step := range steps {
+		res, err := svc.HandleDelivery(ctx, gitlabDelivery(t, step.fixture, "uuid-"+step.fixture))
+		require.NoError(t, err, step.fixture)
+		assert.Equal(t, step.want, res.Action, "step %d: %s", i, step.fixture)
+		assert.Equal(t, "finance/ledger!7", res.PullRequestID)
+	}
+
+	require.Len(t, prs.created, 1)
+	assert.Equal(t, models.CreatePRRequest{
+		PullRequestID:   "finance/ledger!7",
+		PullRequestName: "Partition ledger_entries by month",
+		AuthorID:        "u7",
+		Project:         "finance/ledger",
+		Tags:            []string{"sql"},
+	}, prs.created[0])
+	assert.Equal(t, []models.UpdatePRRequest{{
+		PullRequestID:   "finance/ledger!7",
+		PullRequestName: "Partition ledger_entries by month and year",
+		Project:         "finance/ledger",
+	}}, prs.updated)
+	assert.Equal(t, []string{"finance/ledger!7"}, prs.closed)
+	assert.Equal(t, []string{"finance/ledger!7"}, prs.reopened)
+	assert.Equal(t, []string{"finance/ledger!7"}, prs.merged)
+}
+
+func TestGitLabWebhook_AuthorIsNotActor(t *testing.T) {
+	prs := &fakePRManager{}
+	svc := NewGitLabWebhookService(prs, &fakeDeliveries{}, testGitLabToken, map[string]string{"17": "u7", "fmulder": "u9"})
+
+	// MR, о котором сервис не знал, переоткрыт мейнтейнером - автор остаётся автором
+	res, err := svc.HandleDelivery(context.Background(), gitlabDelivery(t, "merge_request_reopen_by_maintainer.json", "u-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionCreated, res.Action)
+	require.Len(t, prs.created, 1)
+	assert.Equal(t, "u7", prs.created[0].AuthorID)
+}
+
+func TestGitLabWebhook_DuplicateEventUUID(t *testing.T) {
+	prs := &fakePRManager{}
+	deliveries := &fakeDeliveries{}
+	svc := NewGitLabWebhookService(prs, deliveries, testGitLabToken, map[string]string{"dscully": "u7"})
+	ctx := context.Background()
+
+	_, err := svc.HandleDelivery(ctx, gitlabDelivery(t, "merge_request_open.json", "same"))
+	require.NoError(t, err)
+	res, err := svc.HandleDelivery(ctx, gitlabDelivery(t, "merge_request_open.json", "same"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionDuplicate, res.Action)
+
+	// тот же id от другого источника - не дубликат
+	gh := NewGitHubWebhookService(prs, deliveries, testGitHubSecret, map[string]string{"octocat": "u1"})
+	res, err = gh.HandleDelivery(ctx, githubDelivery(t, "pull_request_opened.json", "same"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionCreated, res.Action)
+}
+
+func TestGitLabWebhook_BadToken(t *testing.T) {
+	svc := NewGitLabWebhookService(&fakePRManager{}, &fakeDeliveries{}, testGitLabToken, nil)
+
+	d := gitlabDelivery(t, "merge_request_open.json", "u-1")
+	d.Signature = "wrong"
+	_, err := svc.HandleDelivery(context.Background(), d)
+	assert.ErrorIs(t, err, models.ErrInvalidSignature)
+
+	disabled := NewGitLabWebhookService(&fakePRManager{}, &fakeDeliveries{}, "", nil)
+	d.Signature = ""
+	_, err = disabled.HandleDelivery(context.Background(), d)
+	assert.ErrorIs(t, err, models.ErrInvalidSignature)
+}
+
+func TestGitLabWebhook_AlreadyFinishedPRIsIgnored(t *testing.T) {
+	prs := &fakePRManager{
+		prs:      map[string]bool{"finance/ledger!7": true},
+		finished: map[string]error{"finance/ledger!7": models.ErrPRClosed},
+	}
+	svc := NewGitLabWebhookService(prs, &fakeDeliveries{}, testGitLabToken, map[string]string{"dscully": "u7"})
+
+	// закрыт через API, а в GitLab слит - повторять доставку незачем
+	res, err := svc.HandleDelivery(context.Background(), gitlabDelivery(t, "merge_request_merge.json", "m-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionIgnored, res.Action)
+
+	prs.finished["finance/ledger!7"] = models.ErrPRMerged
+	res, err = svc.HandleDelivery(context.Background(), gitlabDelivery(t, "merge_request_close.json", "c-1"))
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookActionIgnored, res.Action)
+}
+
+func TestGitLabWebhook_ForeignActorNeedsNumericID(t *testing.T) {
+	// по username автора не найти: в событии только author_id, username - у мейнтейнера
+	prs := &fakePRManager{}
+	svc := NewGitLabWebhookService(prs, &fakeDeliveries{}, testGitLabToken, map[string]string{"dscully": "u7"})
+
+	_, err := svc.HandleDelivery(context.Background(), gitlabDelivery(t, "merge_request_reopen_by_maintainer.json", "u-1"))
+	assert.ErrorIs(t, err, models.ErrUnmappedUser)
+	assert.Empty(t, prs.created)
+}
@@ -0,0 +1,103 @@
+package services
+
+/*
+Idempotency-Key для изменяющих запросов:
+	1. Begin захватывает ключ клиента: новый - выполняем запрос, выполненный
+	   с тем же отпечатком - отдаём сохранённый ответ
+	2. Complete сохраняет ответ на TTL, Release освобождает ключ после сбоя,
+	   чтобы повтор выполнился заново
+	3. Фоновая очистка просроченных ключей
+
+Отпечаток - SHA-256 от метода, пути и тела. Тот же ключ с другим телом -
+ошибка клиента, а не повтор. Пока первый запрос выполняется, повтор
+получает ErrIdempotencyInProgress.
+*/
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"time"
+)
+
+type IdempotencyService struct {
+	storage  storage.IdempotencyStorage
+	ttl      time.Duration
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewIdempotencyService(storage storage.IdempotencyStorage, ttl time.Duration, interval time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		storage:  storage,
+		ttl:      ttl,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+func IdempotencyFingerprint(method string, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin возвращает nil, если запрос нужно выполнить, или сохранённый ответ для повтора
+func (s *IdempotencyService) Begin(ctx context.Context, scope string, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+	if key == "" || len(key) > 255 {
+		return nil, models.ErrInvalidIdempotencyKey
+	}
+
+	record, claimed, err := s.storage.ClaimIdempotencyKeyTx(ctx, nil, scope, key, fingerprint, s.now().Add(s.ttl))
+	if err != nil {
+		if err == models.ErrNotFound {
+			// ключ освободили между захватом и чтением - первый запрос упал
+			return nil, models.ErrIdempotencyInProgress
+		}
+		return nil, err
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, models.ErrIdempotencyKeyReused
+	}
+	if !record.Completed {
+		return nil, models.ErrIdempotencyInProgress
+	}
+	return record, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, scope string, key string, statusCode int, contentType string, body []byte) error {
+	return s.storage.CompleteIdempotencyKeyTx(ctx, nil, scope, key, statusCode, contentType, body)
+}
+
+func (s *IdempotencyService) Release(ctx context.Context, scope string, key string) error {
+	return s.storage.ReleaseIdempotencyKeyTx(ctx, nil, scope, key)
+}
+
+// Run крутится до отмены ctx
+func (s *IdempotencyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.storage.DeleteExpiredIdempotencyKeysTx(ctx, nil, s.now())
+			if err != nil {
+				slog.Error("Failed to delete expired idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Debug("Deleted expired idempotency keys", "count", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIdempotency struct {
+	storage.IdempotencyStorage
+	records map[string]*models.IdempotencyRecord
+}
+
+func (f *fakeIdempotency) ClaimIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string, fingerprint string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	if r, ok := f.records[scope+"/"+key]; ok {
+		copied := *r
+		return &copied, false, nil
+	}
+	f.records[scope+"/"+key] = &models.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint, ExpiresAt: expiresAt}
+	return nil, true, nil
+}
+
+func (f *fakeIdempotency) CompleteIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string, statusCode int, contentType string, body []byte) error {
+	r := f.records[scope+"/"+key]
+	r.Completed, r.StatusCode, r.ContentType, r.Body = true, statusCode, contentType, body
+	return nil
+}
+
+func (f *fakeIdempotency) ReleaseIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string) error {
+	delete(f.records, scope+"/"+key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	idem := NewIdempotencyService(&fakeIdempotency{records: map[string]*models.IdempotencyRecord{}}, time.Hour, time.Hour)
+	ctx := context.Background()
+	fp := IdempotencyFingerprint("POST", "/pullRequest/reassign", []byte(`{"pull_request_id":"pr-1","old_user_id":"u1"}`))
+
+	record, err := idem.Begin(ctx, "token:1", "k1", fp)
+	require.NoError(t, err)
+	assert.Nil(t, record, "first request is executed")
+
+	_, err = idem.Begin(ctx, "token:1", "k1", fp)
+	assert.Equal(t, models.ErrIdempotencyInProgress, err)
+
+	require.NoError(t, idem.Complete(ctx, "token:1", "k1", 200, "application/json", []byte(`{"replaced_by":"u2"}`)))
+
+	record, err = idem.Begin(ctx, "token:1", "k1", fp)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, 200, record.StatusCode)
+	assert.Equal(t, `{"replaced_by":"u2"}`, string(record.Body))
+
+	other := IdempotencyFingerprint("POST", "/pullRequest/reassign", []byte(`{"pull_request_id":"pr-2","old_user_id":"u1"}`))
+	_, err = idem.Begin(ctx, "token:1", "k1", other)
+	assert.Equal(t, models.ErrIdempotencyKeyReused, err)
+
+	record, err = idem.Begin(ctx, "token:2", "k1", other)
+	require.NoError(t, err)
+	assert.Nil(t, record, "keys are scoped per client")
+
+	require.NoError(t, idem.Release(ctx, "token:2", "k1"))
+	record, err = idem.Begin(ctx, "token:2", "k1", other)
+	require.NoError(t, err)
+	assert.Nil(t, record, "released key is executed again")
+
+	_, err = idem.Begin(ctx, "token:1", "", fp)
+	assert.Equal(t, models.ErrInvalidIdempotencyKey, err)
+}
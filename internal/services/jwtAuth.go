@@ -0,0 +1,422 @@
+package services
+
+/*
+Аутентификация по JWT от корпоративного SSO:
+	1. Разбор и проверка подписи (RS256 / ES256) ключом из JWKS по kid
+	2. Проверка iss, aud, exp и nbf с допуском на рассинхрон часов
+	3. Claim из конфига (по умолчанию sub) -> users.user_id
+
+JWKS берётся из файла или по URL и кешируется на RefreshInterval.
+Неизвестный kid - признак ротации ключей: кеш перечитывается сразу, но не
+чаще раза в minJWKSRefresh, чтобы мусорные токены не заваливали IdP.
+Неудачная загрузка тоже повторяется не чаще minJWKSRefresh. Загрузка идёт
+без блокировки кеша и одна на всех: остальные запросы ждут её результата.
+
+Скоупы - всегда DefaultScopes. Claim "scope" IdP описывает доступ к IdP
+(openid, email, ...), а не к сервису, и не учитывается. Admin выдаётся
+только по явному сопоставлению: значение из AdminValues в claim AdminClaim
+(строка через пробел или массив, например groups).
+*/
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"time"
+)
+
+const minJWKSRefresh = 10 * time.Second
+
+type JWTConfig struct {
+	JWKSSource      string // путь к файлу или http(s) URL
+	Issuer          string
+	Audience        string
+	UserClaim       string
+	DefaultScopes   []string
+	AdminClaim      string
+	AdminValues     []string
+	Leeway          time.Duration
+	RefreshInterval time.Duration
+	Timeout         time.Duration
+}
+
+type JWTAuthenticator struct {
+	cfg         JWTConfig
+	userStorage storage.UserStorage
+	client      *http.Client
+	now         func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	failedAt  time.Time
+	loadErr   error
+	loading   chan struct{} // закрывается по окончании текущей загрузки
+}
+
+func NewJWTAuthenticator(userStorage storage.UserStorage, cfg JWTConfig) *JWTAuthenticator {
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = "sub"
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 10 * time.Minute
+	}
+	return &JWTAuthenticator{
+		cfg:         cfg,
+		userStorage: userStorage,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		now:         time.Now,
+	}
+}
+
+// looksLikeJWT - три base64url-сегмента через точку; API-токены так не выглядят
+func looksLikeJWT(raw string) bool {
+	return strings.Count(raw, ".") == 2
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, raw string) (*models.Principal, error) {
+	claims, err := a.verify(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := claims[a.cfg.UserClaim].(string)
+	if userID == "" {
+		return nil, fmt.Errorf("%w: claim %q is missing", models.ErrUnauthorized, a.cfg.UserClaim)
+	}
+	if _, err := a.userStorage.GetUserTx(ctx, nil, userID); err != nil {
+		if err == models.ErrNotFound {
+			return nil, fmt.Errorf("%w: unknown user %q", models.ErrUnauthorized, userID)
+		}
+		return nil, err
+	}
+
+	scopes := append([]string(nil), a.cfg.DefaultScopes...)
+	if a.isAdmin(claims) && !contains(scopes, models.ScopeAdmin) {
+		scopes = append(scopes, models.ScopeAdmin)
+	}
+
+	return &models.Principal{
+		Type:   models.PrincipalUser,
+		ID:     userID,
+		Scopes: scopes,
+	}, nil
+}
+
+func (a *JWTAuthenticator) isAdmin(claims map[string]interface{}) bool {
+	if a.cfg.AdminClaim == "" || len(a.cfg.AdminValues) == 0 {
+		return false
+	}
+
+	var values []string
+	switch v := claims[a.cfg.AdminClaim].(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	for _, v := range values {
+		if contains(a.cfg.AdminValues, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *JWTAuthenticator) verify(ctx context.Context, raw string) (map[string]interface{}, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed JWT", models.ErrUnauthorized)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", models.ErrUnauthorized)
+	}
+
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !verifyJWTSignature(header.Alg, key, digest[:], signature) {
+		return nil, fmt.Errorf("%w: invalid signature", models.ErrUnauthorized)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// verifyJWTSignature принимает только асимметричные алгоритмы, подходящие
+// к типу ключа: "none" и HS256 с публичным ключом как секретом не пройдут
+func verifyJWTSignature(alg string, key crypto.PublicKey, digest []byte, signature []byte) bool {
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, signature) == nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func (a *JWTAuthenticator) validateClaims(claims map[string]interface{}) error {
+	now := a.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: exp is required", models.ErrUnauthorized)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(a.cfg.Leeway)) {
+		return fmt.Errorf("%w: token expired", models.ErrUnauthorized)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: token not valid yet", models.ErrUnauthorized)
+	}
+
+	if a.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.cfg.Issuer {
+			return fmt.Errorf("%w: unexpected issuer", models.ErrUnauthorized)
+		}
+	}
+
+	if a.cfg.Audience != "" && !audienceContains(claims["aud"], a.cfg.Audience) {
+		return fmt.Errorf("%w: unexpected audience", models.ErrUnauthorized)
+	}
+
+	return nil
+}
+
+// aud по RFC 7519 - строка или массив строк
+func audienceContains(aud interface{}, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeJWTSegment(segment string, dst interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed JWT", models.ErrUnauthorized)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("%w: malformed JWT", models.ErrUnauthorized)
+	}
+	return nil
+}
+
+func (a *JWTAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	for {
+		a.mu.Lock()
+		now := a.now()
+		age := now.Sub(a.fetchedAt)
+		key, ok := a.keys[kid]
+
+		// ключ не нашёлся или кеш устарел; при ротации и после неудачи
+		// перечитываем не чаще minJWKSRefresh
+		reload := a.keys == nil || age >= a.cfg.RefreshInterval || (!ok && age >= minJWKSRefresh)
+		if now.Sub(a.failedAt) < minJWKSRefresh {
+			reload = false
+		}
+
+		if !reload {
+			loadErr := a.loadErr
+			a.mu.Unlock()
+			switch {
+			case ok:
+				return key, nil // в т.ч. устаревший, если IdP недоступен
+			case loadErr != nil && a.keys == nil:
+				return nil, loadErr
+			default:
+				return nil, fmt.Errorf("%w: unknown key id %q", models.ErrUnauthorized, kid)
+			}
+		}
+
+		if loading := a.loading; loading != nil {
+			a.mu.Unlock()
+			select {
+			case <-loading:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		loading := make(chan struct{})
+		a.loading = loading
+		a.mu.Unlock()
+
+		keys, err := a.loadJWKS(ctx)
+
+		a.mu.Lock()
+		if err != nil {
+			a.failedAt = a.now()
+			a.loadErr = err
+		} else {
+			a.keys = keys
+			a.fetchedAt = a.now()
+			a.loadErr = nil
+		}
+		a.loading = nil
+		close(loading)
+		a.mu.Unlock()
+	}
+}
+
+func (a *JWTAuthenticator) loadJWKS(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var data []byte
+	var err error
+
+	if strings.HasPrefix(a.cfg.JWKSSource, "http://") || strings.HasPrefix(a.cfg.JWKSSource, "https://") {
+		data, err = a.fetchJWKS(ctx)
+	} else {
+		data, err = os.ReadFile(a.cfg.JWKSSource)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	return parseJWKS(data)
+}
+
+func (a *JWTAuthenticator) fetchJWKS(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.cfg.JWKSSource, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS пропускает ключи не для подписи и неподдерживаемых типов
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 coordinates")
+		}
+		// проверяет, что точка лежит на кривой
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
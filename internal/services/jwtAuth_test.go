@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"test-task/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testJWTNow = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+type testSigner struct {
+	kid string
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newRSASigner(t *testing.T, kid string) testSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return testSigner{kid: kid, rsa: key}
+}
+
+func newECSigner(t *testing.T, kid string) testSigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return testSigner{kid: kid, ec: key}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (s testSigner) jwk() map[string]string {
+	if s.rsa != nil {
+		return map[string]string{
+			"kty": "RSA", "kid": s.kid, "use": "sig",
+			"n": b64(s.rsa.N.Bytes()),
+			"e": b64(big.NewInt(int64(s.rsa.E)).Bytes()),
+		}
+	}
+	pub, err := s.ec.PublicKey.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	return map[string]string{
+		"kty": "EC", "kid": s.kid, "use": "sig", "crv": "P-256",
+		"x": b64(pub[1:33]),
+		"y": b64(pub[33:]),
+	}
+}
+
+func (s testSigner) sign(t *testing.T, claims map[string]interface{}) string {
+	alg := "RS256"
+	if s.ec != nil {
+		alg = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": s.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	var err error
+	if s.rsa != nil {
+		sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsa, crypto.SHA256, digest[:])
+	} else {
+		var r, ss *big.Int
+		r, ss, err = ecdsa.Sign(rand.Reader, s.ec, digest[:])
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		ss.FillBytes(sig[32:])
+	}
+	require.NoError(t, err)
+	return input + "." + b64(sig)
+}
+
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	signers []testSigner
+	hits    int
+	down    bool
+}
+
+func newJWKSServer(signers ...testSigner) *jwksServer {
+	s := &jwksServer{signers: signers}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.hits++
+		if s.down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var keys []map[string]string
+		for _, signer := range s.signers {
+			keys = append(keys, signer.jwk())
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	return s
+}
+
+func (s *jwksServer) rotate(signers ...testSigner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signers = signers
+}
+
+func newTestJWT(source string) *JWTAuthenticator {
+	a := NewJWTAuthenticator(&fakeAuthUsers{}, JWTConfig{
+		JWKSSource:    source,
+		Issuer:        "https://sso.example.com",
+		Audience:      "reviewer",
+		DefaultScopes: []string{models.ScopeRead, models.ScopeWrite},
+		Leeway:        30 * time.Second,
+		Timeout:       time.Second,
+	})
+	a.now = func() time.Time { return testJWTNow }
+	return a
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": "https://sso.example.com",
+		"aud": []string{"other", "reviewer"},
+		"sub": "u1",
+		"exp": testJWTNow.Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTAuthenticator_ValidTokens(t *testing.T) {
+	rsaSigner := newRSASigner(t, "rsa-1")
+	ecSigner := newECSigner(t, "ec-1")
+	server := newJWKSServer(rsaSigner, ecSigner)
+	defer server.Close()
+	auth := newTestJWT(server.URL)
+
+	for _, signer := range []testSigner{rsaSigner, ecSigner} {
+		p, err := auth.Authenticate(context.Background(), signer.sign(t, validClaims()))
+		require.NoError(t, err, signer.kid)
+		assert.Equal(t, models.PrincipalUser, p.Type)
+		assert.Equal(t, "u1", p.ID)
+		assert.Equal(t, []string{models.ScopeRead, models.ScopeWrite}, p.Scopes)
+	}
+	assert.Equal(t, 1, server.hits, "JWKS is cached")
+
+	// скоупы IdP не про сервис: обычный OIDC-токен получает DefaultScopes
+	claims := validClaims()
+	claims["scope"] = "openid email"
+	p, err := auth.Authenticate(context.Background(), rsaSigner.sign(t, claims))
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.ScopeRead, models.ScopeWrite}, p.Scopes)
+
+	claims["scope"] = "openid admin"
+	p, err = auth.Authenticate(context.Background(), rsaSigner.sign(t, claims))
+	require.NoError(t, err)
+	assert.NotContains(t, p.Scopes, models.ScopeAdmin)
+}
+
+func TestJWTAuthenticator_AdminMapping(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	server := newJWKSServer(signer)
+	defer server.Close()
+	auth := newTestJWT(server.URL)
+	auth.cfg.AdminClaim = "groups"
+	auth.cfg.AdminValues = []string{"review-admins"}
+
+	cases := []struct {
+		groups interface{}
+		admin  bool
+	}{
+		{[]string{"dev", "review-admins"}, true},
+		{"dev review-admins", true},
+		{[]string{"dev", "admin"}, false},
+		{nil, false},
+	}
+	for _, tc := range cases {
+		claims := validClaims()
+		if tc.groups != nil {
+			claims["groups"] = tc.groups
+		}
+		p, err := auth.Authenticate(context.Background(), signer.sign(t, claims))
+		require.NoError(t, err)
+		assert.Equal(t, tc.admin, p.HasScope(models.ScopeAdmin), "%v", tc.groups)
+	}
+}
+
+func TestJWTAuthenticator_JWKSFailureIsNotRetriedPerRequest(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	server := newJWKSServer(signer)
+	defer server.Close()
+	server.down = true
+	auth := newTestJWT(server.URL)
+
+	for i := 0; i < 3; i++ {
+		_, err := auth.Authenticate(context.Background(), signer.sign(t, validClaims()))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, server.hits)
+
+	server.mu.Lock()
+	server.down = false
+	server.mu.Unlock()
+	auth.now = func() time.Time { return testJWTNow.Add(minJWKSRefresh) }
+	_, err := auth.Authenticate(context.Background(), signer.sign(t, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.hits)
+}
+
+func TestJWTAuthenticator_Rejects(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	stranger := newRSASigner(t, "rsa-1")
+	server := newJWKSServer(signer)
+	defer server.Close()
+	auth := newTestJWT(server.URL)
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	cases := map[string]string{
+		"expired":        signer.sign(t, with("exp", testJWTNow.Add(-time.Minute).Unix())),
+		"no exp":         signer.sign(t, with("exp", nil)),
+		"not yet valid":  signer.sign(t, with("nbf", testJWTNow.Add(time.Hour).Unix())),
+		"wrong issuer":   signer.sign(t, with("iss", "https://evil.example.com")),
+		"wrong audience": signer.sign(t, with("aud", "someone-else")),
+		"unknown user":   signer.sign(t, with("sub", "ghost")),
+		"foreign key":    stranger.sign(t, validClaims()),
+		"alg none":       b64([]byte(`{"alg":"none","kid":"rsa-1"}`)) + "." + b64([]byte(`{"sub":"u1"}`)) + ".",
+	}
+	tampered := strings.Split(signer.sign(t, validClaims()), ".")
+	tampered[1] = b64([]byte(`{"sub":"u1","iss":"https://sso.example.com","aud":"reviewer","exp":9999999999,"scope":"admin"}`))
+	cases["tampered payload"] = strings.Join(tampered, ".")
+
+	for name, token := range cases {
+		_, err := auth.Authenticate(context.Background(), token)
+		assert.True(t, errors.Is(err, models.ErrUnauthorized), "%s: %v", name, err)
+	}
+
+	expiredWithinLeeway := signer.sign(t, with("exp", testJWTNow.Add(-10*time.Second).Unix()))
+	_, err := auth.Authenticate(context.Background(), expiredWithinLeeway)
+	assert.NoError(t, err)
+}
+
+func TestJWTAuthenticator_KeyRotation(t *testing.T) {
+	oldKey := newRSASigner(t, "2025-04")
+	newKey := newECSigner(t, "2025-05")
+	server := newJWKSServer(oldKey)
+	defer server.Close()
+	auth := newTestJWT(server.URL)
+
+	_, err := auth.Authenticate(context.Background(), oldKey.sign(t, validClaims()))
+	require.NoError(t, err)
+
+	server.rotate(oldKey, newKey)
+
+	// сразу после загрузки неизвестный kid не вызывает повторный запрос
+	_, err = auth.Authenticate(context.Background(), newKey.sign(t, validClaims()))
+	assert.True(t, errors.Is(err, models.ErrUnauthorized))
+	assert.Equal(t, 1, server.hits)
+
+	auth.now = func() time.Time { return testJWTNow.Add(minJWKSRefresh) }
+	_, err = auth.Authenticate(context.Background(), newKey.sign(t, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.hits)
+}
+
+func TestJWTAuthenticator_JWKSFile(t *testing.T) {
+	signer := newECSigner(t, "file-key")
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{signer.jwk()}})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	p, err := newTestJWT(path).Authenticate(context.Background(), signer.sign(t, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "u1", p.ID)
+}
+
+func TestAuthService_RoutesJWTToAuthenticator(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	server := newJWKSServer(signer)
+	defer server.Close()
+
+	svc := NewAuthService(&fakeAPITokens{byHash: map[string]*models.APIToken{}}, &fakeAuthUsers{}, "", newTestJWT(server.URL))
+	p, err := svc.Authenticate(context.Background(), signer.sign(t, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "u1", p.ID)
+
+	_, err = svc.Authenticate(context.Background(), "rvw_not-a-jwt")
+	assert.Equal(t, models.ErrUnauthorized, err)
+}
@@ -0,0 +1,101 @@
+package services
+
+/*
+Notifier'ы чатов - отправка сообщения в incoming webhook:
+	1. slack      - {"text": ...}, разметка mrkdwn
+	2. mattermost - {"text": ..., "username": ...}, разметка markdown
+
+Оба принимают ответ 2xx как успех, остальное - ошибка, которую
+ChatNotificationService повторит на следующем сбросе. Адреса webhook'ов
+задают пользователи, поэтому без allowPrivate соединения с приватными и
+loopback-адресами рвутся, как у исходящих вебхуков.
+*/
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"test-task/internal/models"
+	"time"
+)
+
+type Notifier interface {
+	Send(ctx context.Context, webhookURL string, msg models.ChatMessage) error
+}
+
+type SlackNotifier struct {
+	client *http.Client
+}
+
+func NewSlackNotifier(timeout time.Duration, allowPrivate bool) *SlackNotifier {
+	return &SlackNotifier{client: &http.Client{Timeout: timeout, Transport: webhookTransport(timeout, allowPrivate)}}
+}
+
+func (n *SlackNotifier) Send(ctx context.Context, webhookURL string, msg models.ChatMessage) error {
+	var b strings.Builder
+	if msg.Title != "" {
+		fmt.Fprintf(&b, "*%s*\n", msg.Title)
+	}
+	for _, line := range msg.Lines {
+		fmt.Fprintf(&b, "• %s\n", line)
+	}
+
+	return postChatJSON(ctx, n.client, webhookURL, map[string]string{
+		"text": strings.TrimSuffix(b.String(), "\n"),
+	})
+}
+
+type MattermostNotifier struct {
+	client   *http.Client
+	username string
+}
+
+func NewMattermostNotifier(timeout time.Duration, allowPrivate bool, username string) *MattermostNotifier {
+	return &MattermostNotifier{client: &http.Client{Timeout: timeout, Transport: webhookTransport(timeout, allowPrivate)}, username: username}
+}
+
+func (n *MattermostNotifier) Send(ctx context.Context, webhookURL string, msg models.ChatMessage) error {
+	var b strings.Builder
+	if msg.Title != "" {
+		fmt.Fprintf(&b, "#### %s\n", msg.Title)
+	}
+	for _, line := range msg.Lines {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+
+	payload := map[string]string{
+		"text": strings.TrimSuffix(b.String(), "\n"),
+	}
+	if n.username != "" {
+		payload["username"] = n.username
+	}
+	return postChatJSON(ctx, n.client, webhookURL, payload)
+}
+
+func postChatJSON(ctx context.Context, client *http.Client, webhookURL string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package services
+
+/*
+Исходящие вебхуки:
+	1. Управление подписками (url, секрет, фильтр событий)
+	2. Постановка событий в очередь доставок (вызывается диспетчером outbox)
+	3. Фоновая отправка с подписью HMAC-SHA256 и повторами
+	4. Журнал доставок
+
+События приходят из outbox уже после коммита, отправка идёт в фоне из Run -
+исходную транзакцию это не задерживает и не откатывает.
+Повторы - экспоненциально от OutboundWebhookConfig.BaseBackoff до MaxBackoff,
+после MaxAttempts неудач доставка переходит в DEAD.
+
+Подписками управляет только админ. Адреса в приватных, loopback и link-local
+сетях отклоняются при регистрации и ещё раз при соединении (имя могло начать
+резолвиться во внутренний адрес после проверки). AllowPrivateTargets
+отключает обе проверки - для стендов, где получатели во внутренней сети.
+*/
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"time"
+)
+
+var errPrivateTarget = errors.New("target address is not public")
+
+type OutboundWebhookConfig struct {
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	Timeout      time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+
+	AllowPrivateTargets bool
+}
+
+type OutboundWebhookService struct {
+	storage  storage.SubscriptionStorage
+	authz    *Authorizer
+	client   *http.Client
+	cfg      OutboundWebhookConfig
+	lookupIP func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+var knownEventTypes = map[string]bool{
+	models.EventReviewerAssigned:   true,
+	models.EventReviewerReassigned: true,
+	models.EventPRMerged:           true,
+	models.EventSLABreached:        true,
+	models.EventPRStale:            true,
+	models.EventPRClosed:           true,
+	models.EventPRReopened:         true,
+	models.EventUserActiveChanged:  true,
+	models.EventTeamCreated:        true,
+}
+
+func NewOutboundWebhookService(storage storage.SubscriptionStorage, authz *Authorizer, cfg OutboundWebhookConfig) *OutboundWebhookService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+
+	return &OutboundWebhookService{
+		storage:  storage,
+		authz:    authz,
+		client:   &http.Client{Timeout: cfg.Timeout, Transport: webhookTransport(cfg.Timeout, cfg.AllowPrivateTargets)},
+		cfg:      cfg,
+		lookupIP: net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+func (s *OutboundWebhookService) CreateSubscription(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error) {
+	if err := s.authz.RequireAdmin(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http(s) url", models.ErrInvalidSubscription)
+	}
+	if err := s.checkTarget(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+	if req.Secret == "" {
+		return nil, fmt.Errorf("%w: secret is required", models.ErrInvalidSubscription)
+	}
+	for _, eventType := range req.Events {
+		if !knownEventTypes[eventType] {
+			return nil, fmt.Errorf("%w: unknown event %q", models.ErrInvalidSubscription, eventType)
+		}
+	}
+
+	return s.storage.CreateSubscriptionTx(ctx, nil, models.Subscription{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+	})
+}
+
+func (s *OutboundWebhookService) ListSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	if err := s.authz.RequireAdmin(ctx, nil); err != nil {
+		return nil, err
+	}
+	return s.storage.ListSubscriptionsTx(ctx, nil)
+}
+
+func (s *OutboundWebhookService) DeleteSubscription(ctx context.Context, id int64) error {
+	if err := s.authz.RequireAdmin(ctx, nil); err != nil {
+		return err
+	}
+	return s.storage.DeleteSubscriptionTx(ctx, nil, id)
+}
+
+func (s *OutboundWebhookService) ListDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) ([]models.OutboundDelivery, error) {
+	if err := s.authz.RequireAdmin(ctx, nil); err != nil {
+		return nil, err
+	}
+	return s.storage.ListDeliveriesTx(ctx, nil, subscriptionID, status, limit)
+}
+
+// checkTarget отклоняет хост, который является или резолвится
+// в непубличный адрес
+func (s *OutboundWebhookService) checkTarget(ctx context.Context, host string) error {
+	if s.cfg.AllowPrivateTargets {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("%w: url must not point to a private or loopback address", models.ErrInvalidSubscription)
+		}
+		return nil
+	}
+
+	addrs, err := s.lookupIP(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: cannot resolve host %q", models.ErrInvalidSubscription, host)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("%w: url must not point to a private or loopback address", models.ErrInvalidSubscription)
+		}
+	}
+	return nil
+}
+
+// webhookTransport - транспорт запросов на адреса, заданные пользователями
+// (подписки, чаты): без allowPrivate соединение с непубличным адресом рвётся
+func webhookTransport(timeout time.Duration, allowPrivate bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		// через прокси проверялся бы адрес прокси, а не получателя
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: timeout, Control: publicOnlyControl}).DialContext
+	}
+	return transport
+}
+
+// publicOnlyControl - проверка уже разрешённого адреса перед соединением
+func publicOnlyControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", errPrivateTarget, host)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// Enqueue записывает доставки события для всех подходящих подписок
+func (s *OutboundWebhookService) Enqueue(ctx context.Context, event models.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.storage.EnqueueDeliveriesTx(ctx, nil, event, payload)
+	return err
+}
+
+// Run крутится до отмены ctx
+func (s *OutboundWebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deliverDue(ctx)
+		}
+	}
+}
+
+func (s *OutboundWebhookService) deliverDue(ctx context.Context) {
+	lease := s.cfg.Timeout + s.cfg.PollInterval
+	deliveries, err := s.storage.ClaimDueDeliveriesTx(ctx, nil, s.cfg.BatchSize, lease)
+	if err != nil {
+		slog.Error("Failed to claim outbound webhook deliveries", "error", err)
+		return
+	}
+
+	for _, d := range deliveries {
+		s.deliver(ctx, d)
+	}
+}
+
+func (s *OutboundWebhookService) deliver(ctx context.Context, d models.OutboundDelivery) {
+	statusCode, err := s.send(ctx, d)
+	if err == nil {
+		if err := s.storage.MarkDeliveredTx(ctx, nil, d.ID, statusCode); err != nil {
+			slog.Error("Failed to mark outbound webhook delivered", "delivery_id", d.ID, "error", err)
+		}
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	attempts := d.Attempts + 1
+	var next *time.Time
+	if attempts < s.cfg.MaxAttempts {
+		at := time.Now().Add(s.backoff(attempts))
+		next = &at
+	} else {
+		slog.Warn("Outbound webhook delivery is dead", "delivery_id", d.ID, "subscription_id", d.SubscriptionID, "error", err)
+	}
+
+	if err := s.storage.MarkFailedTx(ctx, nil, d.ID, code, err.Error(), next); err != nil {
+		slog.Error("Failed to mark outbound webhook failed", "delivery_id", d.ID, "error", err)
+	}
+}
+
+func (s *OutboundWebhookService) send(ctx context.Context, d models.OutboundDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Webhook-Signature-256", "sha256="+signPayload(d.Secret, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *OutboundWebhookService) backoff(attempt int) time.Duration {
+	delay := s.cfg.BaseBackoff
+	for i := 1; i < attempt && delay < s.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.cfg.MaxBackoff {
+		delay = s.cfg.MaxBackoff
+	}
+	return delay
+}
+
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
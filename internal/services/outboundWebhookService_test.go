@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"test-task/internal/models"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSubscriptions struct {
+	delivered map[int64]int
+	failed    map[int64]*time.Time
+}
+
+func (f *fakeSubscriptions) CreateSubscriptionTx(ctx context.Context, tx pgx.Tx, sub models.Subscription) (*models.Subscription, error) {
+	sub.ID = 1
+	return &sub, nil
+}
+
+func (f *fakeSubscriptions) ListSubscriptionsTx(ctx context.Context, tx pgx.Tx) ([]models.Subscription, error) {
+	return nil, nil
+}
+
+func (f *fakeSubscriptions) DeleteSubscriptionTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	return nil
+}
+
+func (f *fakeSubscriptions) EnqueueDeliveriesTx(ctx context.Context, tx pgx.Tx, event models.Event, payload []byte) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeSubscriptions) ClaimDueDeliveriesTx(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]models.OutboundDelivery, error) {
+	return nil, nil
+}
+
+func (f *fakeSubscriptions) MarkDeliveredTx(ctx context.Context, tx pgx.Tx, id int64, statusCode int) error {
+	f.delivered[id] = statusCode
+	return nil
+}
+
+func (f *fakeSubscriptions) MarkFailedTx(ctx context.Context, tx pgx.Tx, id int64, statusCode *int, lastError string, nextAttemptAt *time.Time) error {
+	f.failed[id] = nextAttemptAt
+	return nil
+}
+
+func (f *fakeSubscriptions) ListDeliveriesTx(ctx context.Context, tx pgx.Tx, subscriptionID int64, status string, limit int) ([]models.OutboundDelivery, error) {
+	return nil, nil
+}
+
+func newTestOutbound() (*OutboundWebhookService, *fakeSubscriptions) {
+	store := &fakeSubscriptions{delivered: map[int64]int{}, failed: map[int64]*time.Time{}}
+	svc := NewOutboundWebhookService(store, nil, OutboundWebhookConfig{
+		MaxAttempts:  3,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   3 * time.Second,
+		Timeout:      time.Second,
+		PollInterval: time.Second,
+		// получатели в тестах - httptest на loopback
+		AllowPrivateTargets: true,
+	})
+	return svc, store
+}
+
+func newTestGuardedOutbound(resolved map[string]string) (*OutboundWebhookService, *fakeSubscriptions) {
+	store := &fakeSubscriptions{delivered: map[int64]int{}, failed: map[int64]*time.Time{}}
+	svc := NewOutboundWebhookService(store, nil, OutboundWebhookConfig{
+		MaxAttempts: 3, BaseBackoff: time.Second, MaxBackoff: time.Second, Timeout: time.Second,
+	})
+	svc.lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		ip, ok := resolved[host]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+	}
+	return svc, store
+}
+
+func TestOutboundWebhook_SignedDelivery(t *testing.T) {
+	payload := []byte(`{"id":"e1","type":"pr.merged"}`)
+
+	var gotSig, gotEvent string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get("X-Webhook-Signature-256")
+		gotEvent = r.Header.Get("X-Webhook-Event")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	svc, store := newTestOutbound()
+	svc.deliver(context.Background(), models.OutboundDelivery{
+		ID: 7, URL: srv.URL, Secret: "s3cret", EventType: models.EventPRMerged, Payload: payload,
+	})
+
+	assert.Equal(t, http.StatusAccepted, store.delivered[7])
+	assert.Equal(t, "sha256="+signPayload("s3cret", payload), gotSig)
+	assert.Equal(t, models.EventPRMerged, gotEvent)
+	assert.Equal(t, payload, gotBody)
+}
+
+func TestOutboundWebhook_RetryThenDead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	svc, store := newTestOutbound()
+
+	before := time.Now()
+	svc.deliver(context.Background(), models.OutboundDelivery{ID: 1, URL: srv.URL, Attempts: 0})
+	require.NotNil(t, store.failed[1])
+	assert.WithinDuration(t, before.Add(time.Second), *store.failed[1], 500*time.Millisecond)
+
+	svc.deliver(context.Background(), models.OutboundDelivery{ID: 2, URL: srv.URL, Attempts: 2})
+	require.Contains(t, store.failed, int64(2))
+	assert.Nil(t, store.failed[2], "last attempt must move delivery to DEAD")
+	assert.Empty(t, store.delivered)
+}
+
+func TestOutboundWebhook_Backoff(t *testing.T) {
+	svc, _ := newTestOutbound()
+
+	assert.Equal(t, time.Second, svc.backoff(1))
+	assert.Equal(t, 2*time.Second, svc.backoff(2))
+	assert.Equal(t, 3*time.Second, svc.backoff(3))
+	assert.Equal(t, 3*time.Second, svc.backoff(10))
+}
+
+func TestOutboundWebhook_SubscriptionValidation(t *testing.T) {
+	svc, _ := newTestGuardedOutbound(map[string]string{"hooks.example.com": "93.184.216.34"})
+	ctx := asAdmin()
+
+	_, err := svc.CreateSubscription(ctx, models.CreateSubscriptionRequest{URL: "ftp://x", Secret: "s"})
+	assert.ErrorIs(t, err, models.ErrInvalidSubscription)
+
+	_, err = svc.CreateSubscription(ctx, models.CreateSubscriptionRequest{URL: "https://hooks.example.com", Secret: ""})
+	assert.ErrorIs(t, err, models.ErrInvalidSubscription)
+
+	_, err = svc.CreateSubscription(ctx, models.CreateSubscriptionRequest{URL: "https://hooks.example.com", Secret: "s", Events: []string{"pr.deleted"}})
+	assert.ErrorIs(t, err, models.ErrInvalidSubscription)
+
+	sub, err := svc.CreateSubscription(ctx, models.CreateSubscriptionRequest{
+		URL: "https://hooks.example.com", Secret: "s", Events: []string{models.EventPRMerged},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.EventPRMerged}, sub.Events)
+}
+
+func TestOutboundWebhook_RejectsPrivateTargets(t *testing.T) {
+	svc, _ := newTestGuardedOutbound(map[string]string{
+		"hooks.example.com": "93.184.216.34",
+		"localhost":         "127.0.0.1",
+		"metadata.internal": "169.254.169.254",
+	})
+	ctx := asAdmin()
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://10.1.2.3/hook",
+		"http://[::1]/hook",
+		"http://localhost/hook",
+		"http://metadata.internal/latest",
+		"http://unknown.example.com/hook",
+	} {
+		_, err := svc.CreateSubscription(ctx, models.CreateSubscriptionRequest{URL: target, Secret: "s"})
+		assert.ErrorIs(t, err, models.ErrInvalidSubscription, target)
+	}
+
+	_, err := svc.CreateSubscription(ctx, models.CreateSubscriptionRequest{URL: "https://hooks.example.com/hook", Secret: "s"})
+	assert.NoError(t, err)
+}
+
+func TestOutboundWebhook_DialRefusesPrivateAddress(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	svc, store := newTestGuardedOutbound(nil)
+	svc.deliver(context.Background(), models.OutboundDelivery{ID: 1, URL: srv.URL})
+
+	assert.False(t, called)
+	assert.Contains(t, store.failed, int64(1))
+	assert.Empty(t, store.delivered)
+}
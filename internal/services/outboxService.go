@@ -0,0 +1,28 @@
+package services
+
+/*
+Dead letter outbox: доставки событий, для которых sink так и не принял
+событие за отведённые попытки. Только для админа - в событиях данные PR
+и пользователей.
+*/
+import (
+	"context"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+)
+
+type OutboxService struct {
+	storage storage.OutboxStorage
+	authz   *Authorizer
+}
+
+func NewOutboxService(storage storage.OutboxStorage, authz *Authorizer) *OutboxService {
+	return &OutboxService{storage: storage, authz: authz}
+}
+
+func (s *OutboxService) ListDead(ctx context.Context, limit int) ([]models.OutboxDelivery, error) {
+	if err := s.authz.RequireAdmin(ctx, nil); err != nil {
+		return nil, err
+	}
+	return s.storage.ListOutboxDeadTx(ctx, nil, limit)
+}
@@ -0,0 +1,166 @@
+package services
+
+/*
+Курсорная пагинация списков:
+	1. Проверка сортировки, порядка и лимита запроса
+	2. Курсор - base64url(JSON) с ключом сортировки и id последней строки;
+	   для клиента непрозрачен
+	3. Сборка страницы: из хранилища запрашивается limit+1 строка, лишняя
+	   показывает, что есть следующая страница
+
+В курсоре записаны сортировка и порядок - с другими параметрами он не
+принимается.
+*/
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"test-task/internal/models"
+	"time"
+)
+
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Key   string `json:"k,omitempty"`
+	ID    string `json:"id"`
+}
+
+func encodeCursor(c pageCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(raw string, sort string, order string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", models.ErrInvalidQuery)
+	}
+
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("%w: invalid cursor", models.ErrInvalidQuery)
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort", models.ErrInvalidQuery)
+	}
+	return &c, nil
+}
+
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return models.DefaultPageSize, nil
+	}
+	if limit < 0 || limit > models.MaxPageSize {
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", models.ErrInvalidQuery, models.MaxPageSize)
+	}
+	return limit, nil
+}
+
+func sortOrder(order string, fallback string) (string, error) {
+	switch order {
+	case "":
+		return fallback, nil
+	case models.SortOrderAsc, models.SortOrderDesc:
+		return order, nil
+	default:
+		return "", fmt.Errorf("%w: order must be asc or desc", models.ErrInvalidQuery)
+	}
+}
+
+// preparePRQuery дополняет запрос значениями по умолчанию и разбирает курсор
+func preparePRQuery(q *models.PRListQuery) error {
+	switch q.Sort {
+	case "":
+		q.Sort = models.PRSortCreatedAt
+	case models.PRSortCreatedAt, models.PRSortUpdatedAt:
+	default:
+		return fmt.Errorf("%w: sort must be created_at or updated_at", models.ErrInvalidQuery)
+	}
+
+	var err error
+	if q.Order, err = sortOrder(q.Order, models.SortOrderDesc); err != nil {
+		return err
+	}
+	if q.Limit, err = pageLimit(q.Limit); err != nil {
+		return err
+	}
+
+	switch q.Status {
+	case "", "OPEN", "MERGED", "CLOSED":
+	default:
+		return fmt.Errorf("%w: status must be OPEN, MERGED or CLOSED", models.ErrInvalidQuery)
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, q.Sort, q.Order)
+		if err != nil {
+			return err
+		}
+		at, err := time.Parse(time.RFC3339Nano, c.Key)
+		if err != nil {
+			return fmt.Errorf("%w: invalid cursor", models.ErrInvalidQuery)
+		}
+		q.After = &models.PRCursor{Time: at, ID: c.ID}
+	}
+	return nil
+}
+
+func prPage(prs []models.PullRequestShort, q models.PRListQuery) *models.PRPage {
+	page := &models.PRPage{PullRequests: prs}
+	if len(prs) <= q.Limit {
+		return page
+	}
+
+	page.PullRequests = prs[:q.Limit]
+	last := page.PullRequests[q.Limit-1]
+	key := last.CreatedAt
+	if q.Sort == models.PRSortUpdatedAt {
+		key = last.UpdatedAt
+	}
+	page.NextCursor = encodeCursor(pageCursor{Sort: q.Sort, Order: q.Order, Key: key.Format(time.RFC3339Nano), ID: last.PullRequestID})
+	return page
+}
+
+func prepareUserQuery(q *models.UserListQuery) error {
+	switch q.Sort {
+	case "":
+		q.Sort = models.UserSortUserID
+	case models.UserSortUserID, models.UserSortUsername:
+	default:
+		return fmt.Errorf("%w: sort must be user_id or username", models.ErrInvalidQuery)
+	}
+
+	var err error
+	if q.Order, err = sortOrder(q.Order, models.SortOrderAsc); err != nil {
+		return err
+	}
+	if q.Limit, err = pageLimit(q.Limit); err != nil {
+		return err
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, q.Sort, q.Order)
+		if err != nil {
+			return err
+		}
+		q.After = &models.UserCursor{Key: c.Key, ID: c.ID}
+	}
+	return nil
+}
+
+func userPage(users []models.User, q models.UserListQuery) *models.UserPage {
+	page := &models.UserPage{Users: users}
+	if len(users) <= q.Limit {
+		return page
+	}
+
+	page.Users = users[:q.Limit]
+	last := page.Users[q.Limit-1]
+	c := pageCursor{Sort: q.Sort, Order: q.Order, ID: last.UserID}
+	if q.Sort == models.UserSortUsername {
+		c.Key = last.Username
+	}
+	page.NextCursor = encodeCursor(c)
+	return page
+}
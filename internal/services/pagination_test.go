@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserPages - пользователи, отсортированные по user_id, с keyset как в хранилище
+type fakeUserPages struct {
+	storage.UserStorage
+	users []models.User
+	seen  []models.UserListQuery
+}
+
+func (f *fakeUserPages) ListUsersPageTx(ctx context.Context, tx pgx.Tx, q models.UserListQuery) ([]models.User, error) {
+	f.seen = append(f.seen, q)
+	result := []models.User{}
+	for _, u := range f.users {
+		if q.After != nil && u.UserID <= q.After.ID {
+			continue
+		}
+		if q.IsActive != nil && u.IsActive != *q.IsActive {
+			continue
+		}
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result[:min(q.Limit, len(result))], nil
+}
+
+func TestListUsersPaging(t *testing.T) {
+	users := &fakeUserPages{}
+	for _, id := range []string{"u1", "u2", "u3", "u4", "u5"} {
+		users.users = append(users.users, models.User{UserID: id, IsActive: id != "u3"})
+	}
+	svc := NewUserService(users, &fakeOutbox{}, &fakeAudit{}, NewAuthorizer(users), nil, nil)
+	ctx := context.Background()
+	active := true
+
+	page, err := svc.ListUsers(ctx, models.UserListQuery{IsActive: &active, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, users.seen[0].Limit, "one extra row tells whether there is a next page")
+	assert.Equal(t, []string{"u1", "u2"}, userIDs(page.Users))
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = svc.ListUsers(ctx, models.UserListQuery{IsActive: &active, Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u4", "u5"}, userIDs(page.Users))
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestListUsersRejectsInvalidQuery(t *testing.T) {
+	users := &fakeUserPages{}
+	svc := NewUserService(users, &fakeOutbox{}, &fakeAudit{}, NewAuthorizer(users), nil, nil)
+	ctx := context.Background()
+
+	usernameCursor := encodeCursor(pageCursor{Sort: models.UserSortUsername, Order: models.SortOrderAsc, Key: "bob", ID: "u2"})
+
+	for _, q := range []models.UserListQuery{
+		{Sort: "email"},
+		{Order: "up"},
+		{Limit: models.MaxPageSize + 1},
+		{Cursor: "not a cursor"},
+		{Cursor: usernameCursor},
+	} {
+		_, err := svc.ListUsers(ctx, q)
+		assert.ErrorIs(t, err, models.ErrInvalidQuery, "%+v", q)
+	}
+	assert.Empty(t, users.seen)
+}
+
+func TestPRCursorRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	q := models.PRListQuery{Limit: 1}
+	require.NoError(t, preparePRQuery(&q))
+	assert.Equal(t, models.PRSortCreatedAt, q.Sort)
+	assert.Equal(t, models.SortOrderDesc, q.Order)
+
+	page := prPage([]models.PullRequestShort{
+		{PullRequestID: "pr-2", CreatedAt: &created},
+		{PullRequestID: "pr-1", CreatedAt: &created},
+	}, q)
+	require.Len(t, page.PullRequests, 1)
+
+	next := models.PRListQuery{Limit: 1, Cursor: page.NextCursor}
+	require.NoError(t, preparePRQuery(&next))
+	require.NotNil(t, next.After)
+	assert.Equal(t, "pr-2", next.After.ID)
+	assert.True(t, created.Equal(next.After.Time), "cursor keeps sub-second precision")
+
+	other := models.PRListQuery{Sort: models.PRSortUpdatedAt, Cursor: page.NextCursor}
+	assert.ErrorIs(t, preparePRQuery(&other), models.ErrInvalidQuery)
+
+	bad := models.PRListQuery{Status: "DRAFT"}
+	assert.ErrorIs(t, preparePRQuery(&bad), models.ErrInvalidQuery)
+}
+
+func userIDs(users []models.User) []string {
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.UserID
+	}
+	return ids
+}
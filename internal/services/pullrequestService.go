@@ -5,6 +5,15 @@ package services
 	2. Merge
 	3. Переназначение пользоватля
 	4. По пользователю найти Ревью
+	5. Хронология PR
+	6. Решение ревьюера (approve / request changes)
+
+Отказы переназначения без кандидата (NO_CANDIDATE) считаются в метрике
+pr_reassign_no_candidate_total.
+
+Каждое изменение PR пишется в журнал аудита и хронологию в своей же транзакции.
+Изменяющие методы - обёртки над одноимёнными со строчной буквы: при конфликте
+serializable-транзакции операция повторяется целиком (retryOnConflict).
 
 Основная сложность в написании сервиса была связана с возможным рейс кондишн.
 Было исправлено за счет транзакций 
@@ -12,31 +21,63 @@ package services
 import (
 	"context"
 	"strings"
+	"test-task/internal/metrics"
 	"test-task/internal/models"
 	"test-task/internal/storage"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type PullRequestService struct {
-	PullRequestServ storage.PullReqStorage
-	userStorage     storage.UserStorage
-	teamStorage     storage.TeamStorage
+	PullRequestServ   storage.PullReqStorage
+	userStorage       storage.UserStorage
+	teamStorage       storage.TeamStorage
+	codeOwnersStorage storage.CodeOwnersStorage
+	slaStorage        storage.SLAStorage
+	outbox            storage.OutboxStorage
+	audit             storage.AuditStorage
+	timeline          storage.TimelineStorage
+	authz             *Authorizer
 }
 
 func NewPullRequestService(
 	PullRequestServ storage.PullReqStorage,
 	userStorage storage.UserStorage,
 	teamStorage storage.TeamStorage,
+	codeOwnersStorage storage.CodeOwnersStorage,
+	slaStorage storage.SLAStorage,
+	outbox storage.OutboxStorage,
+	audit storage.AuditStorage,
+	timeline storage.TimelineStorage,
+	authz *Authorizer,
 ) *PullRequestService {
 	return &PullRequestService{
-		PullRequestServ: PullRequestServ,
-		userStorage:     userStorage,
-		teamStorage:     teamStorage,
+		PullRequestServ:   PullRequestServ,
+		userStorage:       userStorage,
+		teamStorage:       teamStorage,
+		codeOwnersStorage: codeOwnersStorage,
+		slaStorage:        slaStorage,
+		outbox:            outbox,
+		audit:             audit,
+		timeline:          timeline,
+		authz:             authz,
 	}
 }
 
-func (s *PullRequestService) CreatePR(ctx context.Context, req models.CreatePRRequest) (*models.PullRequest, error) {
+func (s *PullRequestService) CreatePR(ctx context.Context, req models.CreatePRRequest) (pr *models.PullRequest, err error) {
+	err = retryOnConflict(ctx, "CreatePR", func() error {
+		pr, err = s.createPR(ctx, req)
+		return err
+	})
+	return pr, err
+}
+
+func (s *PullRequestService) createPR(ctx context.Context, req models.CreatePRRequest) (_ *models.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.CreatePR", attribute.String("pr.id", req.PullRequestID), attribute.String("pr.author_id", req.AuthorID))
+	defer func() { endSpan(span, err) }()
+
 	tx, err := s.PullRequestServ.PRBeginTx(ctx)
 	if err != nil {
 		return nil, err
@@ -47,24 +88,69 @@ func (s *PullRequestService) CreatePR(ctx context.Context, req models.CreatePRRe
 	if err != nil {
 		return nil, models.ErrNotFound
 	}
+	span.SetAttributes(attribute.String("team.name", author.TeamName))
 
 	team, err := s.teamStorage.GetTeamInfoTx(ctx, tx, author.TeamName)
 	if err != nil {
 		return nil, models.ErrNotFound
 	}
 
-	reviewers := s.findReviewersFromTeam(team, req.AuthorID)
+	owners, err := s.findCodeOwners(ctx, tx, author, req.ChangedFiles)
+	if err != nil {
+		return nil, err
+	}
 
-	pr := models.PullRequest{
-		PullRequestID:     req.PullRequestID,
-		PullRequestName:   req.PullRequestName,
-		AuthorID:          req.AuthorID,
-		Status:            "OPEN",
-		AssignedReviewers: reviewers,
+	var reviewers []string
+	for _, owner := range owners {
+		reviewers = append(reviewers, owner.UserID)
 	}
+	reviewers = append(reviewers, s.findReviewersFromTeam(team, reviewers, req)...)
 
-	err = s.PullRequestServ.CreatePRTx(ctx, tx, pr)
+	slaDueAt, err := s.slaDueAt(ctx, tx, author.TeamName, reviewers)
 	if err != nil {
+		return nil, err
+	}
+
+	pr := models.PullRequest{
+		PullRequestID:      req.PullRequestID,
+		PullRequestName:    req.PullRequestName,
+		AuthorID:           req.AuthorID,
+		Status:             "OPEN",
+		AssignedReviewers:  reviewers,
+		Project:            req.Project,
+		Tags:               req.Tags,
+		ChangedFiles:       req.ChangedFiles,
+		CodeOwnerReviewers: owners,
+		SLADueAt:           slaDueAt,
+	}
+
+	// PR, событие, аудит и хронология уходят в базу одним пакетом
+	wctx := storage.WithWriteBatch(ctx)
+	if err := s.PullRequestServ.CreatePRTx(wctx, tx, pr); err != nil {
+		return nil, err
+	}
+
+	if len(reviewers) > 0 {
+		err = recordEvent(wctx, tx, s.outbox, models.EventReviewerAssigned, models.ReviewEvent{
+			PullRequest: &pr,
+			TeamName:    author.TeamName,
+			Reviewers:   reviewers,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAudit(wctx, tx, s.audit, models.AuditEntityPR, pr.PullRequestID, "created", nil, pr); err != nil {
+		return nil, err
+	}
+
+	events := append([]models.TimelineEvent{{Event: models.TimelineCreated}}, reviewerChanges(nil, reviewers, models.TimelineReasonInitial)...)
+	if err := recordTimeline(wctx, tx, s.timeline, pr.PullRequestID, events...); err != nil {
+		return nil, err
+	}
+
+	if err := storage.FlushWrites(wctx, tx); err != nil {
 		if isUniqueConstraintError(err) {
 			return nil, models.ErrPRExists
 		}
@@ -78,32 +164,94 @@ func (s *PullRequestService) CreatePR(ctx context.Context, req models.CreatePRRe
 	return &pr, nil
 }
 
-func (s *PullRequestService) findReviewersFromTeam(team *models.Team, authorID string) []string {
-	var reviewers []string
+// findReviewersFromTeam добирает ревьюеров до двух, не трогая уже выбранных
+func (s *PullRequestService) findReviewersFromTeam(team *models.Team, chosen []string, req models.CreatePRRequest) []string {
+	limit := 2 - len(chosen)
+	if limit <= 0 {
+		return nil
+	}
+
+	var candidates []models.User
 	for _, member := range team.Members {
-		if member.UserID == authorID || !member.IsActive {
+		if member.UserID == req.AuthorID || !member.IsActive || contains(chosen, member.UserID) {
+			continue
+		}
+		candidates = append(candidates, member)
+	}
+	return pickReviewers(candidates, req.Tags, req.ChangedFiles, limit)
+}
+
+// findCodeOwners - обязательные ревьюеры по CODEOWNERS команды автора.
+// Неизвестные, неактивные владельцы и сам автор пропускаются.
+func (s *PullRequestService) findCodeOwners(ctx context.Context, tx pgx.Tx, author *models.User, changedFiles []string) ([]models.CodeOwnerReviewer, error) {
+	if len(changedFiles) == 0 {
+		return nil, nil
+	}
+
+	co, err := s.codeOwnersStorage.GetCodeOwnersTx(ctx, tx, author.TeamName)
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var owners []models.CodeOwnerReviewer
+	for _, owner := range codeOwnersFor(co.Rules, changedFiles) {
+		if owner.UserID == author.UserID {
 			continue
 		}
-		reviewers = append(reviewers, member.UserID)
-		if len(reviewers) >= 2 {
-			break
+		user, err := s.userStorage.GetUserTx(ctx, tx, owner.UserID)
+		if err != nil {
+			if err == models.ErrNotFound {
+				continue
+			}
+			return nil, err
 		}
+		if !user.IsActive {
+			continue
+		}
+		owners = append(owners, owner)
 	}
-	return reviewers
+
+	return owners, nil
+}
+
+func (s *PullRequestService) MergePR(ctx context.Context, prID string) (pr *models.PullRequest, err error) {
+	err = retryOnConflict(ctx, "MergePR", func() error {
+		pr, err = s.mergePR(ctx, prID)
+		return err
+	})
+	return pr, err
 }
 
-func (s *PullRequestService) MergePR(ctx context.Context, prID string) (*models.PullRequest, error) {
+func (s *PullRequestService) mergePR(ctx context.Context, prID string) (_ *models.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.MergePR", attribute.String("pr.id", prID))
+	defer func() { endSpan(span, err) }()
+
 	tx, err := s.PullRequestServ.PRBeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = s.PullRequestServ.GetPRByIDTx(ctx, tx, prID)
+	before, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, prID)
 	if err != nil {
 		return nil, models.ErrNotFound
 	}
 
+	var teamName string
+	if author, err := s.userStorage.GetUserTx(ctx, tx, before.AuthorID); err == nil {
+		teamName = author.TeamName
+	}
+	if err := s.authz.CanMerge(ctx, tx, before, teamName); err != nil {
+		return nil, err
+	}
+
+	if before.Status == "CLOSED" {
+		return nil, models.ErrPRClosed
+	}
+
 	err = s.PullRequestServ.MergePRTx(ctx, tx, prID)
 	if err != nil {
 		return nil, err
@@ -114,6 +262,24 @@ func (s *PullRequestService) MergePR(ctx context.Context, prID string) (*models.
 		return nil, err
 	}
 
+	if before.Status != "MERGED" {
+		err = recordEvent(ctx, tx, s.outbox, models.EventPRMerged, models.ReviewEvent{
+			PullRequest: pr,
+			TeamName:    teamName,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := recordAudit(ctx, tx, s.audit, models.AuditEntityPR, prID, "merged", before, pr); err != nil {
+			return nil, err
+		}
+
+		if err := recordTimeline(ctx, tx, s.timeline, prID, models.TimelineEvent{Event: models.TimelineMerged}); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
@@ -121,7 +287,257 @@ func (s *PullRequestService) MergePR(ctx context.Context, prID string) (*models.
 	return pr, nil
 }
 
-func (s *PullRequestService) ReassignReviewer(ctx context.Context, req models.ReassignRequest) (*models.PullRequest, string, error) {
+func (s *PullRequestService) UpdatePR(ctx context.Context, req models.UpdatePRRequest) (pr *models.PullRequest, err error) {
+	err = retryOnConflict(ctx, "UpdatePR", func() error {
+		pr, err = s.updatePR(ctx, req)
+		return err
+	})
+	return pr, err
+}
+
+// updatePR синхронизирует название и проект PR. Ревьюеры не меняются.
+func (s *PullRequestService) updatePR(ctx context.Context, req models.UpdatePRRequest) (_ *models.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.UpdatePR", attribute.String("pr.id", req.PullRequestID))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.PullRequestServ.PRBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, req.PullRequestID)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+
+	var teamName string
+	if author, err := s.userStorage.GetUserTx(ctx, tx, before.AuthorID); err == nil {
+		teamName = author.TeamName
+	}
+	if err := s.authz.CanMerge(ctx, tx, before, teamName); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.PullRequestServ.UpdatePRDetailsTx(ctx, tx, req.PullRequestID, req.PullRequestName, req.Project)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return before, nil
+	}
+
+	pr, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, req.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityPR, req.PullRequestID, "updated", before, pr); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+func (s *PullRequestService) ClosePR(ctx context.Context, prID string) (pr *models.PullRequest, err error) {
+	err = retryOnConflict(ctx, "ClosePR", func() error {
+		pr, err = s.closePR(ctx, prID)
+		return err
+	})
+	return pr, err
+}
+
+// closePR закрывает PR без merge и освобождает ревьюеров.
+// Повторное закрытие ничего не меняет.
+func (s *PullRequestService) closePR(ctx context.Context, prID string) (_ *models.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.ClosePR", attribute.String("pr.id", prID))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.PullRequestServ.PRBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, prID)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+
+	var teamName string
+	if author, err := s.userStorage.GetUserTx(ctx, tx, before.AuthorID); err == nil {
+		teamName = author.TeamName
+	}
+	if err := s.authz.CanMerge(ctx, tx, before, teamName); err != nil {
+		return nil, err
+	}
+
+	switch before.Status {
+	case "MERGED":
+		return nil, models.ErrPRMerged
+	case "CLOSED":
+		return before, nil
+	}
+
+	ok, err := s.PullRequestServ.ClosePRTx(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return pr, nil
+	}
+
+	err = recordEvent(ctx, tx, s.outbox, models.EventPRClosed, models.ReviewEvent{
+		PullRequest: pr,
+		TeamName:    teamName,
+		Reviewers:   before.AssignedReviewers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityPR, prID, models.TimelineClosed, before, pr); err != nil {
+		return nil, err
+	}
+
+	events := append(reviewerChanges(before.AssignedReviewers, nil, models.TimelineReasonClosed), models.TimelineEvent{Event: models.TimelineClosed})
+	if err := recordTimeline(ctx, tx, s.timeline, prID, events...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+func (s *PullRequestService) ReopenPR(ctx context.Context, prID string) (pr *models.PullRequest, err error) {
+	err = retryOnConflict(ctx, "ReopenPR", func() error {
+		pr, err = s.reopenPR(ctx, prID)
+		return err
+	})
+	return pr, err
+}
+
+// reopenPR возвращает закрытый PR в OPEN. Ревьюеры при закрытии были
+// освобождены, поэтому подбираются заново, как при создании.
+func (s *PullRequestService) reopenPR(ctx context.Context, prID string) (_ *models.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.ReopenPR", attribute.String("pr.id", prID))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.PullRequestServ.PRBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, prID)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+
+	author, err := s.userStorage.GetUserTx(ctx, tx, before.AuthorID)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+	span.SetAttributes(attribute.String("team.name", author.TeamName))
+
+	if err := s.authz.CanMerge(ctx, tx, before, author.TeamName); err != nil {
+		return nil, err
+	}
+
+	switch before.Status {
+	case "MERGED":
+		return nil, models.ErrPRMerged
+	case "OPEN":
+		return before, nil
+	}
+
+	team, err := s.teamStorage.GetTeamInfoTx(ctx, tx, author.TeamName)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+
+	owners, err := s.findCodeOwners(ctx, tx, author, before.ChangedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var reviewers []string
+	for _, owner := range owners {
+		reviewers = append(reviewers, owner.UserID)
+	}
+	reviewers = append(reviewers, s.findReviewersFromTeam(team, reviewers, models.CreatePRRequest{
+		AuthorID:     before.AuthorID,
+		Tags:         before.Tags,
+		ChangedFiles: before.ChangedFiles,
+	})...)
+
+	slaDueAt, err := s.slaDueAt(ctx, tx, author.TeamName, reviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.PullRequestServ.ReopenPRTx(ctx, tx, prID, reviewers, slaDueAt)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return pr, nil
+	}
+
+	err = recordEvent(ctx, tx, s.outbox, models.EventPRReopened, models.ReviewEvent{
+		PullRequest: pr,
+		TeamName:    author.TeamName,
+		Reviewers:   reviewers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityPR, prID, models.TimelineReopened, before, pr); err != nil {
+		return nil, err
+	}
+
+	events := append([]models.TimelineEvent{{Event: models.TimelineReopened}}, reviewerChanges(nil, reviewers, models.TimelineReasonReopened)...)
+	if err := recordTimeline(ctx, tx, s.timeline, prID, events...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+func (s *PullRequestService) ReassignReviewer(ctx context.Context, req models.ReassignRequest) (pr *models.PullRequest, newReviewer string, err error) {
+	err = retryOnConflict(ctx, "ReassignReviewer", func() error {
+		pr, newReviewer, err = s.reassignReviewer(ctx, req)
+		return err
+	})
+	return pr, newReviewer, err
+}
+
+func (s *PullRequestService) reassignReviewer(ctx context.Context, req models.ReassignRequest) (_ *models.PullRequest, _ string, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.ReassignReviewer", attribute.String("pr.id", req.PullRequestID), attribute.String("reviewer.old_user_id", req.OldUserID))
+	defer func() { endSpan(span, err) }()
+
 	tx, err := s.PullRequestServ.PRBeginTx(ctx)
 	if err != nil {
 		return nil, "", err
@@ -133,23 +549,33 @@ func (s *PullRequestService) ReassignReviewer(ctx context.Context, req models.Re
 		return nil, "", models.ErrNotFound
 	}
 
+	author, err := s.userStorage.GetUserTx(ctx, tx, pr.AuthorID)
+	if err != nil {
+		return nil, "", models.ErrNotFound
+	}
+	span.SetAttributes(attribute.String("team.name", author.TeamName))
+
+	if err := s.authz.CanReassign(ctx, tx, pr, author.TeamName); err != nil {
+		return nil, "", err
+	}
+
 	if pr.Status == "MERGED" {
 		return nil, "", models.ErrPRMerged
 	}
+	if pr.Status == "CLOSED" {
+		return nil, "", models.ErrPRClosed
+	}
 
 	if !contains(pr.AssignedReviewers, req.OldUserID) {
 		return nil, "", models.ErrNotAssigned
 	}
 
-	author, err := s.userStorage.GetUserTx(ctx, tx, pr.AuthorID)
-	if err != nil {
-		return nil, "", models.ErrNotFound
-	}
-
-	newReviewer, err := s.findReplacementReviewer(ctx, tx, author.TeamName, pr.AssignedReviewers, req.OldUserID, pr.AuthorID)
+	newReviewer, err := s.findReplacementReviewer(ctx, tx, author.TeamName, pr, req.OldUserID)
 	if err != nil {
+		metrics.NoCandidate.Inc()
 		return nil, "", models.ErrNoCandidate
 	}
+	span.SetAttributes(attribute.String("reviewer.new_user_id", newReviewer))
 
 	newReviewers := replaceInSlice(pr.AssignedReviewers, req.OldUserID, newReviewer)
 	err = s.PullRequestServ.UpdatePRReviewersTx(ctx, tx, req.PullRequestID, newReviewers)
@@ -157,11 +583,43 @@ func (s *PullRequestService) ReassignReviewer(ctx context.Context, req models.Re
 		return nil, "", err
 	}
 
+	// у нового ревьюера своё окно SLA
+	slaDueAt, err := s.slaDueAt(ctx, tx, author.TeamName, newReviewers)
+	if err != nil {
+		return nil, "", err
+	}
+	err = s.PullRequestServ.UpdatePRSLATx(ctx, tx, req.PullRequestID, slaDueAt)
+	if err != nil {
+		return nil, "", err
+	}
+
 	updatedPR, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, req.PullRequestID)
 	if err != nil {
 		return nil, "", err
 	}
 
+	err = recordEvent(ctx, tx, s.outbox, models.EventReviewerReassigned, models.ReviewEvent{
+		PullRequest: updatedPR,
+		TeamName:    author.TeamName,
+		OldReviewer: req.OldUserID,
+		NewReviewer: newReviewer,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityPR, req.PullRequestID, "reviewer_reassigned", pr, updatedPR); err != nil {
+		return nil, "", err
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = models.TimelineReasonReassign
+	}
+	if err := recordTimeline(ctx, tx, s.timeline, req.PullRequestID, reviewerChanges(pr.AssignedReviewers, newReviewers, reason)...); err != nil {
+		return nil, "", err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, "", err
 	}
@@ -169,20 +627,56 @@ func (s *PullRequestService) ReassignReviewer(ctx context.Context, req models.Re
 	return updatedPR, newReviewer, nil
 }
 
-func (s *PullRequestService) GetUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
+func (s *PullRequestService) SubmitReview(ctx context.Context, req models.ReviewDecisionRequest) (pr *models.PullRequest, err error) {
+	err = retryOnConflict(ctx, "SubmitReview", func() error {
+		pr, err = s.submitReview(ctx, req)
+		return err
+	})
+	return pr, err
+}
+
+// submitReview записывает решение назначенного ревьюера в хронологию PR.
+// Решать может сам ревьюер, лид его команды или админ.
+func (s *PullRequestService) submitReview(ctx context.Context, req models.ReviewDecisionRequest) (_ *models.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.SubmitReview", attribute.String("pr.id", req.PullRequestID), attribute.String("reviewer.user_id", req.ReviewerID), attribute.String("review.decision", req.Decision))
+	defer func() { endSpan(span, err) }()
+
+	var event string
+	switch req.Decision {
+	case models.ReviewDecisionApprove:
+		event = models.TimelineApproved
+	case models.ReviewDecisionRequestChanges:
+		event = models.TimelineChangesRequested
+	default:
+		return nil, models.ErrInvalidReviewDecision
+	}
+
 	tx, err := s.PullRequestServ.PRBeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = s.userStorage.GetUserTx(ctx, tx, userID)
+	pr, err := s.PullRequestServ.GetPRByIDTx(ctx, tx, req.PullRequestID)
 	if err != nil {
 		return nil, models.ErrNotFound
 	}
 
-	prs, err := s.PullRequestServ.GetPRsByReviewerTx(ctx, tx, userID)
-	if err != nil {
+	if err := s.authz.RequireUserAccess(ctx, tx, req.ReviewerID); err != nil {
+		return nil, err
+	}
+
+	if pr.Status == "MERGED" {
+		return nil, models.ErrPRMerged
+	}
+	if pr.Status == "CLOSED" {
+		return nil, models.ErrPRClosed
+	}
+	if !contains(pr.AssignedReviewers, req.ReviewerID) {
+		return nil, models.ErrNotAssigned
+	}
+
+	if err := recordTimeline(ctx, tx, s.timeline, req.PullRequestID, models.TimelineEvent{Event: event, UserID: req.ReviewerID}); err != nil {
 		return nil, err
 	}
 
@@ -190,26 +684,107 @@ func (s *PullRequestService) GetUserReviews(ctx context.Context, userID string)
 		return nil, err
 	}
 
-	return prs, nil
+	return pr, nil
 }
 
-func (s *PullRequestService) findReplacementReviewer(ctx context.Context, tx pgx.Tx, teamName string, currentReviewers []string, oldUserID string, authorID string) (string, error) {
+func (s *PullRequestService) GetUserReviews(ctx context.Context, userID string, q models.PRListQuery) (_ *models.PRPage, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.GetUserReviews", attribute.String("user.id", userID))
+	defer func() { endSpan(span, err) }()
+
+	_, err = s.userStorage.GetUserTx(ctx, nil, userID)
+	if err != nil {
+		return nil, models.ErrNotFound
+	}
+
+	q.ReviewerID = userID
+	return s.ListPRs(ctx, q)
+}
+
+// ListPRs - страница PR по фильтрам; запрашивается limit+1 строка, чтобы
+// понять, нужен ли курсор следующей страницы
+func (s *PullRequestService) ListPRs(ctx context.Context, q models.PRListQuery) (_ *models.PRPage, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.ListPRs", attribute.String("pr.status", q.Status), attribute.String("pr.author_id", q.AuthorID))
+	defer func() { endSpan(span, err) }()
+
+	if err := preparePRQuery(&q); err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	q.Limit++
+	prs, err := s.PullRequestServ.ListPRsPageTx(ctx, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	q.Limit = limit
+
+	return prPage(prs, q), nil
+}
+
+func (s *PullRequestService) GetHistory(ctx context.Context, prID string) (_ []models.TimelineEvent, err error) {
+	ctx, span := startSpan(ctx, "PullRequestService.GetHistory", attribute.String("pr.id", prID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.PullRequestServ.GetPRByIDTx(ctx, nil, prID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.timeline.ListTimelineTx(ctx, nil, prID)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []models.TimelineEvent{}
+	}
+
+	return events, nil
+}
+
+// slaDueAt - срок первого ревью от текущего момента по SLA команды.
+// nil, если у команды нет SLA или ревьюеров некому назначить.
+func (s *PullRequestService) slaDueAt(ctx context.Context, tx pgx.Tx, teamName string, reviewers []string) (*time.Time, error) {
+	if len(reviewers) == 0 {
+		return nil, nil
+	}
+
+	sla, err := s.slaStorage.GetTeamSLATx(ctx, tx, teamName)
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	due, err := slaDeadline(*sla, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &due, nil
+}
+
+// findReplacementReviewer при прочих равных предпочитает эксперта по тегам/файлам PR
+func (s *PullRequestService) findReplacementReviewer(ctx context.Context, tx pgx.Tx, teamName string, pr *models.PullRequest, oldUserID string) (string, error) {
 	team, err := s.teamStorage.GetTeamInfoTx(ctx, tx, teamName)
 	if err != nil {
 		return "", err
 	}
 
+	var candidates []models.User
 	for _, member := range team.Members {
-		if member.UserID == authorID ||
+		if member.UserID == pr.AuthorID ||
 			!member.IsActive ||
-			contains(currentReviewers, member.UserID) ||
+			contains(pr.AssignedReviewers, member.UserID) ||
 			member.UserID == oldUserID {
 			continue
 		}
-		return member.UserID, nil
+		candidates = append(candidates, member)
 	}
 
-	return "", models.ErrNoCandidate
+	picked := pickReviewers(candidates, pr.Tags, pr.ChangedFiles, 1)
+	if len(picked) == 0 {
+		return "", models.ErrNoCandidate
+	}
+	return picked[0], nil
 }
 
 func contains(slice []string, item string) bool {
@@ -0,0 +1,92 @@
+package services
+
+/*
+Ограничение частоты запросов (token bucket):
+	1. Отдельная корзина на каждый ключ (токен клиента или IP)
+	2. Корзина пополняется со скоростью Rate токенов в секунду до Burst
+	3. Allow списывает токен или говорит, через сколько он появится
+
+Корзины живут в памяти процесса - при нескольких экземплярах лимит
+действует на каждый отдельно. Полные корзины, к которым давно не
+обращались, периодически удаляются, чтобы карта не росла от разовых IP.
+*/
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type RateLimitConfig struct {
+	Rate  float64 // токенов в секунду, <= 0 - без ограничения
+	Burst int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type RateLimiter struct {
+	mu        sync.Mutex
+	cfg       RateLimitConfig
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	return &RateLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow списывает токен из корзины key; если токена нет - возвращает,
+// сколько ждать до следующего
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.cfg.Rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.cfg.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.cfg.Burst), b.tokens+now.Sub(b.last).Seconds()*l.cfg.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.cfg.Rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep удаляет корзины, которые успели наполниться - они неотличимы от новых
+func (l *RateLimiter) sweep(now time.Time) {
+	refill := time.Duration(float64(l.cfg.Burst) / l.cfg.Rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < refill || now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
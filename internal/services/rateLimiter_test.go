@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 2, Burst: 3})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.Allow("token:1")
+		assert.True(t, ok, "burst request %d", i)
+	}
+
+	ok, wait := limiter.Allow("token:1")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	ok, _ = limiter.Allow("token:2")
+	assert.True(t, ok, "buckets are per key")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.Allow("token:1")
+	assert.True(t, ok, "one token refilled")
+	ok, _ = limiter.Allow("token:1")
+	assert.False(t, ok)
+
+	now = now.Add(time.Hour)
+	limiter.Allow("token:1")
+	assert.Len(t, limiter.buckets, 1, "idle full buckets are swept")
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{})
+	for i := 0; i < 100; i++ {
+		ok, _ := limiter.Allow("ip:10.0.0.1")
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+/*
+Повтор операций при конфликте serializable-транзакций. Конфликт
+(serialization failure, deadlock) - нормальный исход при параллельной
+записи: транзакция откатывается целиком, и операцию можно выполнить
+заново. Внутри общей транзакции (atomic-пакет) повторять нечего - после
+конфликта она уже прервана, решает её владелец.
+*/
+import (
+	"context"
+	"test-task/internal/metrics"
+	"test-task/internal/storage"
+	"time"
+)
+
+const (
+	conflictAttempts = 3
+	conflictBackoff  = 20 * time.Millisecond
+)
+
+// retryOnConflict выполняет op и повторяет её, пока она падает на конфликте
+func retryOnConflict(ctx context.Context, operation string, op func() error) error {
+	err := op()
+	if storage.InTx(ctx) {
+		return err
+	}
+
+	for attempt := 1; attempt < conflictAttempts && storage.IsConflict(err); attempt++ {
+		metrics.DBTransactionRetries.WithLabelValues(operation).Inc()
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * conflictBackoff):
+		}
+		err = op()
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryOnConflict(t *testing.T) {
+	conflict := &pgconn.PgError{Code: "40001"}
+
+	calls := 0
+	err := retryOnConflict(context.Background(), "test", func() error {
+		calls++
+		if calls < 2 {
+			return conflict
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	err = retryOnConflict(context.Background(), "test", func() error {
+		calls++
+		return conflict
+	})
+	assert.ErrorIs(t, err, conflict)
+	assert.Equal(t, conflictAttempts, calls)
+
+	// другие ошибки не повторяются
+	calls = 0
+	err = retryOnConflict(context.Background(), "test", func() error {
+		calls++
+		return errors.New("boom")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	// общую транзакцию пакета не повторить
+	calls = 0
+	err = retryOnConflict(storage.WithTx(context.Background(), &fakeTx{}), "test", func() error {
+		calls++
+		return conflict
+	})
+	assert.ErrorIs(t, err, conflict)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,673 @@
+package services
+
+/*
+SCIM 2.0 провижининг из IdP (Okta, Azure AD и т.п.):
+	1. Users - пользователи: userName = user_id, displayName = username,
+	   первичный email, active
+	2. Groups - команды: displayName = имя команды, members - участники
+
+Пользователь состоит ровно в одной команде, поэтому новые пользователи и
+исключённые из группы попадают в команду по умолчанию (defaultTeam), а
+удаление группы переносит туда её участников. Переименование пользователей
+и групп не поддерживается.
+
+active=false идёт через UserManager.SetUserActive - с той же проверкой прав,
+событием, аудитом и переназначением открытых ревью. DELETE пользователя -
+это деактивация: на него ссылаются PR и аудит.
+
+Фильтры: userName eq "..." для Users и displayName eq "..." для Groups.
+*/
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SCIMService struct {
+	scimStorage storage.SCIMStorage
+	userStorage storage.UserStorage
+	users       UserManager
+	audit       storage.AuditStorage
+	authz       *Authorizer
+	defaultTeam string
+}
+
+func NewSCIMService(
+	scimStorage storage.SCIMStorage,
+	userStorage storage.UserStorage,
+	users UserManager,
+	audit storage.AuditStorage,
+	authz *Authorizer,
+	defaultTeam string,
+) *SCIMService {
+	return &SCIMService{
+		scimStorage: scimStorage,
+		userStorage: userStorage,
+		users:       users,
+		audit:       audit,
+		authz:       authz,
+		defaultTeam: defaultTeam,
+	}
+}
+
+// scimUserPatch - изменения пользователя после разбора PUT или PATCH; nil - не менять
+type scimUserPatch struct {
+	displayName *string
+	email       *string
+	active      *bool
+}
+
+func (s *SCIMService) ListUsers(ctx context.Context, filter string, startIndex int, count int) (*models.SCIMListResponse, error) {
+	userName, err := parseSCIMFilter(filter, "userName")
+	if err != nil {
+		return nil, err
+	}
+	startIndex, count = scimPage(startIndex, count)
+
+	users, total, err := s.scimStorage.ListUsersTx(ctx, nil, userName, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]models.SCIMUser, 0, len(users))
+	for _, u := range users {
+		resources = append(resources, toSCIMUser(u))
+	}
+	return scimList(resources, total, startIndex, len(resources)), nil
+}
+
+func (s *SCIMService) GetUser(ctx context.Context, id string) (*models.SCIMUser, error) {
+	user, err := s.userStorage.GetUserTx(ctx, nil, id)
+	if err != nil {
+		return nil, err
+	}
+	res := toSCIMUser(*user)
+	return &res, nil
+}
+
+func (s *SCIMService) CreateUser(ctx context.Context, req models.SCIMUser) (*models.SCIMUser, error) {
+	if req.UserName == "" {
+		return nil, fmt.Errorf("%w: userName is required", models.ErrInvalidSCIM)
+	}
+
+	user := models.User{
+		UserID:   req.UserName,
+		Username: req.DisplayName,
+		TeamName: s.defaultTeam,
+		IsActive: req.Active == nil || *req.Active,
+		Email:    primaryEmail(req.Emails),
+	}
+	if user.Username == "" {
+		user.Username = req.UserName
+	}
+
+	tx, err := s.userStorage.UserBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.authz.RequireAdmin(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := s.scimStorage.EnsureTeamTx(ctx, tx, s.defaultTeam); err != nil {
+		return nil, err
+	}
+	if err := s.scimStorage.CreateUserTx(ctx, tx, user); err != nil {
+		return nil, err
+	}
+
+	created, err := s.userStorage.GetUserTx(ctx, tx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityUser, user.UserID, "created", nil, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	res := toSCIMUser(*created)
+	return &res, nil
+}
+
+// ReplaceUser - PUT: displayName, emails и active берутся из запроса целиком
+func (s *SCIMService) ReplaceUser(ctx context.Context, id string, req models.SCIMUser) (*models.SCIMUser, error) {
+	if req.UserName != "" && req.UserName != id {
+		return nil, fmt.Errorf("%w: userName is immutable", models.ErrInvalidSCIM)
+	}
+
+	displayName := req.DisplayName
+	if displayName == "" {
+		displayName = id
+	}
+	email := primaryEmail(req.Emails)
+
+	return s.applyUserPatch(ctx, id, scimUserPatch{displayName: &displayName, email: &email, active: req.Active})
+}
+
+func (s *SCIMService) PatchUser(ctx context.Context, id string, req models.SCIMPatchRequest) (*models.SCIMUser, error) {
+	var patch scimUserPatch
+	for _, op := range req.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		default:
+			return nil, fmt.Errorf("%w: unsupported op %q for User", models.ErrInvalidSCIM, op.Op)
+		}
+
+		// Без path значение - объект с атрибутами (так шлёт Okta)
+		values := map[string]json.RawMessage{op.Path: op.Value}
+		if op.Path == "" {
+			values = nil
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return nil, fmt.Errorf("%w: value must be an object", models.ErrInvalidSCIM)
+			}
+		}
+
+		for path, value := range values {
+			if err := patch.set(path, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.applyUserPatch(ctx, id, patch)
+}
+
+func (p *scimUserPatch) set(path string, value json.RawMessage) error {
+	switch strings.ToLower(path) {
+	case "active":
+		active, err := parseSCIMBool(value)
+		if err != nil {
+			return err
+		}
+		p.active = &active
+	case "displayname":
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil {
+			return fmt.Errorf("%w: displayName must be a string", models.ErrInvalidSCIM)
+		}
+		p.displayName = &name
+	case "emails":
+		var emails []models.SCIMEmail
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return fmt.Errorf("%w: emails must be a list", models.ErrInvalidSCIM)
+		}
+		email := primaryEmail(emails)
+		p.email = &email
+	case `emails[type eq "work"].value`, "emails[primary eq true].value":
+		var email string
+		if err := json.Unmarshal(value, &email); err != nil {
+			return fmt.Errorf("%w: email must be a string", models.ErrInvalidSCIM)
+		}
+		p.email = &email
+	case "externalid", "username", "name", "schemas":
+		// Не храним; userName неизменяем и совпадает с id
+	default:
+		return fmt.Errorf("%w: unsupported path %q for User", models.ErrInvalidSCIM, path)
+	}
+	return nil
+}
+
+// DeleteUser деактивирует пользователя
+func (s *SCIMService) DeleteUser(ctx context.Context, id string) error {
+	_, err := s.users.SetUserActive(ctx, id, false)
+	return err
+}
+
+// applyUserPatch: профиль меняется в своей транзакции, активность - через
+// SetUserActive, чтобы деактивация шла по общему пути с переназначением ревью
+func (s *SCIMService) applyUserPatch(ctx context.Context, id string, patch scimUserPatch) (*models.SCIMUser, error) {
+	tx, err := s.userStorage.UserBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.authz.RequireAdmin(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	before, err := s.userStorage.GetUserTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	username, email := before.Username, before.Email
+	if patch.displayName != nil && *patch.displayName != "" {
+		username = *patch.displayName
+	}
+	if patch.email != nil {
+		email = *patch.email
+	}
+
+	if username != before.Username || email != before.Email {
+		if err := s.scimStorage.UpdateUserProfileTx(ctx, tx, id, username, email); err != nil {
+			return nil, err
+		}
+
+		after, err := s.userStorage.GetUserTx(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := recordAudit(ctx, tx, s.audit, models.AuditEntityUser, id, "profile_changed", before, after); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	if patch.active != nil && *patch.active != before.IsActive {
+		if _, err := s.users.SetUserActive(ctx, id, *patch.active); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetUser(ctx, id)
+}
+
+func (s *SCIMService) ListGroups(ctx context.Context, filter string, startIndex int, count int) (*models.SCIMListResponse, error) {
+	teamName, err := parseSCIMFilter(filter, "displayName")
+	if err != nil {
+		return nil, err
+	}
+	startIndex, count = scimPage(startIndex, count)
+
+	teams, total, err := s.scimStorage.ListGroupsTx(ctx, nil, teamName, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]models.SCIMGroup, 0, len(teams))
+	for _, t := range teams {
+		resources = append(resources, toSCIMGroup(t))
+	}
+	return scimList(resources, total, startIndex, len(resources)), nil
+}
+
+func (s *SCIMService) GetGroup(ctx context.Context, id string) (*models.SCIMGroup, error) {
+	team, err := s.scimStorage.GetGroupTx(ctx, nil, id)
+	if err != nil {
+		return nil, err
+	}
+	res := toSCIMGroup(*team)
+	return &res, nil
+}
+
+func (s *SCIMService) CreateGroup(ctx context.Context, req models.SCIMGroup) (*models.SCIMGroup, error) {
+	if req.DisplayName == "" {
+		return nil, fmt.Errorf("%w: displayName is required", models.ErrInvalidSCIM)
+	}
+
+	tx, err := s.userStorage.UserBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.authz.RequireAdmin(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := s.scimStorage.CreateGroupTx(ctx, tx, req.DisplayName); err != nil {
+		return nil, err
+	}
+	if err := s.addMembers(ctx, tx, req.DisplayName, memberIDs(req.Members)); err != nil {
+		return nil, err
+	}
+
+	created, err := s.scimStorage.GetGroupTx(ctx, tx, req.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityTeam, req.DisplayName, "created", nil, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	res := toSCIMGroup(*created)
+	return &res, nil
+}
+
+// ReplaceGroup - PUT: состав группы становится ровно members
+func (s *SCIMService) ReplaceGroup(ctx context.Context, id string, req models.SCIMGroup) (*models.SCIMGroup, error) {
+	if req.DisplayName != "" && req.DisplayName != id {
+		return nil, fmt.Errorf("%w: displayName is immutable", models.ErrInvalidSCIM)
+	}
+
+	return s.changeMembers(ctx, id, func(current map[string]bool) (map[string]bool, error) {
+		return setOf(memberIDs(req.Members)), nil
+	})
+}
+
+func (s *SCIMService) PatchGroup(ctx context.Context, id string, req models.SCIMPatchRequest) (*models.SCIMGroup, error) {
+	return s.changeMembers(ctx, id, func(current map[string]bool) (map[string]bool, error) {
+		for _, op := range req.Operations {
+			if err := applyGroupOperation(id, current, op); err != nil {
+				return nil, err
+			}
+		}
+		return current, nil
+	})
+}
+
+func applyGroupOperation(id string, members map[string]bool, op models.SCIMPatchOperation) error {
+	path := strings.TrimSpace(op.Path)
+	opName := strings.ToLower(op.Op)
+
+	// remove с фильтром в path: members[value eq "u1"]
+	if opName == "remove" && strings.HasPrefix(strings.ToLower(path), "members[") && strings.HasSuffix(path, "]") {
+		userID, err := parseSCIMFilter(path[len("members["):len(path)-1], "value")
+		if err != nil {
+			return err
+		}
+		delete(members, userID)
+		return nil
+	}
+
+	var values []models.SCIMMember
+	switch strings.ToLower(path) {
+	case "members":
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return fmt.Errorf("%w: members must be a list", models.ErrInvalidSCIM)
+			}
+		}
+	case "":
+		var group models.SCIMGroup
+		if err := json.Unmarshal(op.Value, &group); err != nil {
+			return fmt.Errorf("%w: value must be an object", models.ErrInvalidSCIM)
+		}
+		if group.DisplayName != "" && group.DisplayName != id {
+			return fmt.Errorf("%w: displayName is immutable", models.ErrInvalidSCIM)
+		}
+		if group.Members == nil {
+			return nil
+		}
+		values = group.Members
+	case "displayname":
+		var name string
+		if err := json.Unmarshal(op.Value, &name); err != nil || name != id {
+			return fmt.Errorf("%w: displayName is immutable", models.ErrInvalidSCIM)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported path %q for Group", models.ErrInvalidSCIM, op.Path)
+	}
+
+	switch opName {
+	case "add":
+		for _, m := range values {
+			members[m.Value] = true
+		}
+	case "replace":
+		clear(members)
+		for _, m := range values {
+			members[m.Value] = true
+		}
+	case "remove":
+		if len(values) == 0 {
+			clear(members)
+		}
+		for _, m := range values {
+			delete(members, m.Value)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported op %q for Group", models.ErrInvalidSCIM, op.Op)
+	}
+	return nil
+}
+
+// changeMembers приводит состав команды к результату change: новых
+// участников переносит в неё, выбывших - в команду по умолчанию
+func (s *SCIMService) changeMembers(ctx context.Context, id string, change func(current map[string]bool) (map[string]bool, error)) (*models.SCIMGroup, error) {
+	tx, err := s.userStorage.UserBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.authz.RequireAdmin(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	before, err := s.scimStorage.GetGroupTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]bool, len(before.Members))
+	for _, m := range before.Members {
+		current[m.UserID] = true
+	}
+
+	desired, err := change(current)
+	if err != nil {
+		return nil, err
+	}
+	keep := make([]string, 0, len(desired))
+	for userID := range desired {
+		keep = append(keep, userID)
+	}
+	removed := false
+	for _, m := range before.Members {
+		if !desired[m.UserID] {
+			removed = true
+			break
+		}
+	}
+	if removed && id == s.defaultTeam {
+		return nil, fmt.Errorf("%w: members cannot be removed from the default group", models.ErrInvalidSCIM)
+	}
+
+	if removed {
+		if err := s.scimStorage.EnsureTeamTx(ctx, tx, s.defaultTeam); err != nil {
+			return nil, err
+		}
+		if err := s.scimStorage.MoveTeamMembersTx(ctx, tx, id, s.defaultTeam, keep); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.addMembers(ctx, tx, id, keep); err != nil {
+		return nil, err
+	}
+
+	after, err := s.scimStorage.GetGroupTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityTeam, id, "members_changed", before, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	res := toSCIMGroup(*after)
+	return &res, nil
+}
+
+func (s *SCIMService) addMembers(ctx context.Context, tx pgx.Tx, teamName string, userIDs []string) error {
+	moved, err := s.scimStorage.MoveUsersToTeamTx(ctx, tx, userIDs, teamName)
+	if err != nil {
+		return err
+	}
+	if moved != int64(len(userIDs)) {
+		return fmt.Errorf("%w: unknown member", models.ErrInvalidSCIM)
+	}
+	return nil
+}
+
+// DeleteGroup удаляет команду, участники уходят в команду по умолчанию
+func (s *SCIMService) DeleteGroup(ctx context.Context, id string) error {
+	if id == s.defaultTeam {
+		return fmt.Errorf("%w: the default group cannot be deleted", models.ErrInvalidSCIM)
+	}
+
+	tx, err := s.userStorage.UserBeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.authz.RequireAdmin(ctx, tx); err != nil {
+		return err
+	}
+
+	before, err := s.scimStorage.GetGroupTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.scimStorage.EnsureTeamTx(ctx, tx, s.defaultTeam); err != nil {
+		return err
+	}
+	if err := s.scimStorage.MoveTeamMembersTx(ctx, tx, id, s.defaultTeam, nil); err != nil {
+		return err
+	}
+	if err := s.scimStorage.DeleteGroupTx(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityTeam, id, "deleted", before, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func toSCIMUser(u models.User) models.SCIMUser {
+	active := u.IsActive
+	res := models.SCIMUser{
+		Schemas:     []string{models.SCIMSchemaUser},
+		ID:          u.UserID,
+		UserName:    u.UserID,
+		DisplayName: u.Username,
+		Active:      &active,
+		Groups:      []models.SCIMMember{{Value: u.TeamName, Display: u.TeamName}},
+		Meta:        &models.SCIMMeta{ResourceType: models.SCIMResourceUser, Location: "/scim/v2/Users/" + u.UserID},
+	}
+	if u.Email != "" {
+		res.Emails = []models.SCIMEmail{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	return res
+}
+
+func toSCIMGroup(t models.Team) models.SCIMGroup {
+	members := make([]models.SCIMMember, 0, len(t.Members))
+	for _, m := range t.Members {
+		members = append(members, models.SCIMMember{Value: m.UserID, Display: m.Username})
+	}
+	return models.SCIMGroup{
+		Schemas:     []string{models.SCIMSchemaGroup},
+		ID:          t.TeamName,
+		DisplayName: t.TeamName,
+		Members:     members,
+		Meta:        &models.SCIMMeta{ResourceType: models.SCIMResourceGroup, Location: "/scim/v2/Groups/" + t.TeamName},
+	}
+}
+
+func scimList(resources interface{}, total int, startIndex int, itemsPerPage int) *models.SCIMListResponse {
+	return &models.SCIMListResponse{
+		Schemas:      []string{models.SCIMSchemaList},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: itemsPerPage,
+		Resources:    resources,
+	}
+}
+
+// scimPage: startIndex считается с 1, count ограничен SCIMDefaultPageSize
+func scimPage(startIndex int, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 || count > models.SCIMDefaultPageSize {
+		count = models.SCIMDefaultPageSize
+	}
+	return startIndex, count
+}
+
+// parseSCIMFilter разбирает единственную поддерживаемую форму `attr eq "value"`;
+// пустой фильтр - пустое значение
+func parseSCIMFilter(filter string, attr string) (string, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return "", nil
+	}
+
+	parts := strings.SplitN(filter, " ", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[0], attr) || !strings.EqualFold(parts[1], "eq") {
+		return "", fmt.Errorf("%w: only %s eq \"...\" filter is supported", models.ErrInvalidSCIM, attr)
+	}
+
+	value, err := strconv.Unquote(strings.TrimSpace(parts[2]))
+	if err != nil {
+		return "", fmt.Errorf("%w: filter value must be a quoted string", models.ErrInvalidSCIM)
+	}
+	return value, nil
+}
+
+// parseSCIMBool принимает и true, и "True" - Azure AD шлёт булевы строками
+func parseSCIMBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("%w: active must be a boolean", models.ErrInvalidSCIM)
+}
+
+func primaryEmail(emails []models.SCIMEmail) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+func memberIDs(members []models.SCIMMember) []string {
+	ids := make([]string, 0, len(members))
+	seen := make(map[string]bool, len(members))
+	for _, m := range members {
+		if m.Value != "" && !seen[m.Value] {
+			seen[m.Value] = true
+			ids = append(ids, m.Value)
+		}
+	}
+	return ids
+}
+
+func setOf(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
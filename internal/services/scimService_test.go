@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDirectory - пользователи и команды в памяти, общие для UserStorage и SCIMStorage
+type fakeDirectory struct {
+	storage.UserStorage
+	storage.SCIMStorage
+	users map[string]models.User
+	teams map[string]bool
+}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{users: map[string]models.User{}, teams: map[string]bool{}}
+}
+
+func (f *fakeDirectory) UserBeginTx(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+func (f *fakeDirectory) GetUserTx(ctx context.Context, tx pgx.Tx, userID string) (*models.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeDirectory) UpdateUserActiveTx(ctx context.Context, tx pgx.Tx, userID string, isActive bool) error {
+	user := f.users[userID]
+	user.IsActive = isActive
+	f.users[userID] = user
+	return nil
+}
+
+func (f *fakeDirectory) ListUsersTx(ctx context.Context, tx pgx.Tx, userID string, offset int, limit int) ([]models.User, int, error) {
+	var all []models.User
+	for _, u := range f.users {
+		if userID == "" || u.UserID == userID {
+			all = append(all, u)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].UserID < all[j].UserID })
+	end := min(offset+limit, len(all))
+	if offset >= end {
+		return nil, len(all), nil
+	}
+	return all[offset:end], len(all), nil
+}
+
+func (f *fakeDirectory) CreateUserTx(ctx context.Context, tx pgx.Tx, user models.User) error {
+	if _, ok := f.users[user.UserID]; ok {
+		return models.ErrUserExists
+	}
+	f.users[user.UserID] = user
+	return nil
+}
+
+func (f *fakeDirectory) UpdateUserProfileTx(ctx context.Context, tx pgx.Tx, userID string, username string, email string) error {
+	user := f.users[userID]
+	user.Username, user.Email = username, email
+	f.users[userID] = user
+	return nil
+}
+
+func (f *fakeDirectory) EnsureTeamTx(ctx context.Context, tx pgx.Tx, teamName string) error {
+	f.teams[teamName] = true
+	return nil
+}
+
+func (f *fakeDirectory) CreateGroupTx(ctx context.Context, tx pgx.Tx, teamName string) error {
+	if f.teams[teamName] {
+		return models.ErrTeamExists
+	}
+	f.teams[teamName] = true
+	return nil
+}
+
+func (f *fakeDirectory) GetGroupTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.Team, error) {
+	if !f.teams[teamName] {
+		return nil, models.ErrNotFound
+	}
+	team := &models.Team{TeamName: teamName, Members: []models.User{}}
+	for _, u := range f.users {
+		if u.TeamName == teamName {
+			team.Members = append(team.Members, u)
+		}
+	}
+	sort.Slice(team.Members, func(i, j int) bool { return team.Members[i].UserID < team.Members[j].UserID })
+	return team, nil
+}
+
+func (f *fakeDirectory) MoveUsersToTeamTx(ctx context.Context, tx pgx.Tx, userIDs []string, teamName string) (int64, error) {
+	var moved int64
+	for _, id := range userIDs {
+		if user, ok := f.users[id]; ok {
+			user.TeamName = teamName
+			f.users[id] = user
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (f *fakeDirectory) MoveTeamMembersTx(ctx context.Context, tx pgx.Tx, fromTeam string, toTeam string, keep []string) error {
+	for id, user := range f.users {
+		if user.TeamName == fromTeam && !contains(keep, id) {
+			user.TeamName = toTeam
+			f.users[id] = user
+		}
+	}
+	return nil
+}
+
+func (f *fakeDirectory) DeleteGroupTx(ctx context.Context, tx pgx.Tx, teamName string) error {
+	delete(f.teams, teamName)
+	return nil
+}
+
+func newTestSCIM(dir *fakeDirectory, prs *fakeDigestPRs, reassigner *fakeReassigner) (*SCIMService, *fakeAudit) {
+	audit := &fakeAudit{}
+	users := NewUserService(dir, &fakeOutbox{}, audit, NewAuthorizer(dir), prs, reassigner)
+	return NewSCIMService(dir, dir, users, audit, NewAuthorizer(dir), "unassigned"), audit
+}
+
+func TestSCIMUserLifecycle(t *testing.T) {
+	dir := newFakeDirectory()
+	prs := &fakeDigestPRs{byReviewer: map[string][]models.PullRequestShort{
+		"alice": {
+			{PullRequestID: "pr-1", Status: "OPEN"},
+			{PullRequestID: "pr-2", Status: "MERGED"},
+		},
+	}}
+	reassigner := &fakeReassigner{replacements: map[string]string{"alice": "bob"}}
+	scim, audit := newTestSCIM(dir, prs, reassigner)
+	ctx := asAdmin()
+
+	created, err := scim.CreateUser(ctx, models.SCIMUser{
+		UserName:    "alice",
+		DisplayName: "Alice",
+		Emails:      []models.SCIMEmail{{Value: "alice@example.com", Primary: true}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", created.ID)
+	assert.True(t, *created.Active)
+	assert.Equal(t, "unassigned", dir.users["alice"].TeamName)
+	assert.True(t, dir.teams["unassigned"])
+
+	_, err = scim.CreateUser(ctx, models.SCIMUser{UserName: "alice"})
+	assert.ErrorIs(t, err, models.ErrUserExists)
+
+	list, err := scim.ListUsers(ctx, `userName eq "alice"`, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.TotalResults)
+
+	_, err = scim.ListUsers(ctx, `emails co "example"`, 1, 10)
+	assert.ErrorIs(t, err, models.ErrInvalidSCIM)
+
+	// Okta шлёт объект без path, Azure AD - булевы строками
+	patched, err := scim.PatchUser(ctx, "alice", models.SCIMPatchRequest{Operations: []models.SCIMPatchOperation{
+		{Op: "replace", Value: json.RawMessage(`{"displayName":"Alice A."}`)},
+		{Op: "Replace", Path: "active", Value: json.RawMessage(`"False"`)},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice A.", patched.DisplayName)
+	assert.False(t, *patched.Active)
+
+	require.Len(t, reassigner.calls, 1, "only open reviews are reassigned")
+	assert.Equal(t, models.ReassignRequest{PullRequestID: "pr-1", OldUserID: "alice", Reason: models.TimelineReasonDeactivation}, reassigner.calls[0])
+
+	var actions []string
+	for _, e := range audit.entries {
+		actions = append(actions, e.Action)
+	}
+	assert.Equal(t, []string{"created", "profile_changed", "active_changed"}, actions)
+
+	_, err = scim.ReplaceUser(ctx, "alice", models.SCIMUser{UserName: "alice2"})
+	assert.ErrorIs(t, err, models.ErrInvalidSCIM)
+}
+
+func TestSCIMDeleteUserDeactivates(t *testing.T) {
+	dir := newFakeDirectory()
+	dir.users["bob"] = models.User{UserID: "bob", TeamName: "backend", IsActive: true}
+	scim, _ := newTestSCIM(dir, &fakeDigestPRs{}, &fakeReassigner{})
+
+	require.NoError(t, scim.DeleteUser(asAdmin(), "bob"))
+	assert.False(t, dir.users["bob"].IsActive)
+	assert.Contains(t, dir.users, "bob")
+}
+
+func TestSCIMGroupMembership(t *testing.T) {
+	dir := newFakeDirectory()
+	for _, id := range []string{"u1", "u2", "u3"} {
+		dir.users[id] = models.User{UserID: id, TeamName: "unassigned", IsActive: true}
+	}
+	dir.teams["unassigned"] = true
+	scim, _ := newTestSCIM(dir, &fakeDigestPRs{}, &fakeReassigner{})
+	ctx := asAdmin()
+
+	group, err := scim.CreateGroup(ctx, models.SCIMGroup{DisplayName: "backend", Members: []models.SCIMMember{{Value: "u1"}, {Value: "u2"}}})
+	require.NoError(t, err)
+	assert.Len(t, group.Members, 2)
+
+	_, err = scim.CreateGroup(ctx, models.SCIMGroup{DisplayName: "frontend", Members: []models.SCIMMember{{Value: "ghost"}}})
+	assert.ErrorIs(t, err, models.ErrInvalidSCIM)
+
+	group, err = scim.PatchGroup(ctx, "backend", models.SCIMPatchRequest{Operations: []models.SCIMPatchOperation{
+		{Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"u3"}]`)},
+		{Op: "remove", Path: `members[value eq "u1"]`},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []models.SCIMMember{{Value: "u2"}, {Value: "u3"}}, group.Members)
+	assert.Equal(t, "unassigned", dir.users["u1"].TeamName)
+
+	group, err = scim.ReplaceGroup(ctx, "backend", models.SCIMGroup{Members: []models.SCIMMember{{Value: "u1"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []models.SCIMMember{{Value: "u1"}}, group.Members)
+	assert.Equal(t, "unassigned", dir.users["u2"].TeamName)
+
+	_, err = scim.PatchGroup(ctx, "backend", models.SCIMPatchRequest{Operations: []models.SCIMPatchOperation{
+		{Op: "replace", Path: "displayName", Value: json.RawMessage(`"platform"`)},
+	}})
+	assert.ErrorIs(t, err, models.ErrInvalidSCIM)
+
+	require.NoError(t, scim.DeleteGroup(ctx, "backend"))
+	assert.Equal(t, "unassigned", dir.users["u1"].TeamName)
+	assert.NotContains(t, dir.teams, "backend")
+
+	assert.ErrorIs(t, scim.DeleteGroup(ctx, "unassigned"), models.ErrInvalidSCIM)
+}
+
+func TestSCIMRequiresAdmin(t *testing.T) {
+	dir := newFakeDirectory()
+	dir.users["lead"] = models.User{UserID: "lead", TeamName: "backend", Role: models.RoleLead}
+	scim, _ := newTestSCIM(dir, &fakeDigestPRs{}, &fakeReassigner{})
+
+	_, err := scim.CreateUser(asUser("lead"), models.SCIMUser{UserName: "eve"})
+	assert.Equal(t, models.ErrForbidden, err)
+	assert.NotContains(t, dir.users, "eve")
+}
+
+func TestParseSCIMFilter(t *testing.T) {
+	value, err := parseSCIMFilter(`USERNAME Eq "john \"j\" doe"`, "userName")
+	require.NoError(t, err)
+	assert.Equal(t, `john "j" doe`, value)
+
+	value, err = parseSCIMFilter("", "userName")
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	for _, filter := range []string{`userName sw "j"`, `displayName eq "x"`, `userName eq x`} {
+		_, err := parseSCIMFilter(filter, "userName")
+		assert.ErrorIs(t, err, models.ErrInvalidSCIM, filter)
+	}
+}
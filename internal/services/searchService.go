@@ -0,0 +1,91 @@
+package services
+
+/*
+Поиск по PR, пользователям и командам:
+	1. Проверка запроса и лимита (на каждый тип сущности отдельно)
+	2. Подготовка запроса: слова - в префиксный tsquery, текст целиком - для
+	   триграмм и как префикс идентификатора
+	3. Результаты сгруппированы по типу сущности и отсортированы по релевантности
+
+Слова в tsquery содержат только буквы и цифры - спецсимволы to_tsquery
+(&, |, !, :, скобки) из запроса не попадают.
+*/
+import (
+	"context"
+	"fmt"
+	"strings"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"unicode"
+	"unicode/utf8"
+)
+
+type SearchService struct {
+	storage storage.SearchStorage
+}
+
+func NewSearchService(storage storage.SearchStorage) *SearchService {
+	return &SearchService{storage: storage}
+}
+
+func (s *SearchService) Search(ctx context.Context, text string, limit int) (*models.SearchResults, error) {
+	q, err := prepareSearchQuery(text, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	prs, err := s.storage.SearchPRsTx(ctx, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.storage.SearchUsersTx(ctx, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.storage.SearchTeamsTx(ctx, nil, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SearchResults{Query: q.Text, PullRequests: prs, Users: users, Teams: teams}, nil
+}
+
+func prepareSearchQuery(text string, limit int) (models.SearchQuery, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.SearchQuery{}, fmt.Errorf("%w: q is required", models.ErrInvalidQuery)
+	}
+	if utf8.RuneCountInString(text) > models.MaxSearchQueryLen {
+		return models.SearchQuery{}, fmt.Errorf("%w: q must be at most %d characters", models.ErrInvalidQuery, models.MaxSearchQueryLen)
+	}
+
+	switch {
+	case limit == 0:
+		limit = models.DefaultSearchLimit
+	case limit < 0 || limit > models.MaxSearchLimit:
+		return models.SearchQuery{}, fmt.Errorf("%w: limit must be between 1 and %d", models.ErrInvalidQuery, models.MaxSearchLimit)
+	}
+
+	return models.SearchQuery{
+		Text:    text,
+		TSQuery: prefixTSQuery(text),
+		Prefix:  likePrefix(text),
+		Limit:   limit,
+	}, nil
+}
+
+// prefixTSQuery превращает "search feat" в "search:* & feat:*"
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// likePrefix экранирует спецсимволы LIKE, чтобы "pr_1%" искался буквально
+func likePrefix(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text) + "%"
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSearch struct {
+	storage.SearchStorage
+	queries []models.SearchQuery
+}
+
+func (f *fakeSearch) SearchPRsTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.PRSearchHit, error) {
+	f.queries = append(f.queries, q)
+	return []models.PRSearchHit{{PullRequestShort: models.PullRequestShort{PullRequestID: "pr-1"}, Rank: 0.8}}, nil
+}
+
+func (f *fakeSearch) SearchUsersTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.UserSearchHit, error) {
+	return []models.UserSearchHit{}, nil
+}
+
+func (f *fakeSearch) SearchTeamsTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.TeamSearchHit, error) {
+	return []models.TeamSearchHit{{TeamName: "search", Rank: 1}}, nil
+}
+
+func TestSearchGroupsResults(t *testing.T) {
+	store := &fakeSearch{}
+	svc := NewSearchService(store)
+
+	res, err := svc.Search(context.Background(), "  search feat ", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "search feat", res.Query)
+	assert.Len(t, res.PullRequests, 1)
+	assert.Empty(t, res.Users)
+	assert.Len(t, res.Teams, 1)
+
+	require.Len(t, store.queries, 1)
+	assert.Equal(t, models.SearchQuery{
+		Text:    "search feat",
+		TSQuery: "search:* & feat:*",
+		Prefix:  "search feat%",
+		Limit:   models.DefaultSearchLimit,
+	}, store.queries[0])
+}
+
+func TestPrepareSearchQuery(t *testing.T) {
+	q, err := prepareSearchQuery(`Поиск & (pr_1%):*`, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "поиск:* & pr:* & 1:*", q.TSQuery, "tsquery operators are stripped")
+	assert.Equal(t, `Поиск & (pr\_1\%):*%`, q.Prefix)
+
+	q, err = prepareSearchQuery("!!!", 5)
+	require.NoError(t, err)
+	assert.Empty(t, q.TSQuery, "trigram search still runs without words")
+
+	for _, tc := range []struct {
+		text  string
+		limit int
+	}{
+		{"   ", 0},
+		{strings.Repeat("a", models.MaxSearchQueryLen+1), 0},
+		{"pr", models.MaxSearchLimit + 1},
+	} {
+		_, err := prepareSearchQuery(tc.text, tc.limit)
+		assert.ErrorIs(t, err, models.ErrInvalidQuery, tc.text)
+	}
+}
@@ -7,16 +7,110 @@ import (
 
 type TeamManager interface {
 	CreateTeam(ctx context.Context, team models.Team) (*models.Team, error)
-	GetTeam(ctx context.Context, teamName string) (*models.Team, error)
+	GetTeam(ctx context.Context, teamName string, q models.UserListQuery) (*models.TeamPage, error)
+	SetCodeOwners(ctx context.Context, teamName string, content string) (*models.CodeOwners, error)
+	AddMember(ctx context.Context, req models.AddTeamMemberRequest) (*models.Team, error)
+	RemoveMember(ctx context.Context, req models.RemoveTeamMemberRequest) (*models.Team, error)
 }
 
 type UserManager interface {
 	SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, error)
+	SetDigestOptOut(ctx context.Context, userID string, optOut bool) (*models.User, error)
+	SetRole(ctx context.Context, userID string, role string) (*models.User, error)
+	ListUsers(ctx context.Context, q models.UserListQuery) (*models.UserPage, error)
 }
 
 type PullRequestManager interface {
 	CreatePR(ctx context.Context, req models.CreatePRRequest) (*models.PullRequest, error)
 	MergePR(ctx context.Context, prID string) (*models.PullRequest, error)
+	UpdatePR(ctx context.Context, req models.UpdatePRRequest) (*models.PullRequest, error)
+	ClosePR(ctx context.Context, prID string) (*models.PullRequest, error)
+	ReopenPR(ctx context.Context, prID string) (*models.PullRequest, error)
 	ReassignReviewer(ctx context.Context, req models.ReassignRequest) (*models.PullRequest, string, error)
-	GetUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error)
+	SubmitReview(ctx context.Context, req models.ReviewDecisionRequest) (*models.PullRequest, error)
+	GetUserReviews(ctx context.Context, userID string, q models.PRListQuery) (*models.PRPage, error)
+	ListPRs(ctx context.Context, q models.PRListQuery) (*models.PRPage, error)
+	GetHistory(ctx context.Context, prID string) ([]models.TimelineEvent, error)
+}
+
+type WebhookManager interface {
+	HandleDelivery(ctx context.Context, d models.WebhookDelivery) (*models.WebhookResult, error)
+}
+
+type SubscriptionManager interface {
+	CreateSubscription(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.Subscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) ([]models.OutboundDelivery, error)
+}
+
+type EventStreamer interface {
+	Authorize(ctx context.Context, filter models.StreamFilter) error
+	Subscribe(filter models.StreamFilter, lastEventID uint64) (*StreamSubscription, []models.StreamMessage, bool)
+	Unsubscribe(sub *StreamSubscription)
+}
+
+type ChatTargetManager interface {
+	SetTarget(ctx context.Context, req models.SetChatTargetRequest) (*models.ChatTarget, error)
+	DeleteTarget(ctx context.Context, scope string, targetID string) error
+	ListTargets(ctx context.Context) ([]models.ChatTarget, error)
+}
+
+type SLAManager interface {
+	SetTeamSLA(ctx context.Context, sla models.TeamSLA) (*models.TeamSLA, error)
+	GetTeamSLA(ctx context.Context, teamName string) (*models.TeamSLA, error)
+	ListEscalations(ctx context.Context, teamName string, limit int) ([]models.SLAEscalation, error)
+}
+
+type StalePRManager interface {
+	SetPolicy(ctx context.Context, policy models.StalePolicy) (*models.StalePolicy, error)
+	GetPolicy(ctx context.Context, teamName string) (*models.StalePolicy, error)
+	Plan(ctx context.Context) (*models.StalePlan, error)
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, rawToken string) (*models.Principal, error)
+}
+
+type APITokenManager interface {
+	IssueToken(ctx context.Context, req models.IssueTokenRequest) (*models.APIToken, error)
+	ListTokens(ctx context.Context) ([]models.APIToken, error)
+	RevokeToken(ctx context.Context, id int64) error
+}
+
+type IdempotencyManager interface {
+	Begin(ctx context.Context, scope string, key string, fingerprint string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope string, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, scope string, key string) error
+}
+
+type BatchRunner interface {
+	Run(ctx context.Context, mode string, n int, deferWrites func(i int) bool, exec func(ctx context.Context, i int) bool) (int, bool, error)
+}
+
+type AuditReader interface {
+	List(ctx context.Context, entity string, entityID string, cursor string, limit int) (*models.AuditPage, error)
+}
+
+type OutboxReader interface {
+	ListDead(ctx context.Context, limit int) ([]models.OutboxDelivery, error)
+}
+
+type SCIMManager interface {
+	ListUsers(ctx context.Context, filter string, startIndex int, count int) (*models.SCIMListResponse, error)
+	GetUser(ctx context.Context, id string) (*models.SCIMUser, error)
+	CreateUser(ctx context.Context, user models.SCIMUser) (*models.SCIMUser, error)
+	ReplaceUser(ctx context.Context, id string, user models.SCIMUser) (*models.SCIMUser, error)
+	PatchUser(ctx context.Context, id string, patch models.SCIMPatchRequest) (*models.SCIMUser, error)
+	DeleteUser(ctx context.Context, id string) error
+	ListGroups(ctx context.Context, filter string, startIndex int, count int) (*models.SCIMListResponse, error)
+	GetGroup(ctx context.Context, id string) (*models.SCIMGroup, error)
+	CreateGroup(ctx context.Context, group models.SCIMGroup) (*models.SCIMGroup, error)
+	ReplaceGroup(ctx context.Context, id string, group models.SCIMGroup) (*models.SCIMGroup, error)
+	PatchGroup(ctx context.Context, id string, patch models.SCIMPatchRequest) (*models.SCIMGroup, error)
+	DeleteGroup(ctx context.Context, id string) error
+}
+
+type Searcher interface {
+	Search(ctx context.Context, text string, limit int) (*models.SearchResults, error)
 }
@@ -0,0 +1,345 @@
+package services
+
+/*
+SLA первого ревью:
+	1. Настройки SLA команды и журнал эскалаций
+	2. Расчёт срока с учётом рабочего времени команды (пн-пт, WorkStart-WorkEnd)
+	3. Фоновая проверка открытых PR с истёкшим сроком и эскалация по политике:
+		notify   - событие pr.sla_breached (его разносят sink'и outbox, в т.ч. чат)
+		reassign - ReassignReviewer для каждого ревьюера без решения
+
+Срок ставится при создании PR и заново при каждом переназначении - у нового
+ревьюера своё окно. Эскалируются только ревьюеры без решения (approve /
+request changes) в хронологии после своего назначения; если решили все,
+срок снимается. Если заменить некого (NO_CANDIDATE), reassign откатывается
+к notify. Каждая эскалация пишется в sla_escalations в одной транзакции
+с переназначением или отметкой о просрочке.
+*/
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"time"
+)
+
+type SLAService struct {
+	slaStorage  storage.SLAStorage
+	prStorage   storage.PullReqStorage
+	teamStorage storage.TeamStorage
+	prManager   PullRequestManager
+	outbox      storage.OutboxStorage
+	audit       storage.AuditStorage
+	timeline    storage.TimelineStorage
+	authz       *Authorizer
+	interval    time.Duration
+	batchSize   int
+	now         func() time.Time
+}
+
+func NewSLAService(
+	slaStorage storage.SLAStorage,
+	prStorage storage.PullReqStorage,
+	teamStorage storage.TeamStorage,
+	prManager PullRequestManager,
+	outbox storage.OutboxStorage,
+	audit storage.AuditStorage,
+	timeline storage.TimelineStorage,
+	authz *Authorizer,
+	interval time.Duration,
+) *SLAService {
+	return &SLAService{
+		slaStorage:  slaStorage,
+		prStorage:   prStorage,
+		teamStorage: teamStorage,
+		prManager:   prManager,
+		outbox:      outbox,
+		audit:       audit,
+		timeline:    timeline,
+		authz:       authz,
+		interval:    interval,
+		batchSize:   100,
+		now:         time.Now,
+	}
+}
+
+func (s *SLAService) SetTeamSLA(ctx context.Context, sla models.TeamSLA) (*models.TeamSLA, error) {
+	if sla.ResponseHours <= 0 {
+		return nil, fmt.Errorf("%w: response_hours must be positive", models.ErrInvalidSLA)
+	}
+	if sla.Policy != models.SLAPolicyNotify && sla.Policy != models.SLAPolicyReassign {
+		return nil, fmt.Errorf("%w: policy must be notify or reassign", models.ErrInvalidSLA)
+	}
+	if sla.Timezone == "" {
+		sla.Timezone = "UTC"
+	}
+	if _, err := slaDeadline(sla, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.teamStorage.GetTeamInfoTx(ctx, nil, sla.TeamName); err != nil {
+		return nil, err
+	}
+	if err := s.authz.RequireTeamLead(ctx, nil, sla.TeamName); err != nil {
+		return nil, err
+	}
+
+	if err := s.slaStorage.SetTeamSLATx(ctx, nil, sla); err != nil {
+		return nil, err
+	}
+	return s.slaStorage.GetTeamSLATx(ctx, nil, sla.TeamName)
+}
+
+func (s *SLAService) GetTeamSLA(ctx context.Context, teamName string) (*models.TeamSLA, error) {
+	return s.slaStorage.GetTeamSLATx(ctx, nil, teamName)
+}
+
+func (s *SLAService) ListEscalations(ctx context.Context, teamName string, limit int) ([]models.SLAEscalation, error) {
+	return s.slaStorage.ListEscalationsTx(ctx, nil, teamName, limit)
+}
+
+// Run крутится до отмены ctx
+func (s *SLAService) Run(ctx context.Context) {
+	ctx = WithAuditActor(SystemContext(ctx, "sla"), auditSystemActor)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CheckSLA(ctx); err != nil {
+				slog.Error("SLA check failed", "error", err)
+			}
+		}
+	}
+}
+
+// CheckSLA эскалирует все PR с истёкшим сроком
+func (s *SLAService) CheckSLA(ctx context.Context) error {
+	due, err := s.slaStorage.ListSLADueTx(ctx, nil, s.now(), s.batchSize)
+	if err != nil {
+		return err
+	}
+
+	policies := make(map[string]string)
+	for _, d := range due {
+		pending, err := s.pendingReviewers(ctx, d.PullRequest)
+		if err != nil {
+			slog.Error("Failed to load PR timeline for SLA check", "pull_request_id", d.PullRequest.PullRequestID, "error", err)
+			continue
+		}
+		if len(pending) == 0 {
+			// все ревьюеры уже ответили - ждать больше нечего
+			if err := s.prStorage.UpdatePRSLATx(ctx, nil, d.PullRequest.PullRequestID, nil); err != nil {
+				slog.Error("Failed to clear SLA of reviewed PR", "pull_request_id", d.PullRequest.PullRequestID, "error", err)
+			}
+			continue
+		}
+
+		policy, ok := policies[d.TeamName]
+		if !ok {
+			policy = models.SLAPolicyNotify
+			sla, err := s.slaStorage.GetTeamSLATx(ctx, nil, d.TeamName)
+			if err != nil && err != models.ErrNotFound {
+				return err
+			}
+			if sla != nil {
+				policy = sla.Policy
+			}
+			policies[d.TeamName] = policy
+		}
+
+		if policy == models.SLAPolicyReassign && s.escalateByReassign(ctx, d, pending) {
+			continue
+		}
+		if err := s.escalateByNotify(ctx, d, pending); err != nil {
+			slog.Error("Failed to escalate SLA breach", "pull_request_id", d.PullRequest.PullRequestID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// pendingReviewers - назначенные ревьюеры без решения после своего
+// последнего назначения
+func (s *SLAService) pendingReviewers(ctx context.Context, pr models.PullRequest) ([]string, error) {
+	events, err := s.timeline.ListTimelineTx(ctx, nil, pr.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	acted := make(map[string]bool)
+	for _, e := range events {
+		switch e.Event {
+		case models.TimelineReviewerAssigned:
+			delete(acted, e.UserID)
+		case models.TimelineApproved, models.TimelineChangesRequested:
+			acted[e.UserID] = true
+		}
+	}
+
+	var pending []string
+	for _, reviewer := range pr.AssignedReviewers {
+		if !acted[reviewer] {
+			pending = append(pending, reviewer)
+		}
+	}
+	return pending, nil
+}
+
+// escalateByReassign возвращает false, если не удалось заменить ни одного ревьюера
+func (s *SLAService) escalateByReassign(ctx context.Context, d models.SLADuePR, reviewers []string) bool {
+	reassigned := false
+	for _, reviewer := range reviewers {
+		ok, err := s.reassignOne(ctx, d, reviewer)
+		if err != nil {
+			slog.Error("SLA reassign failed", "pull_request_id", d.PullRequest.PullRequestID, "reviewer", reviewer, "error", err)
+			continue
+		}
+		reassigned = reassigned || ok
+	}
+	return reassigned
+}
+
+// reassignOne переназначает ревьюера и пишет эскалацию в одной транзакции:
+// ReassignReviewer работает в savepoint внутри неё. false без ошибки -
+// заменить некого или ревьюер уже снят.
+func (s *SLAService) reassignOne(ctx context.Context, d models.SLADuePR, reviewer string) (bool, error) {
+	tx, err := s.prStorage.PRBeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, newReviewer, err := s.prManager.ReassignReviewer(storage.WithTx(ctx, tx), models.ReassignRequest{
+		PullRequestID: d.PullRequest.PullRequestID,
+		OldUserID:     reviewer,
+		Reason:        models.TimelineReasonSLA,
+	})
+	if err == models.ErrNoCandidate || err == models.ErrNotAssigned {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = s.slaStorage.AddEscalationTx(ctx, tx, models.SLAEscalation{
+		PullRequestID: d.PullRequest.PullRequestID,
+		TeamName:      d.TeamName,
+		Action:        models.SLAPolicyReassign,
+		ReviewerID:    reviewer,
+		NewReviewerID: newReviewer,
+		DueAt:         *d.PullRequest.SLADueAt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *SLAService) escalateByNotify(ctx context.Context, d models.SLADuePR, reviewers []string) error {
+	tx, err := s.prStorage.PRBeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	marked, err := s.slaStorage.MarkSLABreachedTx(ctx, tx, d.PullRequest.PullRequestID)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return nil
+	}
+
+	for _, reviewer := range reviewers {
+		err = s.slaStorage.AddEscalationTx(ctx, tx, models.SLAEscalation{
+			PullRequestID: d.PullRequest.PullRequestID,
+			TeamName:      d.TeamName,
+			Action:        models.SLAPolicyNotify,
+			ReviewerID:    reviewer,
+			DueAt:         *d.PullRequest.SLADueAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	before := d.PullRequest
+	d.PullRequest.SLABreached = true
+	err = recordEvent(ctx, tx, s.outbox, models.EventSLABreached, models.ReviewEvent{
+		PullRequest: &d.PullRequest,
+		TeamName:    d.TeamName,
+		Reviewers:   reviewers,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityPR, d.PullRequest.PullRequestID, "sla_breached", before, d.PullRequest); err != nil {
+		return err
+	}
+
+	if err := recordTimeline(ctx, tx, s.timeline, d.PullRequest.PullRequestID, models.TimelineEvent{Event: models.TimelineSLABreached}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// slaDeadline считает срок ответа от момента from. Без рабочего времени -
+// просто from + ResponseHours, иначе часы набираются только в рабочие
+// интервалы с понедельника по пятницу в часовом поясе команды.
+func slaDeadline(sla models.TeamSLA, from time.Time) (time.Time, error) {
+	total := time.Duration(sla.ResponseHours) * time.Hour
+	if sla.WorkStart == "" && sla.WorkEnd == "" {
+		return from.Add(total), nil
+	}
+
+	loc, err := time.LoadLocation(sla.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: unknown timezone %q", models.ErrInvalidSLA, sla.Timezone)
+	}
+	start, err := time.Parse("15:04", sla.WorkStart)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: work_start must be HH:MM", models.ErrInvalidSLA)
+	}
+	end, err := time.Parse("15:04", sla.WorkEnd)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: work_end must be HH:MM", models.ErrInvalidSLA)
+	}
+	if !end.After(start) {
+		return time.Time{}, fmt.Errorf("%w: work_end must be after work_start", models.ErrInvalidSLA)
+	}
+
+	t := from.In(loc)
+	remaining := total
+	for {
+		y, m, d := t.Date()
+		dayStart := time.Date(y, m, d, start.Hour(), start.Minute(), 0, 0, loc)
+		dayEnd := time.Date(y, m, d, end.Hour(), end.Minute(), 0, 0, loc)
+		nextDay := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday || !t.Before(dayEnd) {
+			t = nextDay
+			continue
+		}
+		if t.Before(dayStart) {
+			t = dayStart
+		}
+
+		available := dayEnd.Sub(t)
+		if remaining <= available {
+			return t.Add(remaining), nil
+		}
+		remaining -= available
+		t = nextDay
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	committed bool
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error { return nil }
+
+type fakeSLAPRs struct {
+	storage.PullReqStorage
+	clearedSLA []string
+}
+
+func (f *fakeSLAPRs) UpdatePRSLATx(ctx context.Context, tx pgx.Tx, prID string, dueAt *time.Time) error {
+	if dueAt == nil {
+		f.clearedSLA = append(f.clearedSLA, prID)
+	}
+	return nil
+}
+
+func (f *fakeSLAPRs) PRBeginTx(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+type fakeOutbox struct {
+	storage.OutboxStorage
+	events []models.Event
+}
+
+func (f *fakeOutbox) AddEventTx(ctx context.Context, tx pgx.Tx, event models.Event) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+type fakeSLAStorage struct {
+	storage.SLAStorage
+	slas        map[string]models.TeamSLA
+	due         []models.SLADuePR
+	breached    map[string]bool
+	escalations []models.SLAEscalation
+	escalateTxs []pgx.Tx
+}
+
+func (f *fakeSLAStorage) GetTeamSLATx(ctx context.Context, tx pgx.Tx, teamName string) (*models.TeamSLA, error) {
+	sla, ok := f.slas[teamName]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &sla, nil
+}
+
+func (f *fakeSLAStorage) ListSLADueTx(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.SLADuePR, error) {
+	return f.due, nil
+}
+
+func (f *fakeSLAStorage) MarkSLABreachedTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error) {
+	if f.breached[prID] {
+		return false, nil
+	}
+	f.breached[prID] = true
+	return true, nil
+}
+
+func (f *fakeSLAStorage) AddEscalationTx(ctx context.Context, tx pgx.Tx, e models.SLAEscalation) error {
+	f.escalations = append(f.escalations, e)
+	f.escalateTxs = append(f.escalateTxs, tx)
+	return nil
+}
+
+type fakeReassigner struct {
+	PullRequestManager
+	replacements map[string]string
+	calls        []models.ReassignRequest
+}
+
+func (f *fakeReassigner) ReassignReviewer(ctx context.Context, req models.ReassignRequest) (*models.PullRequest, string, error) {
+	f.calls = append(f.calls, req)
+	next, ok := f.replacements[req.OldUserID]
+	if !ok {
+		return nil, "", models.ErrNoCandidate
+	}
+	return &models.PullRequest{PullRequestID: req.PullRequestID}, next, nil
+}
+
+func newTestSLA(policy string, replacements map[string]string, decisions ...models.TimelineEvent) (*SLAService, *fakeSLAStorage, *fakeReassigner, *fakeOutbox) {
+	due := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := &fakeSLAStorage{
+		slas: map[string]models.TeamSLA{"backend": {TeamName: "backend", ResponseHours: 8, Policy: policy}},
+		due: []models.SLADuePR{{
+			PullRequest: models.PullRequest{
+				PullRequestID:     "pr-1",
+				AuthorID:          "u1",
+				Status:            "OPEN",
+				AssignedReviewers: []string{"u2", "u3"},
+				SLADueAt:          &due,
+			},
+			TeamName: "backend",
+		}},
+		breached: map[string]bool{},
+	}
+	prManager := &fakeReassigner{replacements: replacements}
+	outbox := &fakeOutbox{}
+	svc := NewSLAService(store, &fakeSLAPRs{}, nil, prManager, outbox, &fakeAudit{}, &fakeTimeline{events: decisions}, nil, time.Minute)
+	return svc, store, prManager, outbox
+}
+
+func TestSLAService_NotifyPolicy(t *testing.T) {
+	svc, store, prManager, outbox := newTestSLA(models.SLAPolicyNotify, nil)
+
+	require.NoError(t, svc.CheckSLA(context.Background()))
+
+	assert.Empty(t, prManager.calls)
+	assert.True(t, store.breached["pr-1"])
+	require.Len(t, store.escalations, 2)
+	assert.Equal(t, models.SLAPolicyNotify, store.escalations[0].Action)
+	assert.Equal(t, "u2", store.escalations[0].ReviewerID)
+
+	require.Len(t, outbox.events, 1)
+	assert.Equal(t, models.EventSLABreached, outbox.events[0].Type)
+	var review models.ReviewEvent
+	require.NoError(t, json.Unmarshal(outbox.events[0].Data, &review))
+	assert.Equal(t, []string{"u2", "u3"}, review.Reviewers)
+	assert.True(t, review.PullRequest.SLABreached)
+
+	// повторная проверка того же срока не эскалирует второй раз
+	require.NoError(t, svc.CheckSLA(context.Background()))
+	assert.Len(t, store.escalations, 2)
+	assert.Len(t, outbox.events, 1)
+}
+
+func TestSLAService_ReassignPolicy(t *testing.T) {
+	svc, store, prManager, outbox := newTestSLA(models.SLAPolicyReassign, map[string]string{"u2": "u4", "u3": "u5"})
+
+	require.NoError(t, svc.CheckSLA(context.Background()))
+
+	require.Len(t, prManager.calls, 2)
+	assert.Equal(t, models.TimelineReasonSLA, prManager.calls[0].Reason)
+	assert.Empty(t, outbox.events)
+	assert.False(t, store.breached["pr-1"])
+	require.Len(t, store.escalations, 2)
+	assert.Equal(t, models.SLAEscalation{
+		PullRequestID: "pr-1",
+		TeamName:      "backend",
+		Action:        models.SLAPolicyReassign,
+		ReviewerID:    "u2",
+		NewReviewerID: "u4",
+		DueAt:         *store.due[0].PullRequest.SLADueAt,
+	}, store.escalations[0])
+	// эскалация пишется в транзакции переназначения
+	for _, tx := range store.escalateTxs {
+		require.NotNil(t, tx)
+		assert.True(t, tx.(*fakeTx).committed)
+	}
+}
+
+func TestSLAService_SkipsReviewersWhoActed(t *testing.T) {
+	svc, store, prManager, outbox := newTestSLA(models.SLAPolicyReassign, map[string]string{"u2": "u4", "u3": "u5"},
+		models.TimelineEvent{PullRequestID: "pr-1", Event: models.TimelineReviewerAssigned, UserID: "u2"},
+		models.TimelineEvent{PullRequestID: "pr-1", Event: models.TimelineApproved, UserID: "u2"},
+	)
+
+	require.NoError(t, svc.CheckSLA(context.Background()))
+
+	require.Len(t, prManager.calls, 1)
+	assert.Equal(t, "u3", prManager.calls[0].OldUserID)
+	require.Len(t, store.escalations, 1)
+	assert.Equal(t, "u3", store.escalations[0].ReviewerID)
+	assert.Empty(t, outbox.events)
+}
+
+func TestSLAService_AllReviewersActed(t *testing.T) {
+	svc, store, prManager, outbox := newTestSLA(models.SLAPolicyNotify, nil,
+		models.TimelineEvent{PullRequestID: "pr-1", Event: models.TimelineApproved, UserID: "u2"},
+		models.TimelineEvent{PullRequestID: "pr-1", Event: models.TimelineChangesRequested, UserID: "u3"},
+	)
+
+	require.NoError(t, svc.CheckSLA(context.Background()))
+
+	assert.Empty(t, prManager.calls)
+	assert.Empty(t, store.escalations)
+	assert.Empty(t, outbox.events)
+	assert.Equal(t, []string{"pr-1"}, svc.prStorage.(*fakeSLAPRs).clearedSLA)
+}
+
+func TestSLAService_ReassignFallsBackToNotify(t *testing.T) {
+	svc, store, prManager, outbox := newTestSLA(models.SLAPolicyReassign, nil)
+
+	require.NoError(t, svc.CheckSLA(context.Background()))
+
+	assert.Len(t, prManager.calls, 2)
+	assert.True(t, store.breached["pr-1"])
+	require.Len(t, outbox.events, 1)
+	assert.Equal(t, models.EventSLABreached, outbox.events[0].Type)
+}
+
+func TestSLADeadline_WorkingHours(t *testing.T) {
+	sla := models.TeamSLA{ResponseHours: 8, WorkStart: "09:00", WorkEnd: "18:00", Timezone: "UTC"}
+
+	// пятница 16:00 - 2 часа в пятницу, остальные 6 в понедельник
+	friday := time.Date(2025, 3, 7, 16, 0, 0, 0, time.UTC)
+	due, err := slaDeadline(sla, friday)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), due)
+
+	// до начала рабочего дня - отсчёт с 09:00
+	early := time.Date(2025, 3, 10, 7, 30, 0, 0, time.UTC)
+	due, err = slaDeadline(sla, early)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 10, 17, 0, 0, 0, time.UTC), due)
+
+	// в выходной - с понедельника
+	sunday := time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC)
+	due, err = slaDeadline(models.TeamSLA{ResponseHours: 18, WorkStart: "09:00", WorkEnd: "18:00", Timezone: "UTC"}, sunday)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 11, 18, 0, 0, 0, time.UTC), due)
+}
+
+func TestSLADeadline_RoundTheClock(t *testing.T) {
+	from := time.Date(2025, 3, 8, 22, 0, 0, 0, time.UTC)
+	due, err := slaDeadline(models.TeamSLA{ResponseHours: 4}, from)
+	require.NoError(t, err)
+	assert.Equal(t, from.Add(4*time.Hour), due)
+}
+
+func TestSLADeadline_Invalid(t *testing.T) {
+	_, err := slaDeadline(models.TeamSLA{ResponseHours: 1, WorkStart: "18:00", WorkEnd: "09:00", Timezone: "UTC"}, time.Now())
+	assert.ErrorIs(t, err, models.ErrInvalidSLA)
+
+	_, err = slaDeadline(models.TeamSLA{ResponseHours: 1, WorkStart: "09:00", WorkEnd: "18:00", Timezone: "Mars/Olympus"}, time.Now())
+	assert.ErrorIs(t, err, models.ErrInvalidSLA)
+}
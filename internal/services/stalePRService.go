@@ -0,0 +1,221 @@
+package services
+
+/*
+Устаревшие PR:
+	1. Политика команды: через StaleAfterDays дней без изменений - stale,
+	   ещё через CloseAfterDays - CLOSED
+	2. План действий на текущий момент (dry-run, ничего не меняет)
+	3. Фоновое применение плана
+
+Пометка stale пишет событие pr.stale (чат уведомляет автора), закрытие -
+pr.closed со списком освобождённых ревьюеров. Любое изменение PR сдвигает
+updated_at, смена ревьюеров снимает отметку stale.
+*/
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"time"
+)
+
+type StalePRService struct {
+	staleStorage storage.StaleStorage
+	prStorage    storage.PullReqStorage
+	teamStorage  storage.TeamStorage
+	outbox       storage.OutboxStorage
+	audit        storage.AuditStorage
+	timeline     storage.TimelineStorage
+	authz        *Authorizer
+	interval     time.Duration
+	batchSize    int
+	now          func() time.Time
+}
+
+func NewStalePRService(
+	staleStorage storage.StaleStorage,
+	prStorage storage.PullReqStorage,
+	teamStorage storage.TeamStorage,
+	outbox storage.OutboxStorage,
+	audit storage.AuditStorage,
+	timeline storage.TimelineStorage,
+	authz *Authorizer,
+	interval time.Duration,
+) *StalePRService {
+	return &StalePRService{
+		staleStorage: staleStorage,
+		prStorage:    prStorage,
+		teamStorage:  teamStorage,
+		outbox:       outbox,
+		audit:        audit,
+		timeline:     timeline,
+		authz:        authz,
+		interval:     interval,
+		batchSize:    100,
+		now:          time.Now,
+	}
+}
+
+func (s *StalePRService) SetPolicy(ctx context.Context, policy models.StalePolicy) (*models.StalePolicy, error) {
+	if policy.StaleAfterDays <= 0 {
+		return nil, fmt.Errorf("%w: stale_after_days must be positive", models.ErrInvalidStalePolicy)
+	}
+	if policy.CloseAfterDays < 0 {
+		return nil, fmt.Errorf("%w: close_after_days must not be negative", models.ErrInvalidStalePolicy)
+	}
+
+	if _, err := s.teamStorage.GetTeamInfoTx(ctx, nil, policy.TeamName); err != nil {
+		return nil, err
+	}
+	if err := s.authz.RequireTeamLead(ctx, nil, policy.TeamName); err != nil {
+		return nil, err
+	}
+
+	if err := s.staleStorage.SetStalePolicyTx(ctx, nil, policy); err != nil {
+		return nil, err
+	}
+	return s.staleStorage.GetStalePolicyTx(ctx, nil, policy.TeamName)
+}
+
+func (s *StalePRService) GetPolicy(ctx context.Context, teamName string) (*models.StalePolicy, error) {
+	return s.staleStorage.GetStalePolicyTx(ctx, nil, teamName)
+}
+
+// Plan - что политика сделала бы прямо сейчас, не больше batchSize
+// действий. Если кандидатов больше, план помечается как усечённый.
+func (s *StalePRService) Plan(ctx context.Context) (*models.StalePlan, error) {
+	candidates, err := s.staleStorage.ListStaleCandidatesTx(ctx, nil, s.now(), s.batchSize+1)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &models.StalePlan{Actions: make([]models.StaleAction, 0, len(candidates))}
+	if len(candidates) > s.batchSize {
+		candidates = candidates[:s.batchSize]
+		plan.Truncated = true
+	}
+
+	for _, c := range candidates {
+		action := models.StaleActionMark
+		if c.PullRequest.StaleAt != nil {
+			action = models.StaleActionClose
+		}
+		plan.Actions = append(plan.Actions, models.StaleAction{
+			Action:            action,
+			PullRequestID:     c.PullRequest.PullRequestID,
+			PullRequestName:   c.PullRequest.PullRequestName,
+			AuthorID:          c.PullRequest.AuthorID,
+			TeamName:          c.TeamName,
+			AssignedReviewers: c.PullRequest.AssignedReviewers,
+			UpdatedAt:         c.PullRequest.UpdatedAt,
+			StaleAt:           c.PullRequest.StaleAt,
+		})
+	}
+	return plan, nil
+}
+
+// Run крутится до отмены ctx
+func (s *StalePRService) Run(ctx context.Context) {
+	ctx = WithAuditActor(SystemContext(ctx, "stale"), auditSystemActor)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Apply(ctx); err != nil {
+				slog.Error("Stale PR check failed", "error", err)
+			}
+		}
+	}
+}
+
+// Apply выполняет план пачками по batchSize, пока кандидаты не кончатся,
+// и возвращает реально применённые действия. Проход, в котором ничего
+// не применилось, останавливает цикл - иначе упавшие PR крутились бы вечно.
+func (s *StalePRService) Apply(ctx context.Context) ([]models.StaleAction, error) {
+	var applied []models.StaleAction
+	for {
+		plan, err := s.Plan(ctx)
+		if err != nil {
+			return applied, err
+		}
+
+		progressed := false
+		for _, action := range plan.Actions {
+			ok, err := s.apply(ctx, action)
+			if err != nil {
+				slog.Error("Failed to apply stale policy", "pull_request_id", action.PullRequestID, "action", action.Action, "error", err)
+				continue
+			}
+			if ok {
+				applied = append(applied, action)
+				progressed = true
+			}
+		}
+
+		if !plan.Truncated || !progressed {
+			return applied, nil
+		}
+	}
+}
+
+func (s *StalePRService) apply(ctx context.Context, action models.StaleAction) (bool, error) {
+	tx, err := s.prStorage.PRBeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := s.prStorage.GetPRByIDTx(ctx, tx, action.PullRequestID)
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+	var eventType, auditAction string
+	var timeline []models.TimelineEvent
+	switch action.Action {
+	case models.StaleActionMark:
+		ok, err = s.staleStorage.MarkPRStaleTx(ctx, tx, action.PullRequestID, *action.UpdatedAt)
+		eventType, auditAction = models.EventPRStale, models.TimelineMarkedStale
+		timeline = []models.TimelineEvent{{Event: models.TimelineMarkedStale}}
+	case models.StaleActionClose:
+		ok, err = s.staleStorage.ClosePRTx(ctx, tx, action.PullRequestID)
+		eventType, auditAction = models.EventPRClosed, models.TimelineClosed
+		timeline = append(reviewerChanges(before.AssignedReviewers, nil, models.TimelineReasonClosed), models.TimelineEvent{Event: models.TimelineClosed})
+	}
+	if err != nil || !ok {
+		return false, err
+	}
+
+	pr, err := s.prStorage.GetPRByIDTx(ctx, tx, action.PullRequestID)
+	if err != nil {
+		return false, err
+	}
+
+	err = recordEvent(ctx, tx, s.outbox, eventType, models.ReviewEvent{
+		PullRequest: pr,
+		TeamName:    action.TeamName,
+		Reviewers:   action.AssignedReviewers,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityPR, action.PullRequestID, auditAction, before, pr); err != nil {
+		return false, err
+	}
+
+	if err := recordTimeline(ctx, tx, s.timeline, action.PullRequestID, timeline...); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
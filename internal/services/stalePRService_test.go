@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStaleStorage struct {
+	storage.StaleStorage
+	candidates []models.StaleCandidate
+	marked     []string
+	closed     []string
+}
+
+// ListStaleCandidatesTx отдаёт ещё не обработанных кандидатов, как таблица
+func (f *fakeStaleStorage) ListStaleCandidatesTx(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.StaleCandidate, error) {
+	var result []models.StaleCandidate
+	for _, c := range f.candidates {
+		if contains(f.marked, c.PullRequest.PullRequestID) || contains(f.closed, c.PullRequest.PullRequestID) {
+			continue
+		}
+		if len(result) == limit {
+			break
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (f *fakeStaleStorage) MarkPRStaleTx(ctx context.Context, tx pgx.Tx, prID string, updatedAt time.Time) (bool, error) {
+	f.marked = append(f.marked, prID)
+	return true, nil
+}
+
+func (f *fakeStaleStorage) ClosePRTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error) {
+	f.closed = append(f.closed, prID)
+	return true, nil
+}
+
+type fakeStalePRs struct {
+	fakeSLAPRs
+}
+
+func (f *fakeStalePRs) GetPRByIDTx(ctx context.Context, tx pgx.Tx, prID string) (*models.PullRequest, error) {
+	return &models.PullRequest{PullRequestID: prID, AuthorID: "u1"}, nil
+}
+
+func newTestStale() (*StalePRService, *fakeStaleStorage, *fakeOutbox) {
+	updated := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	stale := time.Date(2025, 2, 20, 10, 0, 0, 0, time.UTC)
+	policy := models.StalePolicy{TeamName: "backend", StaleAfterDays: 14, CloseAfterDays: 7}
+
+	store := &fakeStaleStorage{candidates: []models.StaleCandidate{
+		{
+			PullRequest: models.PullRequest{PullRequestID: "pr-1", AuthorID: "u1", Status: "OPEN", AssignedReviewers: []string{"u2"}, UpdatedAt: &updated},
+			TeamName:    "backend",
+			Policy:      policy,
+		},
+		{
+			PullRequest: models.PullRequest{PullRequestID: "pr-2", AuthorID: "u1", Status: "OPEN", AssignedReviewers: []string{"u2", "u3"}, UpdatedAt: &updated, StaleAt: &stale},
+			TeamName:    "backend",
+			Policy:      policy,
+		},
+	}}
+	outbox := &fakeOutbox{}
+	svc := NewStalePRService(store, &fakeStalePRs{}, nil, outbox, &fakeAudit{}, &fakeTimeline{}, nil, time.Hour)
+	return svc, store, outbox
+}
+
+func TestStalePRService_PlanDoesNotChangeAnything(t *testing.T) {
+	svc, store, outbox := newTestStale()
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+
+	assert.False(t, plan.Truncated)
+	actions := plan.Actions
+	require.Len(t, actions, 2)
+	assert.Equal(t, models.StaleActionMark, actions[0].Action)
+	assert.Equal(t, "pr-1", actions[0].PullRequestID)
+	assert.Equal(t, models.StaleActionClose, actions[1].Action)
+	assert.Equal(t, []string{"u2", "u3"}, actions[1].AssignedReviewers)
+
+	assert.Empty(t, store.marked)
+	assert.Empty(t, store.closed)
+	assert.Empty(t, outbox.events)
+}
+
+func TestStalePRService_Apply(t *testing.T) {
+	svc, store, outbox := newTestStale()
+
+	applied, err := svc.Apply(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, applied, 2)
+	assert.Equal(t, []string{"pr-1"}, store.marked)
+	assert.Equal(t, []string{"pr-2"}, store.closed)
+
+	require.Len(t, outbox.events, 2)
+	assert.Equal(t, models.EventPRStale, outbox.events[0].Type)
+	assert.Equal(t, models.EventPRClosed, outbox.events[1].Type)
+
+	var closed models.ReviewEvent
+	require.NoError(t, json.Unmarshal(outbox.events[1].Data, &closed))
+	assert.Equal(t, []string{"u2", "u3"}, closed.Reviewers)
+}
+
+func TestStalePRService_PlanTruncatedAndApplyPaginates(t *testing.T) {
+	svc, store, _ := newTestStale()
+	svc.batchSize = 1
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+	assert.True(t, plan.Truncated)
+	require.Len(t, plan.Actions, 1)
+
+	applied, err := svc.Apply(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.Equal(t, []string{"pr-1"}, store.marked)
+	assert.Equal(t, []string{"pr-2"}, store.closed)
+}
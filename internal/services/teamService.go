@@ -3,6 +3,14 @@ package services
 Функции:
 	1. Создание команды
 	2. Получение информации о комнаде 
+	   (участники постранично - фильтр по активности, сортировка, курсор)
+	3. Загрузка CODEOWNERS команды
+	4. Состав команды: лид добавляет участников и убирает их в команду
+	   по умолчанию (та же, что у SCIM)
+
+Ушедший из команды (убран или перенесён в другую) и деактивированный при
+добавлении снимаются с открытых ревью после коммита - так же, как при
+деактивации в UserService.
 
 Фича - указываем в GetTeamInfoTx nil вместо индекса, он автоматом выполняется через
 пул
@@ -11,25 +19,50 @@ import (
 	"context"
 	"test-task/internal/models"
 	"test-task/internal/storage"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TeamService struct {
-	storage storage.TeamStorage
+	storage           storage.TeamStorage
+	userStorage       storage.UserStorage
+	codeOwnersStorage storage.CodeOwnersStorage
+	outbox            storage.OutboxStorage
+	audit             storage.AuditStorage
+	authz             *Authorizer
+	prStorage         storage.PullReqStorage
+	prManager         PullRequestManager
+	defaultTeam       string
 }
 
-func NewTeamService(storage storage.TeamStorage) *TeamService {
+func NewTeamService(storage storage.TeamStorage, userStorage storage.UserStorage, codeOwnersStorage storage.CodeOwnersStorage, outbox storage.OutboxStorage, audit storage.AuditStorage, authz *Authorizer, prStorage storage.PullReqStorage, prManager PullRequestManager, defaultTeam string) *TeamService {
 	return &TeamService{
-		storage: storage,
+		storage:           storage,
+		userStorage:       userStorage,
+		codeOwnersStorage: codeOwnersStorage,
+		outbox:            outbox,
+		audit:             audit,
+		authz:             authz,
+		prStorage:         prStorage,
+		prManager:         prManager,
+		defaultTeam:       defaultTeam,
 	}
 }
 
-func (s *TeamService) CreateTeam(ctx context.Context, team models.Team) (*models.Team, error) {
+func (s *TeamService) CreateTeam(ctx context.Context, team models.Team) (_ *models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.CreateTeam", attribute.String("team.name", team.TeamName), attribute.Int("team.members", len(team.Members)))
+	defer func() { endSpan(span, err) }()
+
 	tx, err := s.storage.TeamBeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
+	if err := s.authz.RequireAdmin(ctx, tx); err != nil {
+		return nil, err
+	}
+
 	err = s.storage.CreateTeamTx(ctx, tx, team)
 	if err != nil {
 		return nil, err
@@ -40,6 +73,14 @@ func (s *TeamService) CreateTeam(ctx context.Context, team models.Team) (*models
 		return nil, err
 	}
 
+	if err := recordEvent(ctx, tx, s.outbox, models.EventTeamCreated, createdTeam); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityTeam, team.TeamName, "created", nil, createdTeam); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
@@ -47,12 +88,229 @@ func (s *TeamService) CreateTeam(ctx context.Context, team models.Team) (*models
 	return createdTeam, nil
 }
 
-func (s *TeamService) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
+// AddMember - лид или админ добавляет пользователя в команду. Забрать
+// участника другой команды (кроме команды по умолчанию) может только тот,
+// кто управляет и ей.
+func (s *TeamService) AddMember(ctx context.Context, req models.AddTeamMemberRequest) (_ *models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.AddMember", attribute.String("team.name", req.TeamName), attribute.String("user.id", req.Member.UserID))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.storage.TeamBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	exists, err := s.storage.TeamExistsTx(ctx, tx, req.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.ErrNotFound
+	}
+	if err := s.authz.RequireTeamLead(ctx, tx, req.TeamName); err != nil {
+		return nil, err
+	}
+
+	// у команды без участников GetTeamInfoTx ничего не находит
+	before, err := s.storage.GetTeamInfoTx(ctx, tx, req.TeamName)
+	if err != nil && err != models.ErrNotFound {
+		return nil, err
+	}
+	if before == nil {
+		before = &models.Team{TeamName: req.TeamName, Members: []models.User{}}
+	}
+
+	existing, err := s.userStorage.GetUserTx(ctx, tx, req.Member.UserID)
+	if err != nil && err != models.ErrNotFound {
+		return nil, err
+	}
+	if existing != nil && existing.TeamName != req.TeamName && existing.TeamName != s.defaultTeam {
+		if err := s.authz.RequireTeamLead(ctx, tx, existing.TeamName); err != nil {
+			return nil, err
+		}
+	}
+
+	member := models.User{
+		UserID:    req.Member.UserID,
+		Username:  req.Member.Username,
+		TeamName:  req.TeamName,
+		IsActive:  true,
+		Skills:    req.Member.Skills,
+		PathGlobs: req.Member.PathGlobs,
+		Email:     req.Member.Email,
+	}
+	if existing != nil {
+		member.IsActive = existing.IsActive
+		if member.Username == "" {
+			member.Username = existing.Username
+		}
+	}
+	if req.Member.IsActive != nil {
+		member.IsActive = *req.Member.IsActive
+	}
+	if err := s.storage.AddTeamMemberTx(ctx, tx, member); err != nil {
+		return nil, err
+	}
+
+	after, err := s.storage.GetTeamInfoTx(ctx, tx, req.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityTeam, req.TeamName, "member_added", before, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		switch {
+		case existing.IsActive && !member.IsActive:
+			reassignOpenReviews(ctx, s.prStorage, s.prManager, member.UserID, models.TimelineReasonDeactivation)
+		case existing.TeamName != req.TeamName:
+			reassignOpenReviews(ctx, s.prStorage, s.prManager, member.UserID, models.TimelineReasonTeamChange)
+		}
+	}
+
+	return after, nil
+}
+
+// RemoveMember - лид или админ переносит участника в команду по умолчанию
+func (s *TeamService) RemoveMember(ctx context.Context, req models.RemoveTeamMemberRequest) (_ *models.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.RemoveMember", attribute.String("team.name", req.TeamName), attribute.String("user.id", req.UserID))
+	defer func() { endSpan(span, err) }()
+
+	if req.TeamName == s.defaultTeam {
+		return nil, models.ErrInvalidTeamMember
+	}
+
+	tx, err := s.storage.TeamBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := s.storage.GetTeamInfoTx(ctx, tx, req.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.RequireTeamLead(ctx, tx, req.TeamName); err != nil {
+		return nil, err
+	}
+
+	moved, err := s.storage.MoveTeamMemberTx(ctx, tx, req.UserID, req.TeamName, s.defaultTeam)
+	if err != nil {
+		return nil, err
+	}
+	if !moved {
+		return nil, models.ErrNotFound
+	}
+
+	after, err := s.storage.GetTeamInfoTx(ctx, tx, req.TeamName)
+	if err != nil && err != models.ErrNotFound {
+		return nil, err
+	}
+	if after == nil {
+		after = &models.Team{TeamName: req.TeamName, Members: []models.User{}}
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityTeam, req.TeamName, "member_removed", before, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	reassignOpenReviews(ctx, s.prStorage, s.prManager, req.UserID, models.TimelineReasonTeamChange)
+
+	return after, nil
+}
+
+func (s *TeamService) GetTeam(ctx context.Context, teamName string, q models.UserListQuery) (_ *models.TeamPage, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetTeam", attribute.String("team.name", teamName))
+	defer func() { endSpan(span, err) }()
 
-	team, err := s.storage.GetTeamInfoTx(ctx, nil, teamName)
+	if err := prepareUserQuery(&q); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.storage.TeamExistsTx(ctx, nil, teamName)
 	if err != nil {
 		return nil, err
 	}
+	if !exists {
+		return nil, models.ErrNotFound
+	}
+
+	q.TeamName = teamName
+	limit := q.Limit
+	q.Limit++
+	users, err := s.userStorage.ListUsersPageTx(ctx, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	q.Limit = limit
+
+	page := userPage(users, q)
+	return &models.TeamPage{TeamName: teamName, Members: page.Users, NextCursor: page.NextCursor}, nil
+}
+
+func (s *TeamService) SetCodeOwners(ctx context.Context, teamName string, content string) (_ *models.CodeOwners, err error) {
+	ctx, span := startSpan(ctx, "TeamService.SetCodeOwners", attribute.String("team.name", teamName))
+	defer func() { endSpan(span, err) }()
+
+	rules, err := ParseCodeOwners(content)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.storage.TeamBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := s.storage.GetTeamInfoTx(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+	if err := s.authz.RequireTeamLead(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	previous, err := s.codeOwnersStorage.GetCodeOwnersTx(ctx, tx, teamName)
+	if err != nil && err != models.ErrNotFound {
+		return nil, err
+	}
+
+	err = s.codeOwnersStorage.SaveCodeOwnersTx(ctx, tx, models.CodeOwners{
+		TeamName: teamName,
+		Content:  content,
+		Rules:    rules,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.codeOwnersStorage.GetCodeOwnersTx(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	var before interface{}
+	if previous != nil {
+		before = previous
+	}
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityTeam, teamName, "codeowners_updated", before, saved); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 
-	return team, nil
+	return saved, nil
 }
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTeams хранит состав команд в тех же пользователях, что видит Authorizer
+type fakeTeams struct {
+	storage.TeamStorage
+	users *fakeRoleUsers
+	teams map[string]bool
+}
+
+func (f *fakeTeams) TeamBeginTx(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+func (f *fakeTeams) TeamExistsTx(ctx context.Context, tx pgx.Tx, teamName string) (bool, error) {
+	return f.teams[teamName], nil
+}
+
+func (f *fakeTeams) GetTeamInfoTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.Team, error) {
+	team := &models.Team{TeamName: teamName}
+	for _, u := range f.users.users {
+		if u.TeamName == teamName {
+			team.Members = append(team.Members, u)
+		}
+	}
+	if len(team.Members) == 0 {
+		return nil, models.ErrNotFound
+	}
+	sort.Slice(team.Members, func(i, j int) bool { return team.Members[i].UserID < team.Members[j].UserID })
+	return team, nil
+}
+
+func (f *fakeTeams) AddTeamMemberTx(ctx context.Context, tx pgx.Tx, member models.User) error {
+	if existing, ok := f.users.users[member.UserID]; ok {
+		member.Role = existing.Role
+	}
+	f.users.users[member.UserID] = member
+	return nil
+}
+
+func (f *fakeTeams) MoveTeamMemberTx(ctx context.Context, tx pgx.Tx, userID string, fromTeam string, toTeam string) (bool, error) {
+	u, ok := f.users.users[userID]
+	if !ok || u.TeamName != fromTeam {
+		return false, nil
+	}
+	u.TeamName = toTeam
+	if u.Role == models.RoleLead {
+		u.Role = models.RoleMember
+	}
+	f.users.users[userID] = u
+	f.teams[toTeam] = true
+	return true, nil
+}
+
+func newTestTeams() (*TeamService, *fakeRoleUsers, *fakeAudit, *fakeReassigner) {
+	users := &fakeRoleUsers{users: map[string]models.User{
+		"admin": {UserID: "admin", TeamName: "ops", Role: models.RoleAdmin},
+		"lead":  {UserID: "lead", TeamName: "backend", Role: models.RoleLead},
+		"lead2": {UserID: "lead2", TeamName: "frontend", Role: models.RoleLead},
+		"u1":    {UserID: "u1", TeamName: "backend", Role: models.RoleMember, IsActive: true},
+		"u2":    {UserID: "u2", TeamName: "frontend", Role: models.RoleMember, IsActive: true},
+		"new":   {UserID: "new", Username: "Newcomer", TeamName: "unassigned", Role: models.RoleMember, IsActive: true},
+	}}
+	teams := &fakeTeams{users: users, teams: map[string]bool{"ops": true, "backend": true, "frontend": true, "unassigned": true}}
+	audit := &fakeAudit{}
+	prs := &fakeDigestPRs{byReviewer: map[string][]models.PullRequestShort{
+		"u1": {{PullRequestID: "pr-1", Status: "OPEN"}, {PullRequestID: "pr-2", Status: "MERGED"}},
+		"u2": {{PullRequestID: "pr-3", Status: "OPEN"}},
+	}}
+	reassigner := &fakeReassigner{replacements: map[string]string{"u1": "lead", "u2": "lead2"}}
+	svc := NewTeamService(teams, users, nil, nil, audit, NewAuthorizer(users), prs, reassigner, "unassigned")
+	return svc, users, audit, reassigner
+}
+
+func TestTeamService_LeadManagesMembers(t *testing.T) {
+	svc, users, audit, reassigner := newTestTeams()
+
+	team, err := svc.AddMember(asUser("lead"), models.AddTeamMemberRequest{
+		TeamName: "backend",
+		Member:   models.TeamMemberInput{UserID: "new"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, team.Members, 3)
+	assert.Equal(t, "backend", users.users["new"].TeamName)
+	assert.Equal(t, "Newcomer", users.users["new"].Username)
+
+	team, err = svc.RemoveMember(asUser("lead"), models.RemoveTeamMemberRequest{TeamName: "backend", UserID: "u1"})
+	require.NoError(t, err)
+	assert.Len(t, team.Members, 2)
+	assert.Equal(t, "unassigned", users.users["u1"].TeamName)
+
+	// открытые ревью ушедшего не блокируют PR бывшей команды
+	assert.Equal(t, []models.ReassignRequest{{PullRequestID: "pr-1", OldUserID: "u1", Reason: models.TimelineReasonTeamChange}}, reassigner.calls)
+
+	require.Len(t, audit.entries, 2)
+	assert.Equal(t, "member_added", audit.entries[0].Action)
+	assert.Equal(t, "member_removed", audit.entries[1].Action)
+}
+
+func TestTeamService_MembershipRequiresLeadOfTeam(t *testing.T) {
+	svc, users, _, reassigner := newTestTeams()
+
+	// чужая команда
+	_, err := svc.AddMember(asUser("lead2"), models.AddTeamMemberRequest{TeamName: "backend", Member: models.TeamMemberInput{UserID: "new"}})
+	assert.Equal(t, models.ErrForbidden, err)
+	_, err = svc.RemoveMember(asUser("lead2"), models.RemoveTeamMemberRequest{TeamName: "backend", UserID: "u1"})
+	assert.Equal(t, models.ErrForbidden, err)
+
+	// участник своей команды - не лид
+	_, err = svc.AddMember(asUser("u1"), models.AddTeamMemberRequest{TeamName: "backend", Member: models.TeamMemberInput{UserID: "new"}})
+	assert.Equal(t, models.ErrForbidden, err)
+
+	// забрать участника другой команды может только тот, кто управляет и ей
+	_, err = svc.AddMember(asUser("lead"), models.AddTeamMemberRequest{TeamName: "backend", Member: models.TeamMemberInput{UserID: "u2"}})
+	assert.Equal(t, models.ErrForbidden, err)
+	assert.Equal(t, "frontend", users.users["u2"].TeamName)
+
+	assert.Empty(t, reassigner.calls)
+
+	_, err = svc.AddMember(asUser("admin"), models.AddTeamMemberRequest{TeamName: "backend", Member: models.TeamMemberInput{UserID: "u2"}})
+	require.NoError(t, err)
+	assert.Equal(t, "backend", users.users["u2"].TeamName)
+	assert.True(t, users.users["u2"].IsActive, "is_active omitted - stays active")
+	assert.Equal(t, []models.ReassignRequest{{PullRequestID: "pr-3", OldUserID: "u2", Reason: models.TimelineReasonTeamChange}}, reassigner.calls)
+}
+
+func TestTeamService_AddMemberIsActive(t *testing.T) {
+	svc, users, _, reassigner := newTestTeams()
+	inactive := false
+
+	_, err := svc.AddMember(asUser("lead"), models.AddTeamMemberRequest{TeamName: "backend", Member: models.TeamMemberInput{UserID: "fresh"}})
+	require.NoError(t, err)
+	assert.True(t, users.users["fresh"].IsActive, "new member is active by default")
+
+	_, err = svc.AddMember(asUser("lead"), models.AddTeamMemberRequest{TeamName: "backend", Member: models.TeamMemberInput{UserID: "u1", IsActive: &inactive}})
+	require.NoError(t, err)
+	assert.False(t, users.users["u1"].IsActive)
+	assert.Equal(t, []models.ReassignRequest{{PullRequestID: "pr-1", OldUserID: "u1", Reason: models.TimelineReasonDeactivation}}, reassigner.calls)
+}
+
+func TestTeamService_RemoveMember_Errors(t *testing.T) {
+	svc, _, _, _ := newTestTeams()
+
+	_, err := svc.RemoveMember(asUser("lead"), models.RemoveTeamMemberRequest{TeamName: "backend", UserID: "u2"})
+	assert.Equal(t, models.ErrNotFound, err)
+
+	_, err = svc.RemoveMember(asUser("admin"), models.RemoveTeamMemberRequest{TeamName: "unassigned", UserID: "new"})
+	assert.Equal(t, models.ErrInvalidTeamMember, err)
+
+	_, err = svc.AddMember(asUser("admin"), models.AddTeamMemberRequest{TeamName: "ghost", Member: models.TeamMemberInput{UserID: "new"}})
+	assert.Equal(t, models.ErrNotFound, err)
+}
@@ -0,0 +1,39 @@
+package services
+
+/*
+Хронология PR: создание, каждое назначение и снятие ревьюера с причиной,
+нарушение SLA, stale, merge и закрытие. Пишется в той же транзакции, что
+и само изменение; автор берётся из контекста, как в журнале аудита.
+*/
+import (
+	"context"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func recordTimeline(ctx context.Context, tx pgx.Tx, timeline storage.TimelineStorage, prID string, events ...models.TimelineEvent) error {
+	actor := auditActor(ctx)
+	for i := range events {
+		events[i].PullRequestID = prID
+		events[i].Actor = actor
+	}
+	return timeline.AddTimelineEventsTx(ctx, tx, events)
+}
+
+// reviewerChanges - снятые и назначенные ревьюеры при переходе от old к new
+func reviewerChanges(old []string, new []string, reason string) []models.TimelineEvent {
+	var events []models.TimelineEvent
+	for _, userID := range old {
+		if !contains(new, userID) {
+			events = append(events, models.TimelineEvent{Event: models.TimelineReviewerUnassigned, UserID: userID, Reason: reason})
+		}
+	}
+	for _, userID := range new {
+		if !contains(old, userID) {
+			events = append(events, models.TimelineEvent{Event: models.TimelineReviewerAssigned, UserID: userID, Reason: reason})
+		}
+	}
+	return events
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTimeline struct {
+	storage.TimelineStorage
+	events []models.TimelineEvent
+}
+
+func (f *fakeTimeline) AddTimelineEventsTx(ctx context.Context, tx pgx.Tx, events []models.TimelineEvent) error {
+	f.events = append(f.events, events...)
+	return nil
+}
+
+func (f *fakeTimeline) ListTimelineTx(ctx context.Context, tx pgx.Tx, prID string) ([]models.TimelineEvent, error) {
+	var events []models.TimelineEvent
+	for _, e := range f.events {
+		if e.PullRequestID == prID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func TestReviewerChanges(t *testing.T) {
+	events := reviewerChanges([]string{"u1", "u2"}, []string{"u3", "u2"}, models.TimelineReasonReassign)
+
+	assert.Equal(t, []models.TimelineEvent{
+		{Event: models.TimelineReviewerUnassigned, UserID: "u1", Reason: models.TimelineReasonReassign},
+		{Event: models.TimelineReviewerAssigned, UserID: "u3", Reason: models.TimelineReasonReassign},
+	}, events)
+
+	assert.Empty(t, reviewerChanges([]string{"u1"}, []string{"u1"}, models.TimelineReasonReassign))
+}
+
+type fakeReviewPRs struct {
+	storage.PullReqStorage
+	pr models.PullRequest
+}
+
+func (f *fakeReviewPRs) PRBeginTx(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+func (f *fakeReviewPRs) GetPRByIDTx(ctx context.Context, tx pgx.Tx, prID string) (*models.PullRequest, error) {
+	if prID != f.pr.PullRequestID {
+		return nil, models.ErrNotFound
+	}
+	pr := f.pr
+	return &pr, nil
+}
+
+func TestSubmitReview(t *testing.T) {
+	prs := &fakeReviewPRs{pr: models.PullRequest{PullRequestID: "pr-1", AuthorID: "author", Status: "OPEN", AssignedReviewers: []string{"rev"}}}
+	timeline := &fakeTimeline{}
+	authz := NewAuthorizer(&fakeRoleUsers{users: map[string]models.User{
+		"author": {UserID: "author", TeamName: "backend", Role: models.RoleMember},
+		"rev":    {UserID: "rev", TeamName: "backend", Role: models.RoleMember},
+		"other":  {UserID: "other", TeamName: "backend", Role: models.RoleMember},
+	}})
+	svc := NewPullRequestService(prs, nil, nil, nil, nil, nil, nil, timeline, authz)
+
+	_, err := svc.SubmitReview(asUser("rev"), models.ReviewDecisionRequest{PullRequestID: "pr-1", ReviewerID: "rev", Decision: models.ReviewDecisionApprove})
+	require.NoError(t, err)
+	_, err = svc.SubmitReview(asUser("rev"), models.ReviewDecisionRequest{PullRequestID: "pr-1", ReviewerID: "rev", Decision: models.ReviewDecisionRequestChanges})
+	require.NoError(t, err)
+
+	require.Len(t, timeline.events, 2)
+	assert.Equal(t, models.TimelineApproved, timeline.events[0].Event)
+	assert.Equal(t, "rev", timeline.events[0].UserID)
+	assert.Equal(t, "pr-1", timeline.events[0].PullRequestID)
+	assert.Equal(t, models.TimelineChangesRequested, timeline.events[1].Event)
+
+	_, err = svc.SubmitReview(asUser("rev"), models.ReviewDecisionRequest{PullRequestID: "pr-1", ReviewerID: "rev", Decision: "lgtm"})
+	assert.Equal(t, models.ErrInvalidReviewDecision, err)
+	_, err = svc.SubmitReview(asUser("other"), models.ReviewDecisionRequest{PullRequestID: "pr-1", ReviewerID: "rev", Decision: models.ReviewDecisionApprove})
+	assert.Equal(t, models.ErrForbidden, err)
+	_, err = svc.SubmitReview(asUser("other"), models.ReviewDecisionRequest{PullRequestID: "pr-1", ReviewerID: "other", Decision: models.ReviewDecisionApprove})
+	assert.Equal(t, models.ErrNotAssigned, err)
+
+	prs.pr.Status = "MERGED"
+	_, err = svc.SubmitReview(asUser("rev"), models.ReviewDecisionRequest{PullRequestID: "pr-1", ReviewerID: "rev", Decision: models.ReviewDecisionApprove})
+	assert.Equal(t, models.ErrPRMerged, err)
+	assert.Len(t, timeline.events, 2)
+}
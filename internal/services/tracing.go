@@ -0,0 +1,29 @@
+package services
+
+/*
+Спаны методов сервисов. Трейсер берётся из глобального провайдера - пока
+экспорт не настроен, спаны ничего не стоят и никуда не уходят.
+*/
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("test-task/internal/services")
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan закрывает спан; вызывать через defer с именованной ошибкой метода
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
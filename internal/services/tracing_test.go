@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"test-task/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestServiceMethodsAreTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	users := &fakeUserPages{users: []models.User{{UserID: "u1", TeamName: "backend"}}}
+	svc := NewUserService(users, &fakeOutbox{}, &fakeAudit{}, NewAuthorizer(users), nil, nil)
+	ctx := context.Background()
+
+	_, err := svc.ListUsers(ctx, models.UserListQuery{TeamName: "backend"})
+	require.NoError(t, err)
+	_, err = svc.ListUsers(ctx, models.UserListQuery{Sort: "email"})
+	require.ErrorIs(t, err, models.ErrInvalidQuery)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "UserService.ListUsers", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("team.name", "backend"))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Len(t, spans[1].Events(), 1, "the error is recorded on the span")
+}
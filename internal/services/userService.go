@@ -4,33 +4,72 @@ package services
 Функции:
 	1. Выставление активности пользоватлеля
 	2. Получение информации о юзере
+	3. Отказ от email-дайджеста
+	4. Назначение роли (только админ)
+	5. Список пользователей постранично с фильтрами
+
+При деактивации открытые ревью пользователя переназначаются после коммита,
+каждое в своей транзакции: если замены нет, ревьюер остаётся и это пишется
+в лог.
 
 Фича - указываем в GetUserTx nil вместо индекса, он автоматом выполняется через
 пул
 */
 import (
 	"context"
+	"fmt"
+	"test-task/internal/logging"
 	"test-task/internal/models"
 	"test-task/internal/storage"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type UserService struct {
 	userStorage storage.UserStorage
+	outbox      storage.OutboxStorage
+	audit       storage.AuditStorage
+	authz       *Authorizer
+	prStorage   storage.PullReqStorage
+	prManager   PullRequestManager
 }
 
-func NewUserService(userStorage storage.UserStorage) *UserService {
+func NewUserService(
+	userStorage storage.UserStorage,
+	outbox storage.OutboxStorage,
+	audit storage.AuditStorage,
+	authz *Authorizer,
+	prStorage storage.PullReqStorage,
+	prManager PullRequestManager,
+) *UserService {
 	return &UserService{
 		userStorage: userStorage,
+		outbox:      outbox,
+		audit:       audit,
+		authz:       authz,
+		prStorage:   prStorage,
+		prManager:   prManager,
 	}
 }
 
-func (s *UserService) SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, error) {
+func (s *UserService) SetUserActive(ctx context.Context, userID string, isActive bool) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.SetUserActive", attribute.String("user.id", userID), attribute.Bool("user.is_active", isActive))
+	defer func() { endSpan(span, err) }()
+
 	tx, err := s.userStorage.UserBeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
+	before, err := s.userStorage.GetUserTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.RequireTeamLead(ctx, tx, before.TeamName); err != nil {
+		return nil, err
+	}
+
 	err = s.userStorage.UpdateUserActiveTx(ctx, tx, userID, isActive)
 	if err != nil {
 		return nil, err
@@ -41,9 +80,151 @@ func (s *UserService) SetUserActive(ctx context.Context, userID string, isActive
 		return nil, err
 	}
 
+	if err := recordEvent(ctx, tx, s.outbox, models.EventUserActiveChanged, res); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityUser, userID, "active_changed", before, res); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	if before.IsActive && !isActive {
+		reassignOpenReviews(ctx, s.prStorage, s.prManager, userID, models.TimelineReasonDeactivation)
+	}
+
+	return res, nil
+}
+
+// reassignOpenReviews снимает пользователя с открытых PR - при деактивации
+// и при уходе из команды. Право на само действие уже проверено, поэтому
+// переназначение идёт от имени системы, но в аудит пишется исходный автор.
+func reassignOpenReviews(ctx context.Context, prStorage storage.PullReqStorage, prManager PullRequestManager, userID string, reason string) {
+	prs, err := prStorage.GetPRsByReviewerTx(ctx, nil, userID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list open reviews of user", "user_id", userID, "reason", reason, "error", err)
+		return
+	}
+
+	sysCtx := SystemContext(WithAuditActor(ctx, auditActor(ctx)), reason)
+	for _, pr := range prs {
+		if pr.Status != "OPEN" {
+			continue
+		}
+		_, newReviewer, err := prManager.ReassignReviewer(sysCtx, models.ReassignRequest{
+			PullRequestID: pr.PullRequestID,
+			OldUserID:     userID,
+			Reason:        reason,
+		})
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to reassign open review", "user_id", userID, "reason", reason, "pull_request_id", pr.PullRequestID, "error", err)
+			continue
+		}
+		logging.FromContext(ctx).Info("Reassigned open review", "user_id", userID, "reason", reason, "pull_request_id", pr.PullRequestID, "new_reviewer", newReviewer)
+	}
+}
+
+func (s *UserService) SetDigestOptOut(ctx context.Context, userID string, optOut bool) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.SetDigestOptOut", attribute.String("user.id", userID))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.userStorage.UserBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := s.userStorage.GetUserTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.RequireSelfOrLead(ctx, tx, before); err != nil {
+		return nil, err
+	}
+
+	if err := s.userStorage.UpdateUserDigestOptOutTx(ctx, tx, userID, optOut); err != nil {
+		return nil, err
+	}
+
+	res, err := s.userStorage.GetUserTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityUser, userID, "digest_opt_out_changed", before, res); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *UserService) SetRole(ctx context.Context, userID string, role string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.SetRole", attribute.String("user.id", userID), attribute.String("user.role", role))
+	defer func() { endSpan(span, err) }()
+
+	switch role {
+	case models.RoleMember, models.RoleLead, models.RoleAdmin:
+	default:
+		return nil, fmt.Errorf("%w: role must be member, lead or admin", models.ErrInvalidRole)
+	}
+
+	tx, err := s.userStorage.UserBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.authz.RequireAdmin(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	before, err := s.userStorage.GetUserTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userStorage.UpdateUserRoleTx(ctx, tx, userID, role); err != nil {
+		return nil, err
+	}
+
+	res, err := s.userStorage.GetUserTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, tx, s.audit, models.AuditEntityUser, userID, "role_changed", before, res); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 
 	return res, nil
 }
+
+func (s *UserService) ListUsers(ctx context.Context, q models.UserListQuery) (_ *models.UserPage, err error) {
+	ctx, span := startSpan(ctx, "UserService.ListUsers", attribute.String("team.name", q.TeamName))
+	defer func() { endSpan(span, err) }()
+
+	if err := prepareUserQuery(&q); err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	q.Limit++
+	users, err := s.userStorage.ListUsersPageTx(ctx, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	q.Limit = limit
+
+	return userPage(users, q), nil
+}
@@ -0,0 +1,198 @@
+package services
+
+/*
+Общая часть входящих вебхуков от git-хостингов:
+	1. Сопоставление логинов хостинга с users.user_id
+	2. Идемпотентность по id доставки
+	3. Применение нормализованного события к PullRequestService
+
+Переоткрытие PR, которого у нас нет (вебхук подключили позже), создаёт его.
+Закрытие и обновление неизвестного PR игнорируются. Так же игнорируются
+слияние PR, уже закрытого через API, и закрытие слитого: состояние у нас
+окончательное, а ошибка заставила бы хостинг повторять доставку.
+
+Захват доставки в webhook_deliveries и изменения PR идут в одной
+транзакции (storage.WithTx): если обработка упала или процесс умер до
+commit, откатывается и захват, и повторная доставка от хостинга пройдёт
+заново. Параллельная доставка того же id ждёт на уникальном ключе и после
+commit первой видит дубль. Конфликт serializable-транзакции повторяется
+целиком - внутри общей транзакции сервис PR этого не делает.
+*/
+import (
+	"context"
+	"strings"
+	"test-task/internal/models"
+	"test-task/internal/storage"
+)
+
+type forgeUsers map[string]string
+
+func newForgeUsers(mapping map[string]string) forgeUsers {
+	users := make(forgeUsers, len(mapping))
+	for login, userID := range mapping {
+		users[strings.ToLower(strings.TrimSpace(login))] = strings.TrimSpace(userID)
+	}
+	return users
+}
+
+func (u forgeUsers) resolve(login string) (string, error) {
+	userID, ok := u[strings.ToLower(login)]
+	if !ok || userID == "" {
+		return "", models.ErrUnmappedUser
+	}
+	return userID, nil
+}
+
+type webhookDeduper struct {
+	source     string
+	deliveries storage.WebhookDeliveryStorage
+}
+
+func (d *webhookDeduper) process(ctx context.Context, deliveryID string, handle func(ctx context.Context) (*models.WebhookResult, error)) (*models.WebhookResult, error) {
+	if deliveryID == "" {
+		return nil, models.ErrInvalidPayload
+	}
+
+	var res *models.WebhookResult
+	err := retryOnConflict(ctx, "webhook_"+d.source, func() error {
+		var err error
+		res, err = d.processTx(ctx, deliveryID, handle)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.DeliveryID = deliveryID
+	return res, nil
+}
+
+func (d *webhookDeduper) processTx(ctx context.Context, deliveryID string, handle func(ctx context.Context) (*models.WebhookResult, error)) (*models.WebhookResult, error) {
+	tx, err := d.deliveries.DeliveryBeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	claimed, err := d.deliveries.ClaimDeliveryTx(ctx, tx, d.source, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return &models.WebhookResult{Action: models.WebhookActionDuplicate}, nil
+	}
+
+	res, err := handle(storage.WithTx(ctx, tx))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type forgeAction int
+
+const (
+	forgeActionIgnore forgeAction = iota
+	forgeActionOpen
+	forgeActionUpdate
+	forgeActionMerge
+	forgeActionClose
+	forgeActionReopen
+)
+
+// forgePREvent - событие PR/MR, приведённое к общему виду для всех хостингов
+type forgePREvent struct {
+	Action      forgeAction
+	PRID        string
+	Title       string
+	AuthorLogin string
+	Project     string
+	Tags        []string
+}
+
+type forgeEventApplier struct {
+	prManager PullRequestManager
+	users     forgeUsers
+}
+
+func (a *forgeEventApplier) apply(ctx context.Context, event forgePREvent) (*models.WebhookResult, error) {
+	res := &models.WebhookResult{PullRequestID: event.PRID, Action: models.WebhookActionIgnored}
+
+	switch event.Action {
+	case forgeActionOpen:
+		return a.create(ctx, event, res)
+
+	case forgeActionUpdate:
+		_, err := a.prManager.UpdatePR(ctx, models.UpdatePRRequest{
+			PullRequestID:   event.PRID,
+			PullRequestName: event.Title,
+			Project:         event.Project,
+		})
+		if err == models.ErrNotFound {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Action = models.WebhookActionUpdated
+
+	case forgeActionMerge:
+		_, err := a.prManager.MergePR(ctx, event.PRID)
+		if err == models.ErrPRClosed {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Action = models.WebhookActionMerged
+
+	case forgeActionClose:
+		_, err := a.prManager.ClosePR(ctx, event.PRID)
+		if err == models.ErrNotFound || err == models.ErrPRMerged {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Action = models.WebhookActionClosed
+
+	case forgeActionReopen:
+		_, err := a.prManager.ReopenPR(ctx, event.PRID)
+		if err == models.ErrNotFound {
+			return a.create(ctx, event, res)
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Action = models.WebhookActionReopened
+	}
+
+	return res, nil
+}
+
+func (a *forgeEventApplier) create(ctx context.Context, event forgePREvent, res *models.WebhookResult) (*models.WebhookResult, error) {
+	authorID, err := a.users.resolve(event.AuthorLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = a.prManager.CreatePR(ctx, models.CreatePRRequest{
+		PullRequestID:   event.PRID,
+		PullRequestName: event.Title,
+		AuthorID:        authorID,
+		Project:         event.Project,
+		Tags:            event.Tags,
+	})
+	if err == models.ErrPRExists {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Action = models.WebhookActionCreated
+	return res, nil
+}
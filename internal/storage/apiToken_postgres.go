@@ -0,0 +1,152 @@
+package storage
+
+/*
+Основные функции:
+	1. Выпуск API-токена (хранится только SHA-256 хеш)
+	2. Поиск токена по хешу
+	3. Список токенов
+	4. Отзыв токена
+
+Отозванный токен остаётся в таблице с revoked_at - по нему видно, кто и
+когда имел доступ.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APITokenPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPITokenPostgresStorage(pool *pgxpool.Pool) *APITokenPostgresStorage {
+	return &APITokenPostgresStorage{pool: pool}
+}
+
+func (s *APITokenPostgresStorage) CreateAPITokenTx(ctx context.Context, tx pgx.Tx, token models.APIToken, tokenHash string) (*models.APIToken, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO api_tokens (token_hash, name, principal_type, principal_id, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, name, principal_type, principal_id, scopes, created_at, revoked_at
+	`
+
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, query, tokenHash, token.Name, token.PrincipalType, token.PrincipalID, token.Scopes)
+	} else {
+		row = s.pool.QueryRow(ctx, query, tokenHash, token.Name, token.PrincipalType, token.PrincipalID, token.Scopes)
+	}
+
+	created, err := scanAPIToken(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *APITokenPostgresStorage) GetAPITokenByHashTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.APIToken, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT id, name, principal_type, principal_id, scopes, created_at, revoked_at
+		FROM api_tokens
+		WHERE token_hash = $1
+	`
+
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, query, tokenHash)
+	} else {
+		row = s.pool.QueryRow(ctx, query, tokenHash)
+	}
+
+	token, err := scanAPIToken(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (s *APITokenPostgresStorage) ListAPITokensTx(ctx context.Context, tx pgx.Tx) ([]models.APIToken, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT id, name, principal_type, principal_id, scopes, created_at, revoked_at
+		FROM api_tokens
+		ORDER BY id
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query)
+	} else {
+		rows, err = s.pool.Query(ctx, query)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (s *APITokenPostgresStorage) RevokeAPITokenTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	tx = ambientTx(ctx, tx)
+	query := `UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, id)
+	} else {
+		result, err = s.pool.Exec(ctx, query, id)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke API token: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	var t models.APIToken
+	err := row.Scan(&t.ID, &t.Name, &t.PrincipalType, &t.PrincipalID, &t.Scopes, &t.CreatedAt, &t.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
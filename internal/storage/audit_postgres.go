@@ -0,0 +1,92 @@
+package storage
+
+/*
+Основные функции:
+	1. Добавление записи в журнал аудита (только внутри транзакции изменения)
+	2. Чтение журнала по сущности страницами от новых к старым
+
+Журнал append-only: UPDATE и DELETE запрещены триггером в БД.
+Пагинация по id: beforeID = 0 - первая страница.
+
+Фича - если Tx - nil, то используем просто pool (только для чтения)
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditPostgresStorage(pool *pgxpool.Pool) *AuditPostgresStorage {
+	return &AuditPostgresStorage{pool: pool}
+}
+
+func (s *AuditPostgresStorage) AddAuditTx(ctx context.Context, tx pgx.Tx, entry models.AuditEntry) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO audit_log (entity, entity_id, action, actor, request_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	err := execWrite(ctx, tx, s.pool, query,
+		entry.Entity,
+		entry.EntityID,
+		entry.Action,
+		entry.Actor,
+		entry.RequestID,
+		entry.Before,
+		entry.After,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	return nil
+}
+
+func (s *AuditPostgresStorage) ListAuditTx(ctx context.Context, tx pgx.Tx, entity string, entityID string, beforeID int64, limit int) ([]models.AuditEntry, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT id, entity, entity_id, action, actor, request_id, before, after, created_at
+		FROM audit_log
+		WHERE entity = $1 AND entity_id = $2 AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, entity, entityID, beforeID, limit)
+	} else {
+		rows, err = s.pool.Query(ctx, query, entity, entityID, beforeID, limit)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &e.Actor, &e.RequestID, &e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	return entries, nil
+}
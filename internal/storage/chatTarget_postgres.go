@@ -0,0 +1,143 @@
+package storage
+
+/*
+Основные функции:
+	1. Установка / удаление webhook чата для пользователя или команды
+	2. Список всех webhook
+	3. Поиск webhook для набора пользователей и их команды
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ChatTargetPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewChatTargetPostgresStorage(pool *pgxpool.Pool) *ChatTargetPostgresStorage {
+	return &ChatTargetPostgresStorage{pool: pool}
+}
+
+func (s *ChatTargetPostgresStorage) SetChatTargetTx(ctx context.Context, tx pgx.Tx, target models.ChatTarget) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO chat_targets (scope, target_id, webhook_url, format)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, target_id)
+		DO UPDATE SET webhook_url = EXCLUDED.webhook_url, format = EXCLUDED.format
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, target.Scope, target.TargetID, target.WebhookURL, target.Format)
+	} else {
+		_, err = s.pool.Exec(ctx, query, target.Scope, target.TargetID, target.WebhookURL, target.Format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to set chat target: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ChatTargetPostgresStorage) DeleteChatTargetTx(ctx context.Context, tx pgx.Tx, scope string, targetID string) error {
+	tx = ambientTx(ctx, tx)
+	query := `DELETE FROM chat_targets WHERE scope = $1 AND target_id = $2`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, scope, targetID)
+	} else {
+		result, err = s.pool.Exec(ctx, query, scope, targetID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to delete chat target: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ChatTargetPostgresStorage) ListChatTargetsTx(ctx context.Context, tx pgx.Tx) ([]models.ChatTarget, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT scope, target_id, webhook_url, format
+		FROM chat_targets
+		ORDER BY scope, target_id
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query)
+	} else {
+		rows, err = s.pool.Query(ctx, query)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat targets: %w", err)
+	}
+
+	return scanChatTargets(rows)
+}
+
+func (s *ChatTargetPostgresStorage) FindChatTargetsTx(ctx context.Context, tx pgx.Tx, userIDs []string, teamName string) ([]models.ChatTarget, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT scope, target_id, webhook_url, format
+		FROM chat_targets
+		WHERE (scope = 'user' AND target_id = ANY($1))
+			OR (scope = 'team' AND target_id = $2)
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, userIDs, teamName)
+	} else {
+		rows, err = s.pool.Query(ctx, query, userIDs, teamName)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat targets: %w", err)
+	}
+
+	return scanChatTargets(rows)
+}
+
+func scanChatTargets(rows pgx.Rows) ([]models.ChatTarget, error) {
+	defer rows.Close()
+
+	var targets []models.ChatTarget
+	for rows.Next() {
+		var t models.ChatTarget
+		if err := rows.Scan(&t.Scope, &t.TargetID, &t.WebhookURL, &t.Format); err != nil {
+			return nil, fmt.Errorf("failed to scan chat target: %w", err)
+		}
+		targets = append(targets, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat targets: %w", err)
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+/*
+Основные функции:
+	1. Сохранение CODEOWNERS команды (исходный текст + разобранные правила)
+	2. Получение CODEOWNERS команды
+
+На команду хранится один файл, повторная загрузка его перезаписывает.
+Правила лежат в JSONB в исходном порядке - для last-match-wins это важно.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"test-task/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CodeOwnersPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewCodeOwnersPostgresStorage(pool *pgxpool.Pool) *CodeOwnersPostgresStorage {
+	return &CodeOwnersPostgresStorage{pool: pool}
+}
+
+func (s *CodeOwnersPostgresStorage) SaveCodeOwnersTx(ctx context.Context, tx pgx.Tx, co models.CodeOwners) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO team_codeowners (team_name, content, rules, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (team_name)
+		DO UPDATE SET content = EXCLUDED.content, rules = EXCLUDED.rules, updated_at = EXCLUDED.updated_at
+	`
+
+	rules, err := json.Marshal(co.Rules)
+	if err != nil {
+		return fmt.Errorf("failed to marshal codeowners rules: %w", err)
+	}
+
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, co.TeamName, co.Content, rules, time.Now())
+	} else {
+		_, err = s.pool.Exec(ctx, query, co.TeamName, co.Content, rules, time.Now())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save codeowners: %w", err)
+	}
+
+	return nil
+}
+
+func (s *CodeOwnersPostgresStorage) GetCodeOwnersTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.CodeOwners, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT team_name, content, rules, updated_at
+		FROM team_codeowners
+		WHERE team_name = $1
+	`
+
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, query, teamName)
+	} else {
+		row = s.pool.QueryRow(ctx, query, teamName)
+	}
+
+	var co models.CodeOwners
+	var rules []byte
+	err := row.Scan(&co.TeamName, &co.Content, &rules, &co.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get codeowners: %w", err)
+	}
+
+	if err := json.Unmarshal(rules, &co.Rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal codeowners rules: %w", err)
+	}
+
+	return &co, nil
+}
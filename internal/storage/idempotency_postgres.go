@@ -0,0 +1,144 @@
+package storage
+
+/*
+Основные функции для Idempotency-Key:
+	1. Захват ключа: новая запись "в работе" или уже существующая
+	2. Сохранение ответа выполненного запроса
+	3. Освобождение ключа, если запрос не удался
+	4. Удаление просроченных ключей
+
+Просроченный ключ, как и ключ, застрявший "в работе" дольше staleAfter
+(процесс упал посреди запроса), захватывается заново. staleAfter должен
+быть заметно больше самого долгого дедлайна обработчиков (WriteTimeout,
+BATCH_TIMEOUT), иначе ключ ещё идущего запроса перехватит повтор - его
+задаёт app из этих дедлайнов.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test-task/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyPostgresStorage struct {
+	pool       *pgxpool.Pool
+	staleAfter time.Duration
+}
+
+func NewIdempotencyPostgresStorage(pool *pgxpool.Pool, staleAfter time.Duration) *IdempotencyPostgresStorage {
+	return &IdempotencyPostgresStorage{pool: pool, staleAfter: staleAfter}
+}
+
+func (s *IdempotencyPostgresStorage) queryRow(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) pgx.Row {
+	if tx != nil {
+		return tx.QueryRow(ctx, query, args...)
+	}
+	return s.pool.QueryRow(ctx, query, args...)
+}
+
+func (s *IdempotencyPostgresStorage) exec(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	if tx != nil {
+		return tx.Exec(ctx, query, args...)
+	}
+	return s.pool.Exec(ctx, query, args...)
+}
+
+// ClaimIdempotencyKeyTx возвращает (nil, true), если ключ захвачен этим
+// вызовом, иначе - существующую запись
+func (s *IdempotencyPostgresStorage) ClaimIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string, fingerprint string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO idempotency_keys (scope, idem_key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, idem_key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			content_type = '',
+			response = NULL,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $5))
+		RETURNING true
+	`
+
+	var claimed bool
+	err := s.queryRow(ctx, tx, query, scope, key, fingerprint, expiresAt, s.staleAfter.Seconds()).Scan(&claimed)
+	if err == nil {
+		return nil, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	record, err := s.getIdempotencyKeyTx(ctx, tx, scope, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return record, false, nil
+}
+
+func (s *IdempotencyPostgresStorage) getIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT scope, idem_key, fingerprint, status_code, content_type, response, expires_at
+		FROM idempotency_keys
+		WHERE scope = $1 AND idem_key = $2
+	`
+
+	var r models.IdempotencyRecord
+	var statusCode *int
+	err := s.queryRow(ctx, tx, query, scope, key).Scan(&r.Scope, &r.Key, &r.Fingerprint, &statusCode, &r.ContentType, &r.Body, &r.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if statusCode != nil {
+		r.Completed = true
+		r.StatusCode = *statusCode
+	}
+	return &r, nil
+}
+
+func (s *IdempotencyPostgresStorage) CompleteIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string, statusCode int, contentType string, body []byte) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response = $5
+		WHERE scope = $1 AND idem_key = $2
+	`
+
+	if _, err := s.exec(ctx, tx, query, scope, key, statusCode, contentType, body); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *IdempotencyPostgresStorage) ReleaseIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string) error {
+	tx = ambientTx(ctx, tx)
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND idem_key = $2 AND status_code IS NULL`
+
+	if _, err := s.exec(ctx, tx, query, scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *IdempotencyPostgresStorage) DeleteExpiredIdempotencyKeysTx(ctx context.Context, tx pgx.Tx, now time.Time) (int64, error) {
+	tx = ambientTx(ctx, tx)
+	result, err := s.exec(ctx, tx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,99 @@
+package storage
+
+/*
+Метрики, которые считаются в момент опроса /metrics:
+	1. Состояние пула соединений pgxpool (без обращения к базе)
+	2. Доменные: открытые PR и открытые ревью на каждого ревьюера
+
+Это два отдельных коллектора: если база не отвечает, статистика пула
+всё равно отдаётся.
+*/
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredDesc = prometheus.NewDesc("db_pool_acquired_conns", "Connections currently checked out of the pool.", nil, nil)
+	poolIdleDesc     = prometheus.NewDesc("db_pool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolTotalDesc    = prometheus.NewDesc("db_pool_total_conns", "All connections in the pool.", nil, nil)
+	poolMaxDesc      = prometheus.NewDesc("db_pool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquiresDesc = prometheus.NewDesc("db_pool_acquires_total", "Successful connection acquires.", nil, nil)
+	poolEmptyDesc    = prometheus.NewDesc("db_pool_empty_acquires_total", "Acquires that had to wait for a connection.", nil, nil)
+	poolWaitDesc     = prometheus.NewDesc("db_pool_acquire_wait_seconds_total", "Time spent waiting for a connection in acquires that found the pool empty.", nil, nil)
+
+	openPRsDesc     = prometheus.NewDesc("pr_open", "Open pull requests.", nil, nil)
+	openReviewsDesc = prometheus.NewDesc("pr_open_reviews", "Open pull requests assigned to each reviewer.", []string{"user_id"}, nil)
+)
+
+type MetricsPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewMetricsPostgresStorage(pool *pgxpool.Pool) *MetricsPostgresStorage {
+	return &MetricsPostgresStorage{pool: pool}
+}
+
+func (s *MetricsPostgresStorage) CollectPool(ctx context.Context) ([]prometheus.Metric, error) {
+	stat := s.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value float64) prometheus.Metric {
+		return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) prometheus.Metric {
+		return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	return []prometheus.Metric{
+		gauge(poolAcquiredDesc, float64(stat.AcquiredConns())),
+		gauge(poolIdleDesc, float64(stat.IdleConns())),
+		gauge(poolTotalDesc, float64(stat.TotalConns())),
+		gauge(poolMaxDesc, float64(stat.MaxConns())),
+		counter(poolAcquiresDesc, float64(stat.AcquireCount())),
+		counter(poolEmptyDesc, float64(stat.EmptyAcquireCount())),
+		counter(poolWaitDesc, stat.EmptyAcquireWaitTime().Seconds()),
+	}, nil
+}
+
+func (s *MetricsPostgresStorage) CollectDomain(ctx context.Context) ([]prometheus.Metric, error) {
+	var openPRs int64
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM pull_requests WHERE status = 'OPEN'").Scan(&openPRs); err != nil {
+		return nil, fmt.Errorf("failed to count open PRs: %w", err)
+	}
+
+	query := `
+		SELECT reviewer, COUNT(*)
+		FROM pull_requests, unnest(assigned_reviewers) AS reviewer
+		WHERE status = 'OPEN'
+		GROUP BY reviewer
+		ORDER BY reviewer
+	`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count open reviews: %w", err)
+	}
+	defer rows.Close()
+
+	collected := []prometheus.Metric{
+		prometheus.MustNewConstMetric(openPRsDesc, prometheus.GaugeValue, float64(openPRs)),
+	}
+	for rows.Next() {
+		var userID string
+		var count int64
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan open reviews: %w", err)
+		}
+		collected = append(collected, prometheus.MustNewConstMetric(openReviewsDesc, prometheus.GaugeValue, float64(count), userID))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating open reviews: %w", err)
+	}
+
+	return collected, nil
+}
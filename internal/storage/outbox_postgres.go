@@ -0,0 +1,299 @@
+package storage
+
+/*
+Основные функции:
+	1. Запись события в outbox (только внутри транзакции сервиса)
+	2. Захват доставок событий по sink'ам для диспетчера
+	3. Отметка доставки / неудачи / DEAD по каждому sink отдельно
+	4. Список DEAD-доставок (dead letter)
+	5. LISTEN на канал outbox, чтобы диспетчер не ждал следующего опроса
+
+AddEventTx делает pg_notify в той же транзакции - уведомление уходит только
+после коммита.
+
+Состояние доставки хранится на пару (событие, sink) в outbox_deliveries:
+упавший sink повторяется сам, не дёргая остальные. Захват короткий -
+строкам ставится available_at = NOW() + lease и транзакция сразу
+коммитится, сама доставка идёт уже без блокировок. Если процесс упал
+посреди доставки, строка вернётся в очередь по истечении lease
+(at-least-once). outbox.delivered_at ставится, когда все доставки события
+завершены (доставлены или DEAD).
+*/
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"test-task/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const outboxChannel = "outbox"
+
+type OutboxPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxPostgresStorage(pool *pgxpool.Pool) *OutboxPostgresStorage {
+	return &OutboxPostgresStorage{pool: pool}
+}
+
+func (s *OutboxPostgresStorage) OutboxBeginTx(ctx context.Context) (pgx.Tx, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.ReadCommitted,
+		AccessMode: pgx.ReadWrite,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return tx, nil
+}
+
+func (s *OutboxPostgresStorage) AddEventTx(ctx context.Context, tx pgx.Tx, event models.Event) error {
+	tx = ambientTx(ctx, tx)
+	if tx == nil {
+		return fmt.Errorf("outbox event %s must be written inside a transaction", event.Type)
+	}
+
+	query := `
+		INSERT INTO outbox (event_id, event_type, payload, occurred_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	err := execWrite(ctx, tx, s.pool, query, event.ID, event.Type, []byte(event.Data), event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+
+	err = execWrite(ctx, tx, s.pool, "SELECT pg_notify($1, $2)", outboxChannel, event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to notify outbox: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimOutboxTx заводит недостающие доставки по sinks для необработанных
+// событий и захватывает до limit доставок, готовых к отправке
+func (s *OutboxPostgresStorage) ClaimOutboxTx(ctx context.Context, tx pgx.Tx, sinks []string, limit int, lease time.Duration) ([]models.OutboxDelivery, error) {
+	tx = ambientTx(ctx, tx)
+	if tx == nil {
+		return nil, fmt.Errorf("outbox deliveries must be claimed inside a transaction")
+	}
+
+	materialize := `
+		INSERT INTO outbox_deliveries (outbox_id, sink)
+		SELECT o.id, sink.name
+		FROM (
+			SELECT id FROM outbox o
+			WHERE o.delivered_at IS NULL AND EXISTS (
+				SELECT 1 FROM unnest($1::TEXT[]) AS sink(name)
+				WHERE NOT EXISTS (
+					SELECT 1 FROM outbox_deliveries d WHERE d.outbox_id = o.id AND d.sink = sink.name
+				)
+			)
+			ORDER BY id
+			LIMIT $2
+		) o
+		CROSS JOIN unnest($1::TEXT[]) AS sink(name)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, materialize, sinks, limit); err != nil {
+		return nil, fmt.Errorf("failed to create outbox deliveries: %w", err)
+	}
+
+	claim := `
+		UPDATE outbox_deliveries d
+		SET attempts = d.attempts + 1, available_at = NOW() + $3 * INTERVAL '1 millisecond'
+		FROM outbox o
+		WHERE o.id = d.outbox_id
+			AND (d.outbox_id, d.sink) IN (
+				SELECT outbox_id, sink FROM outbox_deliveries
+				WHERE sink = ANY($1) AND delivered_at IS NULL AND dead_at IS NULL AND available_at <= NOW()
+				ORDER BY outbox_id
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING d.outbox_id, d.sink, d.attempts, o.event_id, o.event_type, o.payload, o.occurred_at
+	`
+
+	rows, err := tx.Query(ctx, claim, sinks, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.OutboxDelivery
+	for rows.Next() {
+		var d models.OutboxDelivery
+		var payload []byte
+		err := rows.Scan(
+			&d.OutboxID,
+			&d.Sink,
+			&d.Attempts,
+			&d.Event.ID,
+			&d.Event.Type,
+			&payload,
+			&d.Event.OccurredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox delivery: %w", err)
+		}
+		d.Event.Data = payload
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// completeOutboxQuery закрывает событие, когда у него не осталось
+// незавершённых доставок
+const completeOutboxQuery = `
+	UPDATE outbox SET delivered_at = NOW()
+	WHERE id = $1 AND delivered_at IS NULL AND NOT EXISTS (
+		SELECT 1 FROM outbox_deliveries
+		WHERE outbox_id = $1 AND delivered_at IS NULL AND dead_at IS NULL
+	)
+`
+
+func (s *OutboxPostgresStorage) MarkOutboxDeliveredTx(ctx context.Context, tx pgx.Tx, outboxID int64, sink string) error {
+	tx = ambientTx(ctx, tx)
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		UPDATE outbox_deliveries
+		SET delivered_at = NOW(), last_error = ''
+		WHERE outbox_id = $1 AND sink = $2
+	`, outboxID, sink)
+	batch.Queue(completeOutboxQuery, outboxID)
+
+	if err := sendBatch(ctx, tx, s.pool, batch); err != nil {
+		return fmt.Errorf("failed to mark outbox delivery delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkOutboxFailedTx откладывает доставку до availableAt,
+// availableAt == nil - попытки кончились, доставка уходит в DEAD
+func (s *OutboxPostgresStorage) MarkOutboxFailedTx(ctx context.Context, tx pgx.Tx, outboxID int64, sink string, lastError string, availableAt *time.Time) error {
+	tx = ambientTx(ctx, tx)
+	batch := &pgx.Batch{}
+	if availableAt != nil {
+		batch.Queue(`
+			UPDATE outbox_deliveries
+			SET last_error = $3, available_at = $4
+			WHERE outbox_id = $1 AND sink = $2
+		`, outboxID, sink, lastError, *availableAt)
+	} else {
+		batch.Queue(`
+			UPDATE outbox_deliveries
+			SET last_error = $3, dead_at = NOW()
+			WHERE outbox_id = $1 AND sink = $2
+		`, outboxID, sink, lastError)
+		batch.Queue(completeOutboxQuery, outboxID)
+	}
+
+	if err := sendBatch(ctx, tx, s.pool, batch); err != nil {
+		return fmt.Errorf("failed to mark outbox delivery failed: %w", err)
+	}
+	return nil
+}
+
+// ListOutboxDeadTx - последние доставки, для которых кончились попытки
+func (s *OutboxPostgresStorage) ListOutboxDeadTx(ctx context.Context, tx pgx.Tx, limit int) ([]models.OutboxDelivery, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT d.outbox_id, d.sink, d.attempts, d.last_error, d.dead_at,
+			o.event_id, o.event_type, o.payload, o.occurred_at
+		FROM outbox_deliveries d
+		JOIN outbox o ON o.id = d.outbox_id
+		WHERE d.dead_at IS NOT NULL
+		ORDER BY d.dead_at DESC
+		LIMIT $1
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, limit)
+	} else {
+		rows, err = s.pool.Query(ctx, query, limit)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead outbox deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.OutboxDelivery
+	for rows.Next() {
+		var d models.OutboxDelivery
+		var payload []byte
+		err := rows.Scan(
+			&d.OutboxID,
+			&d.Sink,
+			&d.Attempts,
+			&d.LastError,
+			&d.DeadAt,
+			&d.Event.ID,
+			&d.Event.Type,
+			&payload,
+			&d.Event.OccurredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead outbox delivery: %w", err)
+		}
+		d.Event.Data = payload
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dead outbox deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ListenOutbox держит собственное соединение (не из pool - оно занято
+// навсегда) с LISTEN и шлёт в notify на каждое уведомление. При обрыве
+// переподключается, пока ctx не отменён.
+func (s *OutboxPostgresStorage) ListenOutbox(ctx context.Context, notify chan<- struct{}) {
+	for ctx.Err() == nil {
+		if err := s.listen(ctx, notify); err != nil && ctx.Err() == nil {
+			slog.Warn("Outbox listener failed, reconnecting", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func (s *OutboxPostgresStorage) listen(ctx context.Context, notify chan<- struct{}) error {
+	conn, err := pgx.ConnectConfig(ctx, s.pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+outboxChannel); err != nil {
+		return err
+	}
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package storage
+
+/*
+Тесты outbox через контейнер с постгрес:
+	1. Два диспетчера не захватывают одну доставку (SKIP LOCKED)
+	2. Пока lease не истёк, доставка не выдаётся повторно, истёкший - выдаётся
+	3. Неудача откладывает доставку до available_at
+	4. Доставленное и DEAD закрывают событие, DEAD видно в dead letter
+*/
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func setupOutboxDB(t *testing.T) *pgxpool.Pool {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15-alpine",
+		postgres.WithDatabase("test_db"),
+		postgres.WithUsername("test_user"),
+		postgres.WithPassword("test_password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2),
+		),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, container.Terminate(ctx))
+	})
+
+	connStr, err := container.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE outbox (
+			id BIGSERIAL PRIMARY KEY,
+			event_id TEXT NOT NULL UNIQUE,
+			event_type TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			occurred_at TIMESTAMPTZ NOT NULL,
+			delivered_at TIMESTAMPTZ
+		);
+
+		CREATE TABLE outbox_deliveries (
+			outbox_id BIGINT NOT NULL REFERENCES outbox(id) ON DELETE CASCADE,
+			sink TEXT NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			delivered_at TIMESTAMPTZ,
+			dead_at TIMESTAMPTZ,
+			PRIMARY KEY (outbox_id, sink)
+		);
+	`)
+	require.NoError(t, err)
+
+	return pool
+}
+
+func TestOutboxPostgresStorage_Claim(t *testing.T) {
+	pool := setupOutboxDB(t)
+	storage := NewOutboxPostgresStorage(pool)
+	ctx := context.Background()
+	sinks := []string{"log"}
+
+	tx, err := storage.OutboxBeginTx(ctx)
+	require.NoError(t, err)
+	for _, id := range []string{"ev-1", "ev-2"} {
+		err = storage.AddEventTx(ctx, tx, models.Event{
+			ID:         id,
+			Type:       models.EventPRMerged,
+			OccurredAt: time.Now(),
+			Data:       json.RawMessage(`{"pull_request_id":"` + id + `"}`),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit(ctx))
+
+	claim := func(tx pgx.Tx, limit int, lease time.Duration) []models.OutboxDelivery {
+		deliveries, err := storage.ClaimOutboxTx(ctx, tx, sinks, limit, lease)
+		require.NoError(t, err)
+		return deliveries
+	}
+	claimCommitted := func(limit int, lease time.Duration) []models.OutboxDelivery {
+		tx, err := storage.OutboxBeginTx(ctx)
+		require.NoError(t, err)
+		deliveries := claim(tx, limit, lease)
+		require.NoError(t, tx.Commit(ctx))
+		return deliveries
+	}
+	eventIDs := func(deliveries []models.OutboxDelivery) []string {
+		ids := []string{}
+		for _, d := range deliveries {
+			ids = append(ids, d.Event.ID)
+		}
+		return ids
+	}
+
+	// захват с истёкшим сразу lease - как будто диспетчер упал посреди доставки
+	first := claimCommitted(10, 0)
+	require.Equal(t, []string{"ev-1", "ev-2"}, eventIDs(first))
+	assert.Equal(t, 1, first[0].Attempts)
+	assert.Equal(t, "log", first[0].Sink)
+	assert.JSONEq(t, `{"pull_request_id":"ev-1"}`, string(first[0].Event.Data))
+
+	t.Run("concurrent claims skip locked deliveries", func(t *testing.T) {
+		tx1, err := storage.OutboxBeginTx(ctx)
+		require.NoError(t, err)
+		defer tx1.Rollback(ctx)
+		got1 := claim(tx1, 1, time.Hour)
+		require.Equal(t, []string{"ev-1"}, eventIDs(got1))
+		assert.Equal(t, 2, got1[0].Attempts, "the expired lease is claimed again")
+
+		// tx1 ещё держит блокировку - второй захват не ждёт её и берёт остальное
+		tx2, err := storage.OutboxBeginTx(ctx)
+		require.NoError(t, err)
+		defer tx2.Rollback(ctx)
+		got2 := claim(tx2, 10, time.Hour)
+		assert.Equal(t, []string{"ev-2"}, eventIDs(got2))
+
+		require.NoError(t, tx2.Commit(ctx))
+		require.NoError(t, tx1.Commit(ctx))
+	})
+
+	t.Run("leased deliveries are not claimed again", func(t *testing.T) {
+		assert.Empty(t, claimCommitted(10, time.Hour))
+	})
+
+	t.Run("failure postpones until available_at", func(t *testing.T) {
+		id := first[0].OutboxID
+		future := time.Now().Add(time.Hour)
+		require.NoError(t, storage.MarkOutboxFailedTx(ctx, nil, id, "log", "timeout", &future))
+		assert.Empty(t, claimCommitted(10, time.Hour))
+
+		past := time.Now().Add(-time.Minute)
+		require.NoError(t, storage.MarkOutboxFailedTx(ctx, nil, id, "log", "timeout", &past))
+		got := claimCommitted(10, time.Hour)
+		require.Equal(t, []string{"ev-1"}, eventIDs(got))
+		assert.Equal(t, 3, got[0].Attempts)
+	})
+
+	t.Run("delivered and dead complete the event", func(t *testing.T) {
+		deliveredAt := func(id int64) *time.Time {
+			var at *time.Time
+			require.NoError(t, pool.QueryRow(ctx, "SELECT delivered_at FROM outbox WHERE id = $1", id).Scan(&at))
+			return at
+		}
+
+		require.NoError(t, storage.MarkOutboxDeliveredTx(ctx, nil, first[1].OutboxID, "log"))
+		assert.NotNil(t, deliveredAt(first[1].OutboxID))
+
+		require.NoError(t, storage.MarkOutboxFailedTx(ctx, nil, first[0].OutboxID, "log", "gave up", nil))
+		assert.NotNil(t, deliveredAt(first[0].OutboxID))
+
+		dead, err := storage.ListOutboxDeadTx(ctx, nil, 10)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, "ev-1", dead[0].Event.ID)
+		assert.Equal(t, "gave up", dead[0].LastError)
+		assert.Equal(t, 3, dead[0].Attempts)
+		assert.NotNil(t, dead[0].DeadAt)
+
+		// новый sink не заводит доставок для уже закрытых событий
+		tx, err := storage.OutboxBeginTx(ctx)
+		require.NoError(t, err)
+		deliveries, err := storage.ClaimOutboxTx(ctx, tx, []string{"log", "chat"}, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, deliveries)
+		require.NoError(t, tx.Commit(ctx))
+	})
+}
@@ -12,7 +12,6 @@ import (
 func NewPoolPg(cfg *models.PGXConfig) (*pgxpool.Pool, error) {
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName, cfg.SSLMode)
-	fmt.Println(connStr)
 	config, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка конфигурации: %w", err)
@@ -21,6 +20,7 @@ func NewPoolPg(cfg *models.PGXConfig) (*pgxpool.Pool, error) {
 	config.MaxConns = 10
 	config.MinConns = 2
 	config.MaxConnLifetime = time.Hour
+	config.ConnConfig.Tracer = queryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -8,17 +8,27 @@ package storage
 	4. Обновить ревьюеров
 	5. По ревьюеру найти PR
 	6. Проверить существование PR
-	7. Создать транзакцию
+	7. Сдвинуть срок SLA
+	8. Создать транзакцию
+	9. Страница PR с фильтрами (keyset по ключу сортировки и pull_request_id)
+	10. Закрыть / переоткрыть PR
+	11. Обновить название и проект
 
 
 
+updated_at сдвигается при любом изменении PR (merge, смена ревьюеров) -
+по нему политика устаревания считает бездействие. Смена ревьюеров снимает
+отметку stale.
+
 Если у нас уже  "Merge" в таблице PR, то при выполнении функции Merge у нас
-ничего не произойдет, все произодйте в штатном порядке
+ничего не произойдет, все произодйте в штатном порядке. Смёржить можно только
+OPEN PR: закрытый даёт ErrPRClosed. Переоткрыть можно только CLOSED.
 */
 
 import (
 	"context"
 	"fmt"
+	"strings"
 	"test-task/internal/models"
 	"time"
 
@@ -36,6 +46,7 @@ func NewPullRequestPostgresStorage(pool *pgxpool.Pool) *PullRequestPostgresStora
 }
 
 func (s *PullRequestPostgresStorage) CreatePRTx(ctx context.Context, tx pgx.Tx, pr models.PullRequest) error {
+	tx = ambientTx(ctx, tx)
 	query := `
 		INSERT INTO pull_requests (
 			pull_request_id, 
@@ -43,17 +54,26 @@ func (s *PullRequestPostgresStorage) CreatePRTx(ctx context.Context, tx pgx.Tx,
 			author_id, 
 			status, 
 			assigned_reviewers,
-			created_at
-		) VALUES ($1, $2, $3, $4, $5, $6)
+			project,
+			tags,
+			changed_files,
+			created_at,
+			updated_at,
+			sla_due_at
+		) VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, '{}'::TEXT[]), COALESCE($8, '{}'::TEXT[]), $9, $9, $10)
 	`
 
-	_, err := tx.Exec(ctx, query,
+	err := execWrite(ctx, tx, s.pool, query,
 		pr.PullRequestID,
 		pr.PullRequestName,
 		pr.AuthorID,
 		pr.Status,
 		pr.AssignedReviewers,
+		pr.Project,
+		pr.Tags,
+		pr.ChangedFiles,
 		time.Now(),
+		pr.SLADueAt,
 	)
 
 	if err != nil {
@@ -64,6 +84,7 @@ func (s *PullRequestPostgresStorage) CreatePRTx(ctx context.Context, tx pgx.Tx,
 }
 
 func (s *PullRequestPostgresStorage) GetPRByIDTx(ctx context.Context, tx pgx.Tx, prID string) (*models.PullRequest, error) {
+	tx = ambientTx(ctx, tx)
 	query := `
 		SELECT 
 			pull_request_id,
@@ -71,8 +92,16 @@ func (s *PullRequestPostgresStorage) GetPRByIDTx(ctx context.Context, tx pgx.Tx,
 			author_id,
 			status,
 			assigned_reviewers,
+			project,
+			tags,
+			changed_files,
 			created_at,
-			merged_at
+			merged_at,
+			updated_at,
+			stale_at,
+			closed_at,
+			sla_due_at,
+			sla_breached
 		FROM pull_requests 
 		WHERE pull_request_id = $1
 	`
@@ -93,8 +122,16 @@ func (s *PullRequestPostgresStorage) GetPRByIDTx(ctx context.Context, tx pgx.Tx,
 		&pr.AuthorID,
 		&pr.Status,
 		&pr.AssignedReviewers,
+		&pr.Project,
+		&pr.Tags,
+		&pr.ChangedFiles,
 		&pr.CreatedAt,
 		&mergedAt,
+		&pr.UpdatedAt,
+		&pr.StaleAt,
+		&pr.ClosedAt,
+		&pr.SLADueAt,
+		&pr.SLABreached,
 	)
 
 	if err != nil {
@@ -112,10 +149,11 @@ func (s *PullRequestPostgresStorage) GetPRByIDTx(ctx context.Context, tx pgx.Tx,
 }
 
 func (s *PullRequestPostgresStorage) MergePRTx(ctx context.Context, tx pgx.Tx, prID string) error {
+	tx = ambientTx(ctx, tx)
 	query := `
 		UPDATE pull_requests 
-		SET status = $1, merged_at = $2
-		WHERE pull_request_id = $3 AND status != $1
+		SET status = $1, merged_at = $2, updated_at = $2
+		WHERE pull_request_id = $3 AND status = 'OPEN'
 	`
 
 	var result pgconn.CommandTag
@@ -132,22 +170,104 @@ func (s *PullRequestPostgresStorage) MergePRTx(ctx context.Context, tx pgx.Tx, p
 	}
 
 	if result.RowsAffected() == 0 {
-		exists, err := s.checkPRExistsTx(ctx, tx, prID)
+		status, err := s.prStatusTx(ctx, tx, prID)
 		if err != nil {
 			return err
 		}
-		if !exists {
-			return models.ErrNotFound
+		if status == "CLOSED" {
+			return models.ErrPRClosed
 		}
 	}
 
 	return nil
 }
 
+// UpdatePRDetailsTx меняет название и проект. false - PR нет или
+// ничего не изменилось (updated_at тогда не сдвигается).
+func (s *PullRequestPostgresStorage) UpdatePRDetailsTx(ctx context.Context, tx pgx.Tx, prID string, name string, project string) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE pull_requests
+		SET pull_request_name = $2, project = $3, updated_at = NOW()
+		WHERE pull_request_id = $1
+			AND (pull_request_name IS DISTINCT FROM $2 OR project IS DISTINCT FROM $3)
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, prID, name, project)
+	} else {
+		result, err = s.pool.Exec(ctx, query, prID, name, project)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to update PR: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// ClosePRTx закрывает открытый PR и снимает с него всех ревьюеров.
+// false - PR уже не OPEN.
+func (s *PullRequestPostgresStorage) ClosePRTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE pull_requests
+		SET status = 'CLOSED', closed_at = NOW(), updated_at = NOW(),
+			assigned_reviewers = '{}', sla_due_at = NULL
+		WHERE pull_request_id = $1 AND status = 'OPEN'
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, prID)
+	} else {
+		result, err = s.pool.Exec(ctx, query, prID)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to close PR: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// ReopenPRTx возвращает закрытый PR в OPEN с новыми ревьюерами и сроком SLA.
+// false - PR не CLOSED.
+func (s *PullRequestPostgresStorage) ReopenPRTx(ctx context.Context, tx pgx.Tx, prID string, reviewers []string, slaDueAt *time.Time) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE pull_requests
+		SET status = 'OPEN', closed_at = NULL, stale_at = NULL, updated_at = NOW(),
+			assigned_reviewers = $2, sla_due_at = $3, sla_breached = false
+		WHERE pull_request_id = $1 AND status = 'CLOSED'
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, prID, reviewers, slaDueAt)
+	} else {
+		result, err = s.pool.Exec(ctx, query, prID, reviewers, slaDueAt)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to reopen PR: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
 func (s *PullRequestPostgresStorage) UpdatePRReviewersTx(ctx context.Context, tx pgx.Tx, prID string, reviewers []string) error {
+	tx = ambientTx(ctx, tx)
 	query := `
 		UPDATE pull_requests 
-		SET assigned_reviewers = $1
+		SET assigned_reviewers = $1, updated_at = NOW(), stale_at = NULL
 		WHERE pull_request_id = $2 AND status = $3
 	`
 
@@ -171,13 +291,39 @@ func (s *PullRequestPostgresStorage) UpdatePRReviewersTx(ctx context.Context, tx
 	return nil
 }
 
+// UpdatePRSLATx ставит новый срок SLA и снимает отметку о нарушении.
+// dueAt == nil - у PR нет SLA.
+func (s *PullRequestPostgresStorage) UpdatePRSLATx(ctx context.Context, tx pgx.Tx, prID string, dueAt *time.Time) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE pull_requests 
+		SET sla_due_at = $1, sla_breached = false
+		WHERE pull_request_id = $2
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, dueAt, prID)
+	} else {
+		_, err = s.pool.Exec(ctx, query, dueAt, prID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to update PR SLA: %w", err)
+	}
+
+	return nil
+}
+
 func (s *PullRequestPostgresStorage) GetPRsByReviewerTx(ctx context.Context, tx pgx.Tx, userID string) ([]models.PullRequestShort, error) {
+	tx = ambientTx(ctx, tx)
 	query := `
 		SELECT 
 			pull_request_id,
 			pull_request_name,
 			author_id,
-			status
+			status,
+			created_at
 		FROM pull_requests 
 		WHERE $1 = ANY(assigned_reviewers)
 		ORDER BY created_at DESC
@@ -205,6 +351,7 @@ func (s *PullRequestPostgresStorage) GetPRsByReviewerTx(ctx context.Context, tx
 			&pr.PullRequestName,
 			&pr.AuthorID,
 			&pr.Status,
+			&pr.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan PR: %w", err)
@@ -220,26 +367,111 @@ func (s *PullRequestPostgresStorage) GetPRsByReviewerTx(ctx context.Context, tx
 }
 
 func (s *PullRequestPostgresStorage) PRBeginTx(ctx context.Context) (pgx.Tx, error) {
-	return s.pool.BeginTx(ctx, pgx.TxOptions{
+	return beginTx(ctx, s.pool, pgx.TxOptions{
 		IsoLevel:   pgx.Serializable,
 		AccessMode: pgx.ReadWrite,
 	})
 }
 
-func (s *PullRequestPostgresStorage) checkPRExistsTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error) {
-	var exists bool
+// prStatusTx - статус PR, ErrNotFound если его нет
+func (s *PullRequestPostgresStorage) prStatusTx(ctx context.Context, tx pgx.Tx, prID string) (string, error) {
+	var status string
 
 	var row pgx.Row
 	if tx != nil {
-		row = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", prID)
+		row = tx.QueryRow(ctx, "SELECT status FROM pull_requests WHERE pull_request_id = $1", prID)
 	} else {
-		row = s.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", prID)
+		row = s.pool.QueryRow(ctx, "SELECT status FROM pull_requests WHERE pull_request_id = $1", prID)
+	}
+
+	err := row.Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", models.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to check PR status: %w", err)
+	}
+
+	return status, nil
+}
+
+// ListPRsPageTx - keyset-страница: вместо OFFSET условие (ключ, id) после
+// курсора, поэтому глубокие страницы стоят столько же, сколько первая
+func (s *PullRequestPostgresStorage) ListPRsPageTx(ctx context.Context, tx pgx.Tx, q models.PRListQuery) ([]models.PullRequestShort, error) {
+	tx = ambientTx(ctx, tx)
+	sortColumn := "created_at"
+	if q.Sort == models.PRSortUpdatedAt {
+		sortColumn = "updated_at"
+	}
+	cmp, direction := "<", "DESC"
+	if q.Order == models.SortOrderAsc {
+		cmp, direction = ">", "ASC"
+	}
+
+	var conds []string
+	var args []interface{}
+	where := func(cond string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = len(args)
+		}
+		conds = append(conds, fmt.Sprintf(cond, placeholders...))
+	}
+
+	if q.ReviewerID != "" {
+		where("$%d = ANY(assigned_reviewers)", q.ReviewerID)
 	}
+	if q.AuthorID != "" {
+		where("author_id = $%d", q.AuthorID)
+	}
+	if q.Status != "" {
+		where("status = $%d", q.Status)
+	}
+	if q.CreatedFrom != nil {
+		where("created_at >= $%d", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		where("created_at < $%d", *q.CreatedTo)
+	}
+	if q.After != nil {
+		where("("+sortColumn+", pull_request_id) "+cmp+" ($%d, $%d)", q.After.Time, q.After.ID)
+	}
+
+	query := `
+		SELECT pull_request_id, pull_request_name, author_id, status, created_at, updated_at
+		FROM pull_requests
+	`
+	if len(conds) > 0 {
+		query += "WHERE " + strings.Join(conds, " AND ") + "\n"
+	}
+	args = append(args, q.Limit)
+	query += fmt.Sprintf("ORDER BY %s %s, pull_request_id %s LIMIT $%d", sortColumn, direction, direction, len(args))
 
-	err := row.Scan(&exists)
+	var rows pgx.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, args...)
+	} else {
+		rows, err = s.pool.Query(ctx, query, args...)
+	}
 	if err != nil {
-		return false, fmt.Errorf("failed to check PR existence: %w", err)
+		return nil, fmt.Errorf("failed to list PRs: %w", err)
 	}
+	defer rows.Close()
 
-	return exists, nil
+	prs := []models.PullRequestShort{}
+	for rows.Next() {
+		var pr models.PullRequestShort
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &pr.CreatedAt, &pr.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan PR: %w", err)
+		}
+		prs = append(prs, pr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PRs: %w", err)
+	}
+
+	return prs, nil
 }
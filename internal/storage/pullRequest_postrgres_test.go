@@ -52,8 +52,16 @@ func setupTestPRDB(t *testing.T) *pgxpool.Pool {
 			author_id TEXT NOT NULL,
 			status TEXT NOT NULL,
 			assigned_reviewers TEXT[],
+			project TEXT NOT NULL DEFAULT '',
+			tags TEXT[] NOT NULL DEFAULT '{}',
+			changed_files TEXT[] NOT NULL DEFAULT '{}',
 			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
-			merged_at TIMESTAMP WITH TIME ZONE
+			merged_at TIMESTAMP WITH TIME ZONE,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			stale_at TIMESTAMP WITH TIME ZONE,
+			closed_at TIMESTAMP WITH TIME ZONE,
+			sla_due_at TIMESTAMP WITH TIME ZONE,
+			sla_breached BOOLEAN NOT NULL DEFAULT false
 		)
 	`)
 	require.NoError(t, err)
@@ -135,4 +143,105 @@ func TestPullRequestPostgresStorage_Integration(t *testing.T) {
 		err = tx.Commit(ctx)
 		require.NoError(t, err)
 	})
+	t.Run("Close, merge closed and reopen", func(t *testing.T) {
+		tx, err := storage.PRBeginTx(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+
+		testPR := models.PullRequest{
+			PullRequestID:     "PR-CLOSE-TEST",
+			PullRequestName:   "Close Test",
+			AuthorID:          "user1",
+			Status:            "OPEN",
+			AssignedReviewers: []string{"user2"},
+			CreatedAt:         time.Now().UTC(),
+		}
+
+		err = storage.CreatePRTx(ctx, tx, testPR)
+		require.NoError(t, err)
+
+		ok, err := storage.ClosePRTx(ctx, tx, testPR.PullRequestID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		err = storage.MergePRTx(ctx, tx, testPR.PullRequestID)
+		assert.ErrorIs(t, err, models.ErrPRClosed)
+
+		ok, err = storage.ReopenPRTx(ctx, tx, testPR.PullRequestID, []string{"user2"}, nil)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		reopened, err := storage.GetPRByIDTx(ctx, tx, testPR.PullRequestID)
+		require.NoError(t, err)
+		assert.Equal(t, "OPEN", reopened.Status)
+		assert.Nil(t, reopened.ClosedAt)
+		assert.Equal(t, []string{"user2"}, reopened.AssignedReviewers)
+
+		ok, err = storage.ReopenPRTx(ctx, tx, testPR.PullRequestID, nil, nil)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		err = tx.Commit(ctx)
+		require.NoError(t, err)
+	})
+}
+
+// Keyset-страницы: строки с одинаковым created_at различает pull_request_id,
+// и обход страницами даёт тот же порядок, что и один запрос без лимита
+func TestPullRequestPostgresStorage_ListPRsPage(t *testing.T) {
+	pool := setupTestPRDB(t)
+	storage := NewPullRequestPostgresStorage(pool)
+	ctx := context.Background()
+
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	rows := []struct {
+		id      string
+		status  string
+		created time.Time
+	}{
+		{"PR-1", "OPEN", base},
+		{"PR-2", "MERGED", base.Add(time.Hour)},
+		{"PR-3", "OPEN", base.Add(time.Hour)},
+		{"PR-4", "OPEN", base.Add(time.Hour)},
+		{"PR-5", "OPEN", base.Add(2 * time.Hour)},
+	}
+	for _, r := range rows {
+		_, err := pool.Exec(ctx, `
+			INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, updated_at)
+			VALUES ($1, $1, 'author', $2, ARRAY['rev'], $3, $3)
+		`, r.id, r.status, r.created)
+		require.NoError(t, err)
+	}
+
+	walk := func(q models.PRListQuery) []string {
+		var ids []string
+		for page := 0; page < 10; page++ {
+			prs, err := storage.ListPRsPageTx(ctx, nil, q)
+			require.NoError(t, err)
+			for _, pr := range prs {
+				ids = append(ids, pr.PullRequestID)
+			}
+			if len(prs) < q.Limit {
+				return ids
+			}
+			last := prs[len(prs)-1]
+			q.After = &models.PRCursor{Time: *last.CreatedAt, ID: last.PullRequestID}
+		}
+		t.Fatal("pagination does not terminate")
+		return nil
+	}
+
+	assert.Equal(t, []string{"PR-5", "PR-4", "PR-3", "PR-2", "PR-1"}, walk(models.PRListQuery{Limit: 2}))
+	assert.Equal(t, []string{"PR-1", "PR-2", "PR-3", "PR-4", "PR-5"}, walk(models.PRListQuery{Limit: 2, Order: models.SortOrderAsc}))
+
+	// граница страницы внутри группы с одинаковым created_at
+	assert.Equal(t, []string{"PR-2", "PR-3", "PR-4"}, walk(models.PRListQuery{
+		Limit:       1,
+		Order:       models.SortOrderAsc,
+		CreatedFrom: &rows[1].created,
+		CreatedTo:   &rows[4].created,
+	}))
+
+	assert.Equal(t, []string{"PR-5", "PR-4", "PR-3", "PR-1"}, walk(models.PRListQuery{Limit: 3, Status: "OPEN", ReviewerID: "rev"}))
+	assert.Empty(t, walk(models.PRListQuery{Limit: 3, ReviewerID: "nobody"}))
 }
@@ -0,0 +1,270 @@
+package storage
+
+/*
+Основные функции для SCIM-провижининга:
+	1. Пользователи: список с фильтром по user_id, создание, смена профиля
+	2. Группы (= команды): список с фильтром по имени, получение, создание,
+	   удаление
+	3. Перенос пользователей между командами
+
+В отличие от GetTeamInfoTx группы читаются через LEFT JOIN - в SCIM группа
+может быть пустой.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SCIMPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewSCIMPostgresStorage(pool *pgxpool.Pool) *SCIMPostgresStorage {
+	return &SCIMPostgresStorage{pool: pool}
+}
+
+func (s *SCIMPostgresStorage) query(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) (pgx.Rows, error) {
+	if tx != nil {
+		return tx.Query(ctx, query, args...)
+	}
+	return s.pool.Query(ctx, query, args...)
+}
+
+func (s *SCIMPostgresStorage) exec(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	if tx != nil {
+		return tx.Exec(ctx, query, args...)
+	}
+	return s.pool.Exec(ctx, query, args...)
+}
+
+// ListUsersTx - страница пользователей и общее число; userID == "" - без фильтра
+func (s *SCIMPostgresStorage) ListUsersTx(ctx context.Context, tx pgx.Tx, userID string, offset int, limit int) ([]models.User, int, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT user_id, username, team_name, is_active, email, role, COUNT(*) OVER ()
+		FROM users
+		WHERE $1 = '' OR user_id = $1
+		ORDER BY user_id
+		OFFSET $2 LIMIT $3
+	`
+
+	rows, err := s.query(ctx, tx, query, userID, offset, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	var total int
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive, &u.Email, &u.Role, &total); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	// Пустая страница (OFFSET за пределами выборки или LIMIT 0) не вернёт и COUNT
+	if len(users) == 0 {
+		var row pgx.Row
+		countQuery := `SELECT COUNT(*) FROM users WHERE $1 = '' OR user_id = $1`
+		if tx != nil {
+			row = tx.QueryRow(ctx, countQuery, userID)
+		} else {
+			row = s.pool.QueryRow(ctx, countQuery, userID)
+		}
+		if err := row.Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count users: %w", err)
+		}
+	}
+
+	return users, total, nil
+}
+
+func (s *SCIMPostgresStorage) CreateUserTx(ctx context.Context, tx pgx.Tx, user models.User) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO users (user_id, username, team_name, is_active, email)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	result, err := s.exec(ctx, tx, query, user.UserID, user.Username, user.TeamName, user.IsActive, user.Email)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrUserExists
+	}
+
+	return nil
+}
+
+func (s *SCIMPostgresStorage) UpdateUserProfileTx(ctx context.Context, tx pgx.Tx, userID string, username string, email string) error {
+	tx = ambientTx(ctx, tx)
+	query := `UPDATE users SET username = $1, email = $2 WHERE user_id = $3`
+
+	result, err := s.exec(ctx, tx, query, username, email, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user profile: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SCIMPostgresStorage) EnsureTeamTx(ctx context.Context, tx pgx.Tx, teamName string) error {
+	tx = ambientTx(ctx, tx)
+	_, err := s.exec(ctx, tx, "INSERT INTO teams (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", teamName)
+	if err != nil {
+		return fmt.Errorf("failed to ensure team: %w", err)
+	}
+	return nil
+}
+
+func (s *SCIMPostgresStorage) CreateGroupTx(ctx context.Context, tx pgx.Tx, teamName string) error {
+	tx = ambientTx(ctx, tx)
+	result, err := s.exec(ctx, tx, "INSERT INTO teams (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", teamName)
+	if err != nil {
+		return fmt.Errorf("failed to create team: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrTeamExists
+	}
+
+	return nil
+}
+
+// ListGroupsTx - страница команд с участниками и общее число; teamName == "" - без фильтра
+func (s *SCIMPostgresStorage) ListGroupsTx(ctx context.Context, tx pgx.Tx, teamName string, offset int, limit int) ([]models.Team, int, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		WITH page AS (
+			SELECT name, COUNT(*) OVER () AS total
+			FROM teams
+			WHERE $1 = '' OR name = $1
+			ORDER BY name
+			OFFSET $2 LIMIT $3
+		)
+		SELECT p.name, p.total, u.user_id, u.username
+		FROM page p
+		LEFT JOIN users u ON u.team_name = p.name
+		ORDER BY p.name, u.user_id
+	`
+
+	rows, err := s.query(ctx, tx, query, teamName, offset, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query teams: %w", err)
+	}
+	defer rows.Close()
+
+	var teams []models.Team
+	var total int
+	for rows.Next() {
+		var name string
+		var userID, username *string
+		if err := rows.Scan(&name, &total, &userID, &username); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan team: %w", err)
+		}
+		if len(teams) == 0 || teams[len(teams)-1].TeamName != name {
+			teams = append(teams, models.Team{TeamName: name, Members: []models.User{}})
+		}
+		if userID != nil {
+			last := &teams[len(teams)-1]
+			last.Members = append(last.Members, models.User{UserID: *userID, Username: *username, TeamName: name})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating teams: %w", err)
+	}
+
+	if len(teams) == 0 {
+		var row pgx.Row
+		countQuery := `SELECT COUNT(*) FROM teams WHERE $1 = '' OR name = $1`
+		if tx != nil {
+			row = tx.QueryRow(ctx, countQuery, teamName)
+		} else {
+			row = s.pool.QueryRow(ctx, countQuery, teamName)
+		}
+		if err := row.Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count teams: %w", err)
+		}
+	}
+
+	return teams, total, nil
+}
+
+func (s *SCIMPostgresStorage) GetGroupTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.Team, error) {
+	tx = ambientTx(ctx, tx)
+	teams, _, err := s.ListGroupsTx(ctx, tx, teamName, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(teams) == 0 {
+		return nil, models.ErrNotFound
+	}
+	return &teams[0], nil
+}
+
+// MoveUsersToTeamTx возвращает число перенесённых: меньше len(userIDs) -
+// часть пользователей не существует
+func (s *SCIMPostgresStorage) MoveUsersToTeamTx(ctx context.Context, tx pgx.Tx, userIDs []string, teamName string) (int64, error) {
+	tx = ambientTx(ctx, tx)
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.exec(ctx, tx, "UPDATE users SET team_name = $1 WHERE user_id = ANY($2)", teamName, userIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move users: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// MoveTeamMembersTx переносит участников fromTeam, кроме keep, в toTeam
+func (s *SCIMPostgresStorage) MoveTeamMembersTx(ctx context.Context, tx pgx.Tx, fromTeam string, toTeam string, keep []string) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE users SET team_name = $1
+		WHERE team_name = $2 AND NOT (user_id = ANY(COALESCE($3, '{}'::TEXT[])))
+	`
+
+	if _, err := s.exec(ctx, tx, query, toTeam, fromTeam, keep); err != nil {
+		return fmt.Errorf("failed to move team members: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SCIMPostgresStorage) DeleteGroupTx(ctx context.Context, tx pgx.Tx, teamName string) error {
+	tx = ambientTx(ctx, tx)
+	result, err := s.exec(ctx, tx, "DELETE FROM teams WHERE name = $1", teamName)
+	if err != nil {
+		return fmt.Errorf("failed to delete team: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package storage
+
+/*
+Поиск по PR, пользователям и командам:
+	1. Полнотекстовый поиск по названию PR с префиксами слов (to_tsquery 'слово:*')
+	2. Опечатки - триграммы pg_trgm: word_similarity (оператор <%) находит
+	   слово запроса внутри длинного названия
+	3. Префикс идентификатора - ILIKE 'q%'
+
+Все условия покрыты GIN-индексами (gin_trgm_ops и tsvector). Релевантность -
+максимум из оценок: точное совпадение префикса id выше всего.
+
+Фича - если Tx - nil, то используем просто pool (только для чтения)
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SearchPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewSearchPostgresStorage(pool *pgxpool.Pool) *SearchPostgresStorage {
+	return &SearchPostgresStorage{pool: pool}
+}
+
+func (s *SearchPostgresStorage) query(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) (pgx.Rows, error) {
+	if tx != nil {
+		return tx.Query(ctx, query, args...)
+	}
+	return s.pool.Query(ctx, query, args...)
+}
+
+func (s *SearchPostgresStorage) SearchPRsTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.PRSearchHit, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT pull_request_id, pull_request_name, author_id, status, created_at, updated_at,
+			GREATEST(
+				CASE WHEN pull_request_id ILIKE $3 THEN 1.0 ELSE 0 END,
+				CASE WHEN $2 <> '' THEN ts_rank(to_tsvector('simple', pull_request_name), to_tsquery('simple', $2)) * 2 ELSE 0 END,
+				word_similarity($1, pull_request_name),
+				similarity($1, pull_request_id)
+			)::float8 AS rank
+		FROM pull_requests
+		WHERE ($2 <> '' AND to_tsvector('simple', pull_request_name) @@ to_tsquery('simple', $2))
+			OR $1 <% pull_request_name
+			OR pull_request_id % $1
+			OR pull_request_id ILIKE $3
+		ORDER BY rank DESC, pull_request_id
+		LIMIT $4
+	`
+
+	rows, err := s.query(ctx, tx, query, q.Text, q.TSQuery, q.Prefix, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search PRs: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []models.PRSearchHit{}
+	for rows.Next() {
+		var h models.PRSearchHit
+		if err := rows.Scan(&h.PullRequestID, &h.PullRequestName, &h.AuthorID, &h.Status, &h.CreatedAt, &h.UpdatedAt, &h.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan PR hit: %w", err)
+		}
+		hits = append(hits, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PR hits: %w", err)
+	}
+
+	return hits, nil
+}
+
+func (s *SearchPostgresStorage) SearchUsersTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.UserSearchHit, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT user_id, username, team_name, is_active,
+			GREATEST(
+				CASE WHEN user_id ILIKE $2 OR username ILIKE $2 THEN 1.0 ELSE 0 END,
+				word_similarity($1, username),
+				similarity($1, user_id)
+			)::float8 AS rank
+		FROM users
+		WHERE $1 <% username
+			OR user_id % $1
+			OR username ILIKE $2
+			OR user_id ILIKE $2
+		ORDER BY rank DESC, user_id
+		LIMIT $3
+	`
+
+	rows, err := s.query(ctx, tx, query, q.Text, q.Prefix, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []models.UserSearchHit{}
+	for rows.Next() {
+		var h models.UserSearchHit
+		if err := rows.Scan(&h.UserID, &h.Username, &h.TeamName, &h.IsActive, &h.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan user hit: %w", err)
+		}
+		hits = append(hits, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user hits: %w", err)
+	}
+
+	return hits, nil
+}
+
+func (s *SearchPostgresStorage) SearchTeamsTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.TeamSearchHit, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT name,
+			GREATEST(
+				CASE WHEN name ILIKE $2 THEN 1.0 ELSE 0 END,
+				word_similarity($1, name)
+			)::float8 AS rank
+		FROM teams
+		WHERE $1 <% name OR name ILIKE $2
+		ORDER BY rank DESC, name
+		LIMIT $3
+	`
+
+	rows, err := s.query(ctx, tx, query, q.Text, q.Prefix, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search teams: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []models.TeamSearchHit{}
+	for rows.Next() {
+		var h models.TeamSearchHit
+		if err := rows.Scan(&h.TeamName, &h.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan team hit: %w", err)
+		}
+		hits = append(hits, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating team hits: %w", err)
+	}
+
+	return hits, nil
+}
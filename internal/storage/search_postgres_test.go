@@ -0,0 +1,161 @@
+package storage
+
+/*
+Тесты поиска через контейнер с постгрес (pg_trgm и GIN-индексы как в
+init.sh):
+	1. Опечатка находится триграммами, слабое совпадение отсекается порогом
+	2. Префикс слова - через tsquery 'слово:*'
+	3. Префикс идентификатора ранжируется выше нечётких совпадений
+*/
+import (
+	"context"
+	"testing"
+
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func setupSearchDB(t *testing.T) *pgxpool.Pool {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15-alpine",
+		postgres.WithDatabase("test_db"),
+		postgres.WithUsername("test_user"),
+		postgres.WithPassword("test_password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2),
+		),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, container.Terminate(ctx))
+	})
+
+	connStr, err := container.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	_, err = pool.Exec(ctx, `
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+		CREATE TABLE teams (name TEXT PRIMARY KEY);
+
+		CREATE TABLE users (
+			user_id TEXT PRIMARY KEY,
+			username TEXT NOT NULL,
+			team_name TEXT NOT NULL REFERENCES teams(name),
+			is_active BOOLEAN NOT NULL DEFAULT true
+		);
+
+		CREATE TABLE pull_requests (
+			pull_request_id TEXT PRIMARY KEY,
+			pull_request_name TEXT NOT NULL,
+			author_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+
+		CREATE INDEX idx_pull_requests_name_fts ON pull_requests USING GIN(to_tsvector('simple', pull_request_name));
+		CREATE INDEX idx_pull_requests_name_trgm ON pull_requests USING GIN(pull_request_name gin_trgm_ops);
+		CREATE INDEX idx_pull_requests_id_trgm ON pull_requests USING GIN(pull_request_id gin_trgm_ops);
+		CREATE INDEX idx_users_username_trgm ON users USING GIN(username gin_trgm_ops);
+		CREATE INDEX idx_users_id_trgm ON users USING GIN(user_id gin_trgm_ops);
+		CREATE INDEX idx_teams_name_trgm ON teams USING GIN(name gin_trgm_ops);
+
+		INSERT INTO teams (name) VALUES ('backend'), ('backoffice'), ('frontend');
+		INSERT INTO users (user_id, username, team_name) VALUES
+			('u1', 'kowalski', 'backend'),
+			('u2', 'kowalczyk', 'backend'),
+			('u3', 'smith', 'frontend');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status) VALUES
+			('PR-101', 'Add search feature', 'u1', 'OPEN'),
+			('PR-102', 'Fix login bug', 'u1', 'OPEN'),
+			('PR-103', 'Search results pagination', 'u2', 'MERGED'),
+			('PR-104', 'Research notes', 'u3', 'OPEN'),
+			('PR-200', 'Refactor payments', 'u3', 'OPEN');
+	`)
+	require.NoError(t, err)
+
+	return pool
+}
+
+func TestSearchPostgresStorage(t *testing.T) {
+	pool := setupSearchDB(t)
+	storage := NewSearchPostgresStorage(pool)
+	ctx := context.Background()
+
+	prIDs := func(hits []models.PRSearchHit) []string {
+		ids := []string{}
+		for _, h := range hits {
+			ids = append(ids, h.PullRequestID)
+		}
+		return ids
+	}
+
+	t.Run("PR name: full word beats a fuzzy match inside another word", func(t *testing.T) {
+		hits, err := storage.SearchPRsTx(ctx, nil, models.SearchQuery{Text: "search", TSQuery: "search:*", Prefix: "search%", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"PR-101", "PR-103", "PR-104"}, prIDs(hits))
+		assert.Greater(t, hits[1].Rank, hits[2].Rank)
+	})
+
+	t.Run("PR name: typo", func(t *testing.T) {
+		hits, err := storage.SearchPRsTx(ctx, nil, models.SearchQuery{Text: "serch", TSQuery: "serch:*", Prefix: "serch%", Limit: 10})
+		require.NoError(t, err)
+		assert.Subset(t, prIDs(hits), []string{"PR-101", "PR-103"})
+		assert.NotContains(t, prIDs(hits), "PR-102")
+	})
+
+	t.Run("PR name: word prefix", func(t *testing.T) {
+		hits, err := storage.SearchPRsTx(ctx, nil, models.SearchQuery{Text: "pag", TSQuery: "pag:*", Prefix: "pag%", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"PR-103"}, prIDs(hits))
+	})
+
+	t.Run("PR id prefix ranks first", func(t *testing.T) {
+		hits, err := storage.SearchPRsTx(ctx, nil, models.SearchQuery{Text: "PR-10", TSQuery: "", Prefix: "PR-10%", Limit: 10})
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, len(hits), 4)
+		assert.Equal(t, []string{"PR-101", "PR-102", "PR-103", "PR-104"}, prIDs(hits)[:4])
+		assert.Equal(t, 1.0, hits[0].Rank)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		hits, err := storage.SearchUsersTx(ctx, nil, models.SearchQuery{Text: "kowalsky", Prefix: "kowalsky%", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, hits, 1, "kowalczyk is below the similarity threshold")
+		assert.Equal(t, "u1", hits[0].UserID)
+
+		hits, err = storage.SearchUsersTx(ctx, nil, models.SearchQuery{Text: "kowal", Prefix: "kowal%", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, hits, 2)
+		assert.Equal(t, "u1", hits[0].UserID)
+		assert.Equal(t, "u2", hits[1].UserID)
+	})
+
+	t.Run("teams", func(t *testing.T) {
+		hits, err := storage.SearchTeamsTx(ctx, nil, models.SearchQuery{Text: "back", Prefix: "back%", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, hits, 2)
+		assert.Equal(t, "backend", hits[0].TeamName)
+		assert.Equal(t, "backoffice", hits[1].TeamName)
+
+		hits, err = storage.SearchTeamsTx(ctx, nil, models.SearchQuery{Text: "bakend", Prefix: "bakend%", Limit: 10})
+		require.NoError(t, err)
+		require.NotEmpty(t, hits)
+		assert.Equal(t, "backend", hits[0].TeamName)
+	})
+}
@@ -0,0 +1,224 @@
+package storage
+
+/*
+Основные функции:
+	1. Настройки SLA команды (сохранение / получение)
+	2. Поиск открытых PR с истёкшим сроком SLA
+	3. Отметка нарушения SLA (один раз на срок)
+	4. Журнал эскалаций
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SLAPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewSLAPostgresStorage(pool *pgxpool.Pool) *SLAPostgresStorage {
+	return &SLAPostgresStorage{pool: pool}
+}
+
+func (s *SLAPostgresStorage) SetTeamSLATx(ctx context.Context, tx pgx.Tx, sla models.TeamSLA) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO team_sla (team_name, response_hours, policy, work_start, work_end, timezone, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (team_name)
+		DO UPDATE SET response_hours = EXCLUDED.response_hours, policy = EXCLUDED.policy,
+			work_start = EXCLUDED.work_start, work_end = EXCLUDED.work_end,
+			timezone = EXCLUDED.timezone, updated_at = EXCLUDED.updated_at
+	`
+
+	args := []any{sla.TeamName, sla.ResponseHours, sla.Policy, sla.WorkStart, sla.WorkEnd, sla.Timezone}
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, args...)
+	} else {
+		_, err = s.pool.Exec(ctx, query, args...)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to save team SLA: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SLAPostgresStorage) GetTeamSLATx(ctx context.Context, tx pgx.Tx, teamName string) (*models.TeamSLA, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT team_name, response_hours, policy, work_start, work_end, timezone, updated_at
+		FROM team_sla
+		WHERE team_name = $1
+	`
+
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, query, teamName)
+	} else {
+		row = s.pool.QueryRow(ctx, query, teamName)
+	}
+
+	var sla models.TeamSLA
+	err := row.Scan(&sla.TeamName, &sla.ResponseHours, &sla.Policy, &sla.WorkStart, &sla.WorkEnd, &sla.Timezone, &sla.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get team SLA: %w", err)
+	}
+
+	return &sla, nil
+}
+
+func (s *SLAPostgresStorage) ListSLADueTx(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.SLADuePR, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status,
+			pr.assigned_reviewers, pr.sla_due_at, u.team_name
+		FROM pull_requests pr
+		JOIN users u ON u.user_id = pr.author_id
+		WHERE pr.status = 'OPEN' AND NOT pr.sla_breached AND pr.sla_due_at <= $1
+		ORDER BY pr.sla_due_at
+		LIMIT $2
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, now, limit)
+	} else {
+		rows, err = s.pool.Query(ctx, query, now, limit)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SLA due PRs: %w", err)
+	}
+	defer rows.Close()
+
+	var due []models.SLADuePR
+	for rows.Next() {
+		var d models.SLADuePR
+		err := rows.Scan(
+			&d.PullRequest.PullRequestID,
+			&d.PullRequest.PullRequestName,
+			&d.PullRequest.AuthorID,
+			&d.PullRequest.Status,
+			&d.PullRequest.AssignedReviewers,
+			&d.PullRequest.SLADueAt,
+			&d.TeamName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan SLA due PR: %w", err)
+		}
+		due = append(due, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating SLA due PRs: %w", err)
+	}
+
+	return due, nil
+}
+
+// MarkSLABreachedTx - false, если PR уже отмечен (например, другим экземпляром)
+func (s *SLAPostgresStorage) MarkSLABreachedTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE pull_requests
+		SET sla_breached = true
+		WHERE pull_request_id = $1 AND NOT sla_breached
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, prID)
+	} else {
+		result, err = s.pool.Exec(ctx, query, prID)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to mark SLA breached: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *SLAPostgresStorage) AddEscalationTx(ctx context.Context, tx pgx.Tx, e models.SLAEscalation) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO sla_escalations (pull_request_id, team_name, action, reviewer_id, new_reviewer_id, due_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	args := []any{e.PullRequestID, e.TeamName, e.Action, e.ReviewerID, e.NewReviewerID, e.DueAt}
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, args...)
+	} else {
+		_, err = s.pool.Exec(ctx, query, args...)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to record escalation: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SLAPostgresStorage) ListEscalationsTx(ctx context.Context, tx pgx.Tx, teamName string, limit int) ([]models.SLAEscalation, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT id, pull_request_id, team_name, action, reviewer_id, new_reviewer_id, due_at, created_at
+		FROM sla_escalations
+		WHERE team_name = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, teamName, limit)
+	} else {
+		rows, err = s.pool.Query(ctx, query, teamName, limit)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query escalations: %w", err)
+	}
+	defer rows.Close()
+
+	var escalations []models.SLAEscalation
+	for rows.Next() {
+		var e models.SLAEscalation
+		err := rows.Scan(&e.ID, &e.PullRequestID, &e.TeamName, &e.Action, &e.ReviewerID, &e.NewReviewerID, &e.DueAt, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan escalation: %w", err)
+		}
+		escalations = append(escalations, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating escalations: %w", err)
+	}
+
+	return escalations, nil
+}
@@ -0,0 +1,197 @@
+package storage
+
+/*
+Основные функции:
+	1. Политика устаревания PR для команды (сохранение / получение)
+	2. Поиск открытых PR, которые пора пометить stale или закрыть
+	3. Пометка stale и закрытие с освобождением ревьюеров
+
+Пометка и закрытие проверяют состояние в WHERE: если PR успели изменить
+или другой экземпляр уже всё сделал, возвращается false.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StalePostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewStalePostgresStorage(pool *pgxpool.Pool) *StalePostgresStorage {
+	return &StalePostgresStorage{pool: pool}
+}
+
+func (s *StalePostgresStorage) SetStalePolicyTx(ctx context.Context, tx pgx.Tx, policy models.StalePolicy) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO team_stale_policy (team_name, stale_after_days, close_after_days, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (team_name)
+		DO UPDATE SET stale_after_days = EXCLUDED.stale_after_days,
+			close_after_days = EXCLUDED.close_after_days, updated_at = EXCLUDED.updated_at
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, policy.TeamName, policy.StaleAfterDays, policy.CloseAfterDays)
+	} else {
+		_, err = s.pool.Exec(ctx, query, policy.TeamName, policy.StaleAfterDays, policy.CloseAfterDays)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to save stale policy: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StalePostgresStorage) GetStalePolicyTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.StalePolicy, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT team_name, stale_after_days, close_after_days, updated_at
+		FROM team_stale_policy
+		WHERE team_name = $1
+	`
+
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, query, teamName)
+	} else {
+		row = s.pool.QueryRow(ctx, query, teamName)
+	}
+
+	var policy models.StalePolicy
+	err := row.Scan(&policy.TeamName, &policy.StaleAfterDays, &policy.CloseAfterDays, &policy.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get stale policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+// ListStaleCandidatesTx - открытые PR, у которых истёк срок до stale
+// (и они ещё не stale) или срок до закрытия (и они уже stale)
+func (s *StalePostgresStorage) ListStaleCandidatesTx(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.StaleCandidate, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status,
+			pr.assigned_reviewers, pr.updated_at, pr.stale_at,
+			u.team_name, p.stale_after_days, p.close_after_days
+		FROM pull_requests pr
+		JOIN users u ON u.user_id = pr.author_id
+		JOIN team_stale_policy p ON p.team_name = u.team_name
+		WHERE pr.status = 'OPEN' AND (
+			(pr.stale_at IS NULL AND pr.updated_at <= $1 - p.stale_after_days * INTERVAL '1 day')
+			OR (pr.stale_at IS NOT NULL AND p.close_after_days > 0
+				AND pr.stale_at <= $1 - p.close_after_days * INTERVAL '1 day')
+		)
+		ORDER BY pr.updated_at
+		LIMIT $2
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, now, limit)
+	} else {
+		rows, err = s.pool.Query(ctx, query, now, limit)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []models.StaleCandidate
+	for rows.Next() {
+		var c models.StaleCandidate
+		err := rows.Scan(
+			&c.PullRequest.PullRequestID,
+			&c.PullRequest.PullRequestName,
+			&c.PullRequest.AuthorID,
+			&c.PullRequest.Status,
+			&c.PullRequest.AssignedReviewers,
+			&c.PullRequest.UpdatedAt,
+			&c.PullRequest.StaleAt,
+			&c.TeamName,
+			&c.Policy.StaleAfterDays,
+			&c.Policy.CloseAfterDays,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stale candidate: %w", err)
+		}
+		c.Policy.TeamName = c.TeamName
+		candidates = append(candidates, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stale candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// MarkPRStaleTx помечает PR, если он всё ещё открыт, не stale и не менялся с updatedAt
+func (s *StalePostgresStorage) MarkPRStaleTx(ctx context.Context, tx pgx.Tx, prID string, updatedAt time.Time) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE pull_requests
+		SET stale_at = NOW()
+		WHERE pull_request_id = $1 AND status = 'OPEN' AND stale_at IS NULL AND updated_at = $2
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, prID, updatedAt)
+	} else {
+		result, err = s.pool.Exec(ctx, query, prID, updatedAt)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to mark PR stale: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// ClosePRTx закрывает stale PR и снимает с него всех ревьюеров
+func (s *StalePostgresStorage) ClosePRTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE pull_requests
+		SET status = 'CLOSED', closed_at = NOW(), updated_at = NOW(),
+			assigned_reviewers = '{}', sla_due_at = NULL
+		WHERE pull_request_id = $1 AND status = 'OPEN' AND stale_at IS NOT NULL
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, prID)
+	} else {
+		result, err = s.pool.Exec(ctx, query, prID)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to close PR: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
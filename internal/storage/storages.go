@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"test-task/internal/models"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -11,20 +12,134 @@ type PullReqStorage interface {
 	CreatePRTx(ctx context.Context, tx pgx.Tx, pr models.PullRequest) error
 	GetPRByIDTx(ctx context.Context, tx pgx.Tx, prID string) (*models.PullRequest, error)
 	MergePRTx(ctx context.Context, tx pgx.Tx, prID string) error
+	UpdatePRDetailsTx(ctx context.Context, tx pgx.Tx, prID string, name string, project string) (bool, error)
+	ClosePRTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error)
+	ReopenPRTx(ctx context.Context, tx pgx.Tx, prID string, reviewers []string, slaDueAt *time.Time) (bool, error)
 	UpdatePRReviewersTx(ctx context.Context, tx pgx.Tx, prID string, reviewers []string) error
 	GetPRsByReviewerTx(ctx context.Context, tx pgx.Tx, userID string) ([]models.PullRequestShort, error)
+	UpdatePRSLATx(ctx context.Context, tx pgx.Tx, prID string, dueAt *time.Time) error
 
 	PRBeginTx(ctx context.Context) (pgx.Tx, error)
+	ListPRsPageTx(ctx context.Context, tx pgx.Tx, q models.PRListQuery) ([]models.PullRequestShort, error)
 }
 
 type TeamStorage interface {
 	CreateTeamTx(ctx context.Context, tx pgx.Tx, team models.Team) error
 	GetTeamInfoTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.Team, error)
 	TeamBeginTx(ctx context.Context) (pgx.Tx, error)
+	TeamExistsTx(ctx context.Context, tx pgx.Tx, teamName string) (bool, error)
+	AddTeamMemberTx(ctx context.Context, tx pgx.Tx, member models.User) error
+	MoveTeamMemberTx(ctx context.Context, tx pgx.Tx, userID string, fromTeam string, toTeam string) (bool, error)
 }
 
 type UserStorage interface {
 	GetUserTx(ctx context.Context, tx pgx.Tx, userID string) (*models.User, error)
 	UpdateUserActiveTx(ctx context.Context, tx pgx.Tx, userID string, isActive bool) error
+	UpdateUserRoleTx(ctx context.Context, tx pgx.Tx, userID string, role string) error
+	UpdateUserDigestOptOutTx(ctx context.Context, tx pgx.Tx, userID string, optOut bool) error
+	ListDigestRecipientsTx(ctx context.Context, tx pgx.Tx) ([]models.User, error)
+	ClaimDigestRunTx(ctx context.Context, tx pgx.Tx, day time.Time) (bool, error)
 	UserBeginTx(ctx context.Context) (pgx.Tx, error)
+	ListUsersPageTx(ctx context.Context, tx pgx.Tx, q models.UserListQuery) ([]models.User, error)
+}
+
+type CodeOwnersStorage interface {
+	SaveCodeOwnersTx(ctx context.Context, tx pgx.Tx, co models.CodeOwners) error
+	GetCodeOwnersTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.CodeOwners, error)
+}
+
+type WebhookDeliveryStorage interface {
+	DeliveryBeginTx(ctx context.Context) (pgx.Tx, error)
+	ClaimDeliveryTx(ctx context.Context, tx pgx.Tx, source string, deliveryID string) (bool, error)
+}
+
+type SubscriptionStorage interface {
+	CreateSubscriptionTx(ctx context.Context, tx pgx.Tx, sub models.Subscription) (*models.Subscription, error)
+	ListSubscriptionsTx(ctx context.Context, tx pgx.Tx) ([]models.Subscription, error)
+	DeleteSubscriptionTx(ctx context.Context, tx pgx.Tx, id int64) error
+
+	EnqueueDeliveriesTx(ctx context.Context, tx pgx.Tx, event models.Event, payload []byte) (int64, error)
+	ClaimDueDeliveriesTx(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]models.OutboundDelivery, error)
+	MarkDeliveredTx(ctx context.Context, tx pgx.Tx, id int64, statusCode int) error
+	MarkFailedTx(ctx context.Context, tx pgx.Tx, id int64, statusCode *int, lastError string, nextAttemptAt *time.Time) error
+	ListDeliveriesTx(ctx context.Context, tx pgx.Tx, subscriptionID int64, status string, limit int) ([]models.OutboundDelivery, error)
+}
+
+type OutboxStorage interface {
+	AddEventTx(ctx context.Context, tx pgx.Tx, event models.Event) error
+	ClaimOutboxTx(ctx context.Context, tx pgx.Tx, sinks []string, limit int, lease time.Duration) ([]models.OutboxDelivery, error)
+	MarkOutboxDeliveredTx(ctx context.Context, tx pgx.Tx, outboxID int64, sink string) error
+	MarkOutboxFailedTx(ctx context.Context, tx pgx.Tx, outboxID int64, sink string, lastError string, availableAt *time.Time) error
+	ListOutboxDeadTx(ctx context.Context, tx pgx.Tx, limit int) ([]models.OutboxDelivery, error)
+	OutboxBeginTx(ctx context.Context) (pgx.Tx, error)
+	ListenOutbox(ctx context.Context, notify chan<- struct{})
+}
+
+type ChatTargetStorage interface {
+	SetChatTargetTx(ctx context.Context, tx pgx.Tx, target models.ChatTarget) error
+	DeleteChatTargetTx(ctx context.Context, tx pgx.Tx, scope string, targetID string) error
+	ListChatTargetsTx(ctx context.Context, tx pgx.Tx) ([]models.ChatTarget, error)
+	FindChatTargetsTx(ctx context.Context, tx pgx.Tx, userIDs []string, teamName string) ([]models.ChatTarget, error)
+}
+
+type SLAStorage interface {
+	SetTeamSLATx(ctx context.Context, tx pgx.Tx, sla models.TeamSLA) error
+	GetTeamSLATx(ctx context.Context, tx pgx.Tx, teamName string) (*models.TeamSLA, error)
+
+	ListSLADueTx(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.SLADuePR, error)
+	MarkSLABreachedTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error)
+	AddEscalationTx(ctx context.Context, tx pgx.Tx, e models.SLAEscalation) error
+	ListEscalationsTx(ctx context.Context, tx pgx.Tx, teamName string, limit int) ([]models.SLAEscalation, error)
+}
+
+type StaleStorage interface {
+	SetStalePolicyTx(ctx context.Context, tx pgx.Tx, policy models.StalePolicy) error
+	GetStalePolicyTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.StalePolicy, error)
+
+	ListStaleCandidatesTx(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.StaleCandidate, error)
+	MarkPRStaleTx(ctx context.Context, tx pgx.Tx, prID string, updatedAt time.Time) (bool, error)
+	ClosePRTx(ctx context.Context, tx pgx.Tx, prID string) (bool, error)
+}
+
+type AuditStorage interface {
+	AddAuditTx(ctx context.Context, tx pgx.Tx, entry models.AuditEntry) error
+	ListAuditTx(ctx context.Context, tx pgx.Tx, entity string, entityID string, beforeID int64, limit int) ([]models.AuditEntry, error)
+}
+
+type APITokenStorage interface {
+	CreateAPITokenTx(ctx context.Context, tx pgx.Tx, token models.APIToken, tokenHash string) (*models.APIToken, error)
+	GetAPITokenByHashTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.APIToken, error)
+	ListAPITokensTx(ctx context.Context, tx pgx.Tx) ([]models.APIToken, error)
+	RevokeAPITokenTx(ctx context.Context, tx pgx.Tx, id int64) error
+}
+
+type SCIMStorage interface {
+	ListUsersTx(ctx context.Context, tx pgx.Tx, userID string, offset int, limit int) ([]models.User, int, error)
+	CreateUserTx(ctx context.Context, tx pgx.Tx, user models.User) error
+	UpdateUserProfileTx(ctx context.Context, tx pgx.Tx, userID string, username string, email string) error
+	EnsureTeamTx(ctx context.Context, tx pgx.Tx, teamName string) error
+	CreateGroupTx(ctx context.Context, tx pgx.Tx, teamName string) error
+	ListGroupsTx(ctx context.Context, tx pgx.Tx, teamName string, offset int, limit int) ([]models.Team, int, error)
+	GetGroupTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.Team, error)
+	MoveUsersToTeamTx(ctx context.Context, tx pgx.Tx, userIDs []string, teamName string) (int64, error)
+	MoveTeamMembersTx(ctx context.Context, tx pgx.Tx, fromTeam string, toTeam string, keep []string) error
+	DeleteGroupTx(ctx context.Context, tx pgx.Tx, teamName string) error
+}
+
+type IdempotencyStorage interface {
+	ClaimIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string, fingerprint string, expiresAt time.Time) (*models.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string, statusCode int, contentType string, body []byte) error
+	ReleaseIdempotencyKeyTx(ctx context.Context, tx pgx.Tx, scope string, key string) error
+	DeleteExpiredIdempotencyKeysTx(ctx context.Context, tx pgx.Tx, now time.Time) (int64, error)
+}
+
+type TimelineStorage interface {
+	AddTimelineEventsTx(ctx context.Context, tx pgx.Tx, events []models.TimelineEvent) error
+	ListTimelineTx(ctx context.Context, tx pgx.Tx, prID string) ([]models.TimelineEvent, error)
+}
+
+type SearchStorage interface {
+	SearchPRsTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.PRSearchHit, error)
+	SearchUsersTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.UserSearchHit, error)
+	SearchTeamsTx(ctx context.Context, tx pgx.Tx, q models.SearchQuery) ([]models.TeamSearchHit, error)
 }
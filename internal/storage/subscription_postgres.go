@@ -0,0 +1,313 @@
+package storage
+
+/*
+Основные функции:
+	1. Подписки на исходящие вебхуки (создание, список, удаление)
+	2. Постановка доставок события в очередь по подходящим подпискам
+	3. Захват доставок, которым пора уходить
+	4. Отметка результата доставки
+	5. Журнал доставок
+
+Захват - UPDATE ... FOR UPDATE SKIP LOCKED со сдвигом next_attempt_at на
+время аренды: несколько экземпляров сервиса не отправят одну доставку
+одновременно, а упавший посреди отправки экземпляр не потеряет её.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SubscriptionPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewSubscriptionPostgresStorage(pool *pgxpool.Pool) *SubscriptionPostgresStorage {
+	return &SubscriptionPostgresStorage{pool: pool}
+}
+
+func (s *SubscriptionPostgresStorage) CreateSubscriptionTx(ctx context.Context, tx pgx.Tx, sub models.Subscription) (*models.Subscription, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, events)
+		VALUES ($1, $2, COALESCE($3, '{}'::TEXT[]))
+		RETURNING id, url, secret, events, created_at
+	`
+
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, query, sub.URL, sub.Secret, sub.Events)
+	} else {
+		row = s.pool.QueryRow(ctx, query, sub.URL, sub.Secret, sub.Events)
+	}
+
+	var created models.Subscription
+	err := row.Scan(&created.ID, &created.URL, &created.Secret, &created.Events, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return &created, nil
+}
+
+func (s *SubscriptionPostgresStorage) ListSubscriptionsTx(ctx context.Context, tx pgx.Tx) ([]models.Subscription, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT id, url, secret, events, created_at
+		FROM webhook_subscriptions
+		ORDER BY id
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query)
+	} else {
+		rows, err = s.pool.Query(ctx, query)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []models.Subscription
+	for rows.Next() {
+		var sub models.Subscription
+		if err := rows.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.Events, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+func (s *SubscriptionPostgresStorage) DeleteSubscriptionTx(ctx context.Context, tx pgx.Tx, id int64) error {
+	tx = ambientTx(ctx, tx)
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, id)
+	} else {
+		result, err = s.pool.Exec(ctx, query, id)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+// EnqueueDeliveriesTx создаёт по доставке на каждую подписку, чей фильтр
+// пропускает тип события. Пустой фильтр - все события.
+func (s *SubscriptionPostgresStorage) EnqueueDeliveriesTx(ctx context.Context, tx pgx.Tx, event models.Event, payload []byte) (int64, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO webhook_outbound_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3
+		FROM webhook_subscriptions
+		WHERE cardinality(events) = 0 OR $2 = ANY(events)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, event.ID, event.Type, payload)
+	} else {
+		result, err = s.pool.Exec(ctx, query, event.ID, event.Type, payload)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue deliveries: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (s *SubscriptionPostgresStorage) ClaimDueDeliveriesTx(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]models.OutboundDelivery, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE webhook_outbound_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		FROM webhook_subscriptions sub
+		WHERE sub.id = d.subscription_id
+			AND d.id IN (
+				SELECT id FROM webhook_outbound_deliveries
+				WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status,
+			d.attempts, d.created_at, sub.url, sub.secret
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, limit, lease.Milliseconds())
+	} else {
+		rows, err = s.pool.Query(ctx, query, limit, lease.Milliseconds())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.OutboundDelivery
+	for rows.Next() {
+		var d models.OutboundDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.CreatedAt,
+			&d.URL,
+			&d.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *SubscriptionPostgresStorage) MarkDeliveredTx(ctx context.Context, tx pgx.Tx, id int64, statusCode int) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE webhook_outbound_deliveries
+		SET status = 'DELIVERED', attempts = attempts + 1, last_status_code = $2,
+			last_error = '', delivered_at = NOW()
+		WHERE id = $1
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, id, statusCode)
+	} else {
+		_, err = s.pool.Exec(ctx, query, id, statusCode)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to mark delivery delivered: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailedTx - nextAttemptAt == nil переводит доставку в DEAD
+func (s *SubscriptionPostgresStorage) MarkFailedTx(ctx context.Context, tx pgx.Tx, id int64, statusCode *int, lastError string, nextAttemptAt *time.Time) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE webhook_outbound_deliveries
+		SET attempts = attempts + 1,
+			last_status_code = $2,
+			last_error = $3,
+			status = CASE WHEN $4::TIMESTAMPTZ IS NULL THEN 'DEAD' ELSE 'PENDING' END,
+			next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE id = $1
+	`
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, id, statusCode, lastError, nextAttemptAt)
+	} else {
+		_, err = s.pool.Exec(ctx, query, id, statusCode, lastError, nextAttemptAt)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to mark delivery failed: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SubscriptionPostgresStorage) ListDeliveriesTx(ctx context.Context, tx pgx.Tx, subscriptionID int64, status string, limit int) ([]models.OutboundDelivery, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT id, subscription_id, event_id, event_type, status, attempts,
+			last_status_code, last_error, next_attempt_at, created_at, delivered_at
+		FROM webhook_outbound_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, subscriptionID, status, limit)
+	} else {
+		rows, err = s.pool.Query(ctx, query, subscriptionID, status, limit)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.OutboundDelivery
+	for rows.Next() {
+		var d models.OutboundDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Status,
+			&d.Attempts,
+			&d.LastStatusCode,
+			&d.LastError,
+			&d.NextAttemptAt,
+			&d.CreatedAt,
+			&d.DeliveredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
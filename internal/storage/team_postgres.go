@@ -5,6 +5,7 @@ package storage
 	1. Создание команды
 	2. Получение информации о команде
 	3. Создать транзакцию
+	4. Добавить участника в команду / перенести из неё в другую
 
 Создание команды проихсодит атомарно.
 При создании происходит проверка через SQL запрос на то, существет
@@ -21,6 +22,7 @@ import (
 	"test-task/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -33,17 +35,30 @@ func NewTeamPostgresStorage(pool *pgxpool.Pool) *TeamPostgresStorage {
 }
 
 func (s *TeamPostgresStorage) TeamBeginTx(ctx context.Context) (pgx.Tx, error) {
-	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{
+	return beginTx(ctx, s.pool, pgx.TxOptions{
 		IsoLevel:   pgx.Serializable,
 		AccessMode: pgx.ReadWrite,
 	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+}
+
+func (s *TeamPostgresStorage) TeamExistsTx(ctx context.Context, tx pgx.Tx, teamName string) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	var row pgx.Row
+	if tx != nil {
+		row = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE name = $1)", teamName)
+	} else {
+		row = s.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE name = $1)", teamName)
 	}
-	return tx, nil
+
+	var exists bool
+	if err := row.Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check team existence: %w", err)
+	}
+	return exists, nil
 }
 
 func (s *TeamPostgresStorage) CreateTeamTx(ctx context.Context, tx pgx.Tx, team models.Team) error {
+	tx = ambientTx(ctx, tx)
 	var exists bool
 	var row pgx.Row
 	if tx != nil {
@@ -69,45 +84,90 @@ func (s *TeamPostgresStorage) CreateTeamTx(ctx context.Context, tx pgx.Tx, team
 		return fmt.Errorf("failed to create team: %w", err)
 	}
 
+	// участники уходят одним пакетом - при импорте команд это основная нагрузка
+	batch := &pgx.Batch{}
 	for _, member := range team.Members {
-		if err := s.createUserTx(ctx, tx, member); err != nil {
-			return fmt.Errorf("failed to create user %s: %w", member.UserID, err)
-		}
+		batch.Queue(upsertUserQuery, member.UserID, member.Username, member.TeamName, member.IsActive, member.Skills, member.PathGlobs, member.Email)
 	}
+	if err := sendBatch(ctx, tx, s.pool, batch); err != nil {
+		return fmt.Errorf("failed to create/update users: %w", err)
+	}
+
+	return nil
+}
 
+// upsertUserQuery: skills, path_globs и email, не переданные в запросе (NULL /
+// пустая строка), у существующего пользователя не затираются. Пустой массив
+// передан явно - значит очистить.
+const upsertUserQuery = `
+	INSERT INTO users (user_id, username, team_name, is_active, skills, path_globs, email)
+	VALUES ($1, $2, $3, $4, COALESCE($5, '{}'::TEXT[]), COALESCE($6, '{}'::TEXT[]), $7)
+	ON CONFLICT (user_id)
+	DO UPDATE SET username = EXCLUDED.username, team_name = EXCLUDED.team_name, is_active = EXCLUDED.is_active,
+		skills = COALESCE($5, users.skills),
+		path_globs = COALESCE($6, users.path_globs),
+		email = COALESCE(NULLIF($7, ''), users.email)
+`
+
+// AddTeamMemberTx добавляет пользователя в команду или переносит в неё
+// существующего; роль не меняется
+func (s *TeamPostgresStorage) AddTeamMemberTx(ctx context.Context, tx pgx.Tx, member models.User) error {
+	tx = ambientTx(ctx, tx)
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, upsertUserQuery, member.UserID, member.Username, member.TeamName, member.IsActive, member.Skills, member.PathGlobs, member.Email)
+	} else {
+		_, err = s.pool.Exec(ctx, upsertUserQuery, member.UserID, member.Username, member.TeamName, member.IsActive, member.Skills, member.PathGlobs, member.Email)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add team member: %w", err)
+	}
 	return nil
 }
 
-func (s *TeamPostgresStorage) createUserTx(ctx context.Context, tx pgx.Tx, user models.User) error {
+// MoveTeamMemberTx переносит участника fromTeam в toTeam (создаётся при
+// необходимости). Лид становится обычным участником - лидом он был только
+// своей команды. false - пользователь не состоит в fromTeam.
+func (s *TeamPostgresStorage) MoveTeamMemberTx(ctx context.Context, tx pgx.Tx, userID string, fromTeam string, toTeam string) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	const ensureTeam = "INSERT INTO teams (name) VALUES ($1) ON CONFLICT (name) DO NOTHING"
 	query := `
-		INSERT INTO users (user_id, username, team_name, is_active) 
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (user_id) 
-		DO UPDATE SET username = EXCLUDED.username, team_name = EXCLUDED.team_name, is_active = EXCLUDED.is_active
+		UPDATE users
+		SET team_name = $3, role = CASE WHEN role = 'lead' THEN 'member' ELSE role END
+		WHERE user_id = $1 AND team_name = $2
 	`
 
+	var result pgconn.CommandTag
+	var err error
 	if tx != nil {
-		_, err := tx.Exec(ctx, query, user.UserID, user.Username, user.TeamName, user.IsActive)
-		if err != nil {
-			return fmt.Errorf("failed to create/update user: %w", err)
+		if _, err = tx.Exec(ctx, ensureTeam, toTeam); err == nil {
+			result, err = tx.Exec(ctx, query, userID, fromTeam, toTeam)
 		}
 	} else {
-		_, err := s.pool.Exec(ctx, query, user.UserID, user.Username, user.TeamName, user.IsActive)
-		if err != nil {
-			return fmt.Errorf("failed to create/update user: %w", err)
+		if _, err = s.pool.Exec(ctx, ensureTeam, toTeam); err == nil {
+			result, err = s.pool.Exec(ctx, query, userID, fromTeam, toTeam)
 		}
 	}
-	return nil
+	if err != nil {
+		return false, fmt.Errorf("failed to move team member: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
 }
 
 func (s *TeamPostgresStorage) GetTeamInfoTx(ctx context.Context, tx pgx.Tx, teamName string) (*models.Team, error) {
+	tx = ambientTx(ctx, tx)
 	query := `
         SELECT 
             t.name as team_name, 
             u.user_id, 
             u.username, 
             u.team_name, 
-            u.is_active
+            u.is_active,
+            u.skills,
+            u.path_globs,
+            u.email,
+            u.digest_opt_out,
+            u.role
         FROM teams t
         JOIN users u ON u.team_name = t.name
         WHERE t.name = $1
@@ -139,6 +199,11 @@ func (s *TeamPostgresStorage) GetTeamInfoTx(ctx context.Context, tx pgx.Tx, team
 			&user.Username,
 			&user.TeamName,
 			&user.IsActive,
+			&user.Skills,
+			&user.PathGlobs,
+			&user.Email,
+			&user.DigestOptOut,
+			&user.Role,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan team member: %w", err)
@@ -57,7 +57,12 @@ func setupTestDB(t *testing.T) *pgxpool.Pool {
 			user_id TEXT PRIMARY KEY,
 			username TEXT NOT NULL,
 			team_name TEXT NOT NULL REFERENCES teams(name) ON DELETE CASCADE,
-			is_active BOOLEAN NOT NULL DEFAULT true
+			is_active BOOLEAN NOT NULL DEFAULT true,
+			skills TEXT[] NOT NULL DEFAULT '{}',
+			path_globs TEXT[] NOT NULL DEFAULT '{}',
+			email TEXT NOT NULL DEFAULT '',
+			digest_opt_out BOOLEAN NOT NULL DEFAULT false,
+			role TEXT NOT NULL DEFAULT 'member'
 		);
 
 		CREATE INDEX IF NOT EXISTS idx_users_team ON users(team_name);
@@ -279,3 +284,41 @@ func TestTeamPostgresStorage_Transaction_Rollback(t *testing.T) {
 	_, err = storage.GetTeamInfoTx(ctx, tx, "rollback_test")
 	assert.ErrorIs(t, err, models.ErrNotFound)
 }
+
+func TestTeamPostgresStorage_MoveTeamMember(t *testing.T) {
+	pool := setupTestDB(t)
+	storage := NewTeamPostgresStorage(pool)
+	ctx := context.Background()
+
+	err := storage.CreateTeamTx(ctx, nil, models.Team{
+		TeamName: "backend",
+		Members: []models.User{
+			{UserID: "u1", Username: "Alice", TeamName: "backend", IsActive: true},
+		},
+	})
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "UPDATE users SET role = 'lead' WHERE user_id = 'u1'")
+	require.NoError(t, err)
+
+	err = storage.AddTeamMemberTx(ctx, nil, models.User{UserID: "u2", Username: "Bob", TeamName: "backend", IsActive: true})
+	require.NoError(t, err)
+
+	moved, err := storage.MoveTeamMemberTx(ctx, nil, "u1", "frontend", "unassigned")
+	require.NoError(t, err)
+	assert.False(t, moved)
+
+	moved, err = storage.MoveTeamMemberTx(ctx, nil, "u1", "backend", "unassigned")
+	require.NoError(t, err)
+	assert.True(t, moved)
+
+	team, err := storage.GetTeamInfoTx(ctx, nil, "unassigned")
+	require.NoError(t, err)
+	require.Len(t, team.Members, 1)
+	assert.Equal(t, "u1", team.Members[0].UserID)
+	assert.Equal(t, models.RoleMember, team.Members[0].Role)
+
+	team, err = storage.GetTeamInfoTx(ctx, nil, "backend")
+	require.NoError(t, err)
+	require.Len(t, team.Members, 1)
+	assert.Equal(t, "u2", team.Members[0].UserID)
+}
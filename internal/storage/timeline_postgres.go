@@ -0,0 +1,86 @@
+package storage
+
+/*
+Основные функции:
+	1. Добавление событий в хронологию PR (в транзакции изменения)
+	2. Хронология PR от старых к новым
+
+В отличие от assigned_reviewers, который перезаписывается целиком,
+здесь остаётся каждое назначение и снятие ревьюера с причиной.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+	"test-task/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TimelinePostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewTimelinePostgresStorage(pool *pgxpool.Pool) *TimelinePostgresStorage {
+	return &TimelinePostgresStorage{pool: pool}
+}
+
+func (s *TimelinePostgresStorage) AddTimelineEventsTx(ctx context.Context, tx pgx.Tx, events []models.TimelineEvent) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO pr_timeline (pull_request_id, event, user_id, reason, actor)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	batch := &pgx.Batch{}
+	for _, e := range events {
+		batch.Queue(query, e.PullRequestID, e.Event, e.UserID, e.Reason, e.Actor)
+	}
+	if err := sendWrites(ctx, tx, s.pool, batch); err != nil {
+		return fmt.Errorf("failed to add timeline events: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TimelinePostgresStorage) ListTimelineTx(ctx context.Context, tx pgx.Tx, prID string) ([]models.TimelineEvent, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT id, pull_request_id, event, user_id, reason, actor, created_at
+		FROM pr_timeline
+		WHERE pull_request_id = $1
+		ORDER BY id
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, prID)
+	} else {
+		rows, err = s.pool.Query(ctx, query, prID)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query PR timeline: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.TimelineEvent
+	for rows.Next() {
+		var e models.TimelineEvent
+		if err := rows.Scan(&e.ID, &e.PullRequestID, &e.Event, &e.UserID, &e.Reason, &e.Actor, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan timeline event: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PR timeline: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+/*
+Спаны на каждый запрос pgx (pgx.QueryTracer) и пакет запросов (pgx.BatchTracer).
+Текст запроса пишется как есть - значения идут параметрами и в спан не попадают.
+
+Упавшие запросы пишутся в лог запроса уровнем DEBUG - с request_id видно,
+какой SQL стоит за 5xx.
+*/
+
+import (
+	"context"
+	"strings"
+
+	"test-task/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "test-task/internal/storage"
+
+type queryTracer struct{}
+
+type querySQLKey struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := sqlOperation(data.SQL)
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, "db "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", op),
+			attribute.String("db.query.text", strings.TrimSpace(data.SQL)),
+		))
+	return context.WithValue(ctx, querySQLKey{}, data.SQL)
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if data.Err != nil {
+		sql, _ := ctx.Value(querySQLKey{}).(string)
+		logging.FromContext(ctx).Debug("Query failed", "sql", strings.Join(strings.Fields(sql), " "), "error", data.Err)
+	}
+	endDBSpan(trace.SpanFromContext(ctx), data.CommandTag.RowsAffected(), data.Err)
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, "db batch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.Int("db.operation.batch.size", data.Batch.Len()),
+		))
+	return ctx
+}
+
+func (queryTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if data.Err != nil {
+		trace.SpanFromContext(ctx).AddEvent("query failed", trace.WithAttributes(
+			attribute.String("db.query.text", strings.TrimSpace(data.SQL)),
+			attribute.String("error", data.Err.Error()),
+		))
+	}
+}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	endDBSpan(trace.SpanFromContext(ctx), -1, data.Err)
+}
+
+func endDBSpan(span trace.Span, rowsAffected int64, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if rowsAffected >= 0 {
+		span.SetAttributes(attribute.Int64("db.response.rows_affected", rowsAffected))
+	}
+	span.End()
+}
+
+// sqlOperation - первое слово запроса: SELECT, INSERT, BEGIN...
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
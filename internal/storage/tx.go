@@ -0,0 +1,190 @@
+package storage
+
+/*
+Общая транзакция на несколько вызовов сервисов (POST /batch, режим atomic):
+WithTx кладёт транзакцию в контекст, и XBeginTx внутри такого контекста
+открывают в ней savepoint вместо новой транзакции. Commit сервиса снимает
+savepoint, Rollback откатывает только свою часть - судьбу всего решает
+владелец внешней транзакции.
+
+Вызовы с tx == nil внутри такого контекста тоже выполняются в общей
+транзакции (ambientTx), иначе чтения сервиса не видели бы ещё не
+закоммиченные изменения предыдущих операций пакета. Вне WithTx nil - pool.
+
+WithWriteBatch откладывает записи без результата (CreatePRTx, AddAuditTx,
+AddTimelineEventsTx, AddEventTx) до FlushWrites, и они уходят в базу одним
+пакетом. Ошибки таких записей (в т.ч. unique violation) всплывают только
+на FlushWrites; читать записанное до сброса нельзя. Вложенный WithWriteBatch
+(createPR внутри atomic-пакета) пишет во внешний пакет, и его FlushWrites
+ничего не делает - сбрасывает владелец внешнего.
+
+Транзакции верхнего уровня считаются в db_transactions_total: commit,
+rollback и conflict - COMMIT не прошёл из-за serialization failure или
+deadlock. Конфликт на промежуточном запросе сервис откатывает сам, и он
+попадает в rollback. Повторяют конфликтующие транзакции сервисы (IsConflict).
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test-task/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ambientTxKey struct{}
+
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, ambientTxKey{}, tx)
+}
+
+type writeBatchKey struct{}
+
+type outerWriteBatchKey struct{}
+
+func WithWriteBatch(ctx context.Context) context.Context {
+	if InWriteBatch(ctx) {
+		return context.WithValue(ctx, outerWriteBatchKey{}, true)
+	}
+	return context.WithValue(ctx, writeBatchKey{}, &pgx.Batch{})
+}
+
+// InWriteBatch - записи без результата сейчас откладываются
+func InWriteBatch(ctx context.Context) bool {
+	_, ok := ctx.Value(writeBatchKey{}).(*pgx.Batch)
+	return ok
+}
+
+// FlushWrites отправляет отложенные записи одним обращением к базе
+func FlushWrites(ctx context.Context, tx pgx.Tx) error {
+	if outer, _ := ctx.Value(outerWriteBatchKey{}).(bool); outer {
+		return nil
+	}
+	batch, ok := ctx.Value(writeBatchKey{}).(*pgx.Batch)
+	if !ok || batch.Len() == 0 {
+		return nil
+	}
+	queued := *batch
+	*batch = pgx.Batch{}
+
+	tx = ambientTx(ctx, tx)
+	if tx == nil {
+		return errors.New("deferred writes must be flushed inside a transaction")
+	}
+	if err := tx.SendBatch(ctx, &queued).Close(); err != nil {
+		return fmt.Errorf("failed to flush writes: %w", err)
+	}
+	return nil
+}
+
+// execWrite выполняет запрос или откладывает его, если в ctx открыт WithWriteBatch
+func execWrite(ctx context.Context, tx pgx.Tx, pool *pgxpool.Pool, query string, args ...any) error {
+	if batch, ok := ctx.Value(writeBatchKey{}).(*pgx.Batch); ok {
+		batch.Queue(query, args...)
+		return nil
+	}
+
+	var err error
+	if tx != nil {
+		_, err = tx.Exec(ctx, query, args...)
+	} else {
+		_, err = pool.Exec(ctx, query, args...)
+	}
+	return err
+}
+
+// sendWrites - sendBatch, который тоже откладывается при WithWriteBatch
+func sendWrites(ctx context.Context, tx pgx.Tx, pool *pgxpool.Pool, batch *pgx.Batch) error {
+	if deferred, ok := ctx.Value(writeBatchKey{}).(*pgx.Batch); ok {
+		deferred.QueuedQueries = append(deferred.QueuedQueries, batch.QueuedQueries...)
+		return nil
+	}
+	return sendBatch(ctx, tx, pool, batch)
+}
+
+// InTx - вызов идёт внутри общей транзакции WithTx
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(ambientTxKey{}).(pgx.Tx)
+	return ok
+}
+
+// ambientTx - tx, а при nil - общая транзакция из ctx, если она есть
+func ambientTx(ctx context.Context, tx pgx.Tx) pgx.Tx {
+	if tx != nil {
+		return tx
+	}
+	tx, _ = ctx.Value(ambientTxKey{}).(pgx.Tx)
+	return tx
+}
+
+func beginTx(ctx context.Context, pool *pgxpool.Pool, opts pgx.TxOptions) (pgx.Tx, error) {
+	if tx, ok := ctx.Value(ambientTxKey{}).(pgx.Tx); ok {
+		nested, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin savepoint: %w", err)
+		}
+		return nested, nil
+	}
+
+	tx, err := pool.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &meteredTx{Tx: tx}, nil
+}
+
+// meteredTx считает исход транзакции; savepoint-ы через Begin не оборачиваются
+type meteredTx struct {
+	pgx.Tx
+	finished bool
+}
+
+func (t *meteredTx) Commit(ctx context.Context) error {
+	err := t.Tx.Commit(ctx)
+	if t.finished {
+		return err
+	}
+	t.finished = true
+
+	switch {
+	case err == nil:
+		metrics.DBTransactions.WithLabelValues("commit").Inc()
+	case IsConflict(err):
+		metrics.DBTransactions.WithLabelValues("conflict").Inc()
+	default:
+		metrics.DBTransactions.WithLabelValues("rollback").Inc()
+	}
+	return err
+}
+
+func (t *meteredTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	if !t.finished {
+		t.finished = true
+		metrics.DBTransactions.WithLabelValues("rollback").Inc()
+	}
+	return err
+}
+
+// IsConflict - serialization failure или deadlock: транзакцию можно повторить
+func IsConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// sendBatch отправляет пакет запросов одним обращением к базе
+func sendBatch(ctx context.Context, tx pgx.Tx, pool *pgxpool.Pool, batch *pgx.Batch) error {
+	var results pgx.BatchResults
+	if tx != nil {
+		results = tx.SendBatch(ctx, batch)
+	} else {
+		results = pool.SendBatch(ctx, batch)
+	}
+	return results.Close()
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTx struct {
+	pgx.Tx
+	name string
+}
+
+func TestAmbientTx(t *testing.T) {
+	ctx := context.Background()
+	outer := &stubTx{name: "outer"}
+	own := &stubTx{name: "own"}
+
+	assert.Nil(t, ambientTx(ctx, nil), "without WithTx nil stays nil - pool")
+	assert.Equal(t, own, ambientTx(ctx, own))
+
+	ctx = WithTx(ctx, outer)
+	assert.Equal(t, outer, ambientTx(ctx, nil))
+	assert.Equal(t, own, ambientTx(ctx, own), "explicit tx wins")
+}
+
+type stubBatchResults struct {
+	pgx.BatchResults
+}
+
+func (stubBatchResults) Close() error { return nil }
+
+type batchingTx struct {
+	pgx.Tx
+	sent []*pgx.Batch
+}
+
+func (tx *batchingTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	tx.sent = append(tx.sent, b)
+	return stubBatchResults{}
+}
+
+func TestWriteBatch(t *testing.T) {
+	tx := &batchingTx{}
+	ctx := WithWriteBatch(context.Background())
+
+	require.NoError(t, execWrite(ctx, tx, nil, "INSERT 1"))
+	batch := &pgx.Batch{}
+	batch.Queue("INSERT 2")
+	batch.Queue("INSERT 3")
+	require.NoError(t, sendWrites(ctx, tx, nil, batch))
+	assert.Empty(t, tx.sent, "writes wait for FlushWrites")
+
+	require.NoError(t, FlushWrites(ctx, tx))
+	require.Len(t, tx.sent, 1)
+	assert.Equal(t, 3, tx.sent[0].Len())
+
+	require.NoError(t, FlushWrites(ctx, tx))
+	assert.Len(t, tx.sent, 1, "nothing left to flush")
+}
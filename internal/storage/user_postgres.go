@@ -4,7 +4,8 @@ package storage
 Основные фукнции:
 	1. Получение данных о юзере по индексу
 	2. Обновление активности юзера
-	3. Создать транзакцию
+	3. Отказ от email-дайджеста и список получателей дайджеста
+	4. Создать транзакцию
 
 Фича - если Tx - nil, то используем просто pool
 */
@@ -12,7 +13,9 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strings"
 	"test-task/internal/models"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -28,19 +31,16 @@ func NewUserPostgresStorage(pool *pgxpool.Pool) *UserPostgresStorage {
 }
 
 func (s *UserPostgresStorage) UserBeginTx(ctx context.Context) (pgx.Tx, error) {
-	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{
+	return beginTx(ctx, s.pool, pgx.TxOptions{
 		IsoLevel:   pgx.Serializable,
 		AccessMode: pgx.ReadWrite,
 	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	return tx, nil
 }
 
 func (s *UserPostgresStorage) GetUserTx(ctx context.Context, tx pgx.Tx, userID string) (*models.User, error) {
+	tx = ambientTx(ctx, tx)
 	query := `
-		SELECT user_id, username, team_name, is_active
+		SELECT user_id, username, team_name, is_active, skills, path_globs, email, digest_opt_out, role
 		FROM users 
 		WHERE user_id = $1
 	`
@@ -58,6 +58,11 @@ func (s *UserPostgresStorage) GetUserTx(ctx context.Context, tx pgx.Tx, userID s
 		&user.Username,
 		&user.TeamName,
 		&user.IsActive,
+		&user.Skills,
+		&user.PathGlobs,
+		&user.Email,
+		&user.DigestOptOut,
+		&user.Role,
 	)
 
 	if err != nil {
@@ -71,6 +76,7 @@ func (s *UserPostgresStorage) GetUserTx(ctx context.Context, tx pgx.Tx, userID s
 }
 
 func (s *UserPostgresStorage) UpdateUserActiveTx(ctx context.Context, tx pgx.Tx, userID string, isActive bool) error {
+	tx = ambientTx(ctx, tx)
 	query := `
 		UPDATE users 
 		SET is_active = $1
@@ -96,3 +102,195 @@ func (s *UserPostgresStorage) UpdateUserActiveTx(ctx context.Context, tx pgx.Tx,
 
 	return nil
 }
+
+func (s *UserPostgresStorage) UpdateUserDigestOptOutTx(ctx context.Context, tx pgx.Tx, userID string, optOut bool) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE users 
+		SET digest_opt_out = $1
+		WHERE user_id = $2
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, optOut, userID)
+	} else {
+		result, err = s.pool.Exec(ctx, query, optOut, userID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *UserPostgresStorage) UpdateUserRoleTx(ctx context.Context, tx pgx.Tx, userID string, role string) error {
+	tx = ambientTx(ctx, tx)
+	query := `
+		UPDATE users 
+		SET role = $1
+		WHERE user_id = $2
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, role, userID)
+	} else {
+		result, err = s.pool.Exec(ctx, query, role, userID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListDigestRecipientsTx - активные пользователи с email, не отказавшиеся от дайджеста
+func (s *UserPostgresStorage) ListDigestRecipientsTx(ctx context.Context, tx pgx.Tx) ([]models.User, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		SELECT user_id, username, team_name, is_active, email
+		FROM users
+		WHERE is_active AND NOT digest_opt_out AND email <> ''
+		ORDER BY user_id
+	`
+
+	var rows pgx.Rows
+	var err error
+
+	if tx != nil {
+		rows, err = tx.Query(ctx, query)
+	} else {
+		rows, err = s.pool.Query(ctx, query)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, &user.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// ClaimDigestRunTx - true, если рассылку за этот день ещё никто не начинал
+func (s *UserPostgresStorage) ClaimDigestRunTx(ctx context.Context, tx pgx.Tx, day time.Time) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO digest_runs (run_date) VALUES ($1)
+		ON CONFLICT (run_date) DO NOTHING
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, day)
+	} else {
+		result, err = s.pool.Exec(ctx, query, day)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest run: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// ListUsersPageTx - keyset-страница пользователей, как ListPRsPageTx
+func (s *UserPostgresStorage) ListUsersPageTx(ctx context.Context, tx pgx.Tx, q models.UserListQuery) ([]models.User, error) {
+	tx = ambientTx(ctx, tx)
+	cmp, direction := ">", "ASC"
+	if q.Order == models.SortOrderDesc {
+		cmp, direction = "<", "DESC"
+	}
+
+	var conds []string
+	var args []interface{}
+	if q.TeamName != "" {
+		args = append(args, q.TeamName)
+		conds = append(conds, fmt.Sprintf("team_name = $%d", len(args)))
+	}
+	if q.IsActive != nil {
+		args = append(args, *q.IsActive)
+		conds = append(conds, fmt.Sprintf("is_active = $%d", len(args)))
+	}
+
+	orderBy := fmt.Sprintf("user_id %s", direction)
+	if q.Sort == models.UserSortUsername {
+		orderBy = fmt.Sprintf("username %s, user_id %s", direction, direction)
+	}
+	if q.After != nil {
+		if q.Sort == models.UserSortUsername {
+			args = append(args, q.After.Key, q.After.ID)
+			conds = append(conds, fmt.Sprintf("(username, user_id) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+		} else {
+			args = append(args, q.After.ID)
+			conds = append(conds, fmt.Sprintf("user_id %s $%d", cmp, len(args)))
+		}
+	}
+
+	query := `
+		SELECT user_id, username, team_name, is_active, skills, path_globs, email, digest_opt_out, role
+		FROM users
+	`
+	if len(conds) > 0 {
+		query += "WHERE " + strings.Join(conds, " AND ") + "\n"
+	}
+	args = append(args, q.Limit)
+	query += fmt.Sprintf("ORDER BY %s LIMIT $%d", orderBy, len(args))
+
+	var rows pgx.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.Query(ctx, query, args...)
+	} else {
+		rows, err = s.pool.Query(ctx, query, args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		err := rows.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive, &u.Skills, &u.PathGlobs, &u.Email, &u.DigestOptOut, &u.Role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
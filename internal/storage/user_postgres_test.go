@@ -74,7 +74,12 @@ func createTestTables(ctx context.Context, pool *pgxpool.Pool) error {
 			user_id VARCHAR(50) PRIMARY KEY,
 			username VARCHAR(100) NOT NULL,
 			team_name VARCHAR(100) NOT NULL,
-			is_active BOOLEAN NOT NULL DEFAULT true
+			is_active BOOLEAN NOT NULL DEFAULT true,
+			skills TEXT[] NOT NULL DEFAULT '{}',
+			path_globs TEXT[] NOT NULL DEFAULT '{}',
+			email TEXT NOT NULL DEFAULT '',
+			digest_opt_out BOOLEAN NOT NULL DEFAULT false,
+			role TEXT NOT NULL DEFAULT 'member'
 		);
 
 		INSERT INTO users (user_id, username, team_name, is_active) VALUES
@@ -154,3 +159,46 @@ func TestNewUserPostgresStorage(t *testing.T) {
 	assert.NotNil(t, storage)
 	assert.Equal(t, pool, storage.pool)
 }
+
+// Keyset по username: одинаковые имена различает user_id, курсор не
+// пропускает и не повторяет строки на границе страниц
+func TestUserPostgresStorage_ListUsersPage(t *testing.T) {
+	pool := setupTestDatabase(t)
+	storage := NewUserPostgresStorage(pool)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO users (user_id, username, team_name, is_active) VALUES
+			('user4', 'jane_smith', 'Team Alpha', true),
+			('user5', 'jane_smith', 'Team Alpha', true)
+	`)
+	require.NoError(t, err)
+
+	walk := func(q models.UserListQuery) []string {
+		var ids []string
+		for page := 0; page < 10; page++ {
+			users, err := storage.ListUsersPageTx(ctx, nil, q)
+			require.NoError(t, err)
+			for _, u := range users {
+				ids = append(ids, u.UserID)
+			}
+			if len(users) < q.Limit {
+				return ids
+			}
+			last := users[len(users)-1]
+			q.After = &models.UserCursor{Key: last.Username, ID: last.UserID}
+		}
+		t.Fatal("pagination does not terminate")
+		return nil
+	}
+
+	assert.Equal(t, []string{"user1", "user2", "user3", "user4", "user5"}, walk(models.UserListQuery{Limit: 2}))
+	assert.Equal(t, []string{"user5", "user4", "user3", "user2", "user1"}, walk(models.UserListQuery{Limit: 2, Order: models.SortOrderDesc}))
+
+	// bob_wilson, jane_smith x3 (user2 < user4 < user5), john_doe
+	assert.Equal(t, []string{"user3", "user2", "user4", "user5", "user1"}, walk(models.UserListQuery{Limit: 2, Sort: models.UserSortUsername}))
+	assert.Equal(t, []string{"user1", "user5", "user4", "user2", "user3"}, walk(models.UserListQuery{Limit: 1, Sort: models.UserSortUsername, Order: models.SortOrderDesc}))
+
+	active := true
+	assert.Equal(t, []string{"user1", "user4", "user5"}, walk(models.UserListQuery{Limit: 2, TeamName: "Team Alpha", IsActive: &active}))
+}
@@ -0,0 +1,62 @@
+package storage
+
+/*
+Основные функции:
+	1. Транзакция обработки доставки (serializable, как у PR - изменения PR
+	   идут в ней же)
+	2. Захват delivery id входящего вебхука (идемпотентность)
+
+Захват - это INSERT ... ON CONFLICT DO NOTHING: повторная доставка того же
+события от того же источника вернёт false и не будет обработана второй раз.
+Снимать захват не нужно - при неудаче он откатывается вместе с транзакцией.
+
+Фича - если Tx - nil, то используем просто pool
+*/
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookPostgresStorage(pool *pgxpool.Pool) *WebhookPostgresStorage {
+	return &WebhookPostgresStorage{pool: pool}
+}
+
+func (s *WebhookPostgresStorage) DeliveryBeginTx(ctx context.Context) (pgx.Tx, error) {
+	return beginTx(ctx, s.pool, pgx.TxOptions{
+		IsoLevel:   pgx.Serializable,
+		AccessMode: pgx.ReadWrite,
+	})
+}
+
+func (s *WebhookPostgresStorage) ClaimDeliveryTx(ctx context.Context, tx pgx.Tx, source string, deliveryID string) (bool, error) {
+	tx = ambientTx(ctx, tx)
+	query := `
+		INSERT INTO webhook_deliveries (source, delivery_id)
+		VALUES ($1, $2)
+		ON CONFLICT (source, delivery_id) DO NOTHING
+	`
+
+	var result pgconn.CommandTag
+	var err error
+
+	if tx != nil {
+		result, err = tx.Exec(ctx, query, source, deliveryID)
+	} else {
+		result, err = s.pool.Exec(ctx, query, source, deliveryID)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
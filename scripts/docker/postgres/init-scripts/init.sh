@@ -5,6 +5,8 @@ until pg_isready -U "$POSTGRES_USER" -d "$POSTGRES_DB"; do
 done
 
 psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
     CREATE TABLE IF NOT EXISTS teams (
         name TEXT PRIMARY KEY
     );
@@ -13,7 +15,12 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
         user_id TEXT PRIMARY KEY,
         username TEXT NOT NULL,
         team_name TEXT NOT NULL REFERENCES teams(name) ON DELETE CASCADE,
-        is_active BOOLEAN NOT NULL DEFAULT true
+        is_active BOOLEAN NOT NULL DEFAULT true,
+        skills TEXT[] NOT NULL DEFAULT '{}',
+        path_globs TEXT[] NOT NULL DEFAULT '{}',
+        email TEXT NOT NULL DEFAULT '',
+        digest_opt_out BOOLEAN NOT NULL DEFAULT false,
+        role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'lead', 'admin'))
     );
 
 
@@ -21,14 +28,196 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
         pull_request_id TEXT PRIMARY KEY,
         pull_request_name TEXT NOT NULL,
         author_id TEXT NOT NULL REFERENCES users(user_id),
-        status TEXT NOT NULL CHECK (status IN ('OPEN', 'MERGED')),
+        status TEXT NOT NULL CHECK (status IN ('OPEN', 'MERGED', 'CLOSED')),
         assigned_reviewers TEXT[] NOT NULL DEFAULT '{}',
+        project TEXT NOT NULL DEFAULT '',
+        tags TEXT[] NOT NULL DEFAULT '{}',
+        changed_files TEXT[] NOT NULL DEFAULT '{}',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        merged_at TIMESTAMPTZ,
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        stale_at TIMESTAMPTZ,
+        closed_at TIMESTAMPTZ,
+        sla_due_at TIMESTAMPTZ,
+        sla_breached BOOLEAN NOT NULL DEFAULT false
+    );
+
+    CREATE TABLE IF NOT EXISTS team_codeowners (
+        team_name TEXT PRIMARY KEY REFERENCES teams(name) ON DELETE CASCADE,
+        content TEXT NOT NULL,
+        rules JSONB NOT NULL DEFAULT '[]',
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS webhook_deliveries (
+        source TEXT NOT NULL,
+        delivery_id TEXT NOT NULL,
+        received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (source, delivery_id)
+    );
+
+    CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+        id BIGSERIAL PRIMARY KEY,
+        url TEXT NOT NULL,
+        secret TEXT NOT NULL,
+        events TEXT[] NOT NULL DEFAULT '{}',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS webhook_outbound_deliveries (
+        id BIGSERIAL PRIMARY KEY,
+        subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+        event_id TEXT NOT NULL,
+        event_type TEXT NOT NULL,
+        payload JSONB NOT NULL,
+        status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'DELIVERED', 'DEAD')),
+        attempts INT NOT NULL DEFAULT 0,
+        last_status_code INT,
+        last_error TEXT NOT NULL DEFAULT '',
+        next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        delivered_at TIMESTAMPTZ,
+        UNIQUE (subscription_id, event_id)
+    );
+
+    CREATE TABLE IF NOT EXISTS outbox (
+        id BIGSERIAL PRIMARY KEY,
+        event_id TEXT NOT NULL UNIQUE,
+        event_type TEXT NOT NULL,
+        payload JSONB NOT NULL,
+        occurred_at TIMESTAMPTZ NOT NULL,
+        delivered_at TIMESTAMPTZ
+    );
+
+    CREATE TABLE IF NOT EXISTS outbox_deliveries (
+        outbox_id BIGINT NOT NULL REFERENCES outbox(id) ON DELETE CASCADE,
+        sink TEXT NOT NULL,
+        attempts INT NOT NULL DEFAULT 0,
+        last_error TEXT NOT NULL DEFAULT '',
+        available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        delivered_at TIMESTAMPTZ,
+        dead_at TIMESTAMPTZ,
+        PRIMARY KEY (outbox_id, sink)
+    );
+
+    CREATE TABLE IF NOT EXISTS chat_targets (
+        scope TEXT NOT NULL CHECK (scope IN ('user', 'team')),
+        target_id TEXT NOT NULL,
+        webhook_url TEXT NOT NULL,
+        format TEXT NOT NULL CHECK (format IN ('slack', 'mattermost')),
+        PRIMARY KEY (scope, target_id)
+    );
+
+    CREATE TABLE IF NOT EXISTS digest_runs (
+        run_date DATE PRIMARY KEY,
+        started_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS team_sla (
+        team_name TEXT PRIMARY KEY REFERENCES teams(name) ON DELETE CASCADE,
+        response_hours INT NOT NULL CHECK (response_hours > 0),
+        policy TEXT NOT NULL CHECK (policy IN ('notify', 'reassign')),
+        work_start TEXT NOT NULL DEFAULT '',
+        work_end TEXT NOT NULL DEFAULT '',
+        timezone TEXT NOT NULL DEFAULT 'UTC',
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS team_stale_policy (
+        team_name TEXT PRIMARY KEY REFERENCES teams(name) ON DELETE CASCADE,
+        stale_after_days INT NOT NULL CHECK (stale_after_days > 0),
+        close_after_days INT NOT NULL DEFAULT 0 CHECK (close_after_days >= 0),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS sla_escalations (
+        id BIGSERIAL PRIMARY KEY,
+        pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+        team_name TEXT NOT NULL,
+        action TEXT NOT NULL,
+        reviewer_id TEXT NOT NULL DEFAULT '',
+        new_reviewer_id TEXT NOT NULL DEFAULT '',
+        due_at TIMESTAMPTZ NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS audit_log (
+        id BIGSERIAL PRIMARY KEY,
+        entity TEXT NOT NULL CHECK (entity IN ('team', 'user', 'pr')),
+        entity_id TEXT NOT NULL,
+        action TEXT NOT NULL,
+        actor TEXT NOT NULL DEFAULT '',
+        request_id TEXT NOT NULL DEFAULT '',
+        before JSONB,
+        after JSONB,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS api_tokens (
+        id BIGSERIAL PRIMARY KEY,
+        token_hash TEXT NOT NULL UNIQUE,
+        name TEXT NOT NULL,
+        principal_type TEXT NOT NULL CHECK (principal_type IN ('user', 'service')),
+        principal_id TEXT NOT NULL,
+        scopes TEXT[] NOT NULL DEFAULT '{}',
         created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-        merged_at TIMESTAMPTZ
+        revoked_at TIMESTAMPTZ
     );
 
-    CREATE INDEX IF NOT EXISTS idx_users_team ON users(team_name);
+    CREATE TABLE IF NOT EXISTS pr_timeline (
+        id BIGSERIAL PRIMARY KEY,
+        pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+        event TEXT NOT NULL,
+        user_id TEXT NOT NULL DEFAULT '',
+        reason TEXT NOT NULL DEFAULT '',
+        actor TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+
+    CREATE TABLE IF NOT EXISTS idempotency_keys (
+        scope TEXT NOT NULL,
+        idem_key TEXT NOT NULL,
+        fingerprint TEXT NOT NULL,
+        status_code INT,
+        content_type TEXT NOT NULL DEFAULT '',
+        response BYTEA,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        expires_at TIMESTAMPTZ NOT NULL,
+        PRIMARY KEY (scope, idem_key)
+    );
+
+    CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS \$\$
+    BEGIN
+        RAISE EXCEPTION 'audit_log is append-only';
+    END;
+    \$\$ LANGUAGE plpgsql;
+
+    DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+    CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+        FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+    CREATE INDEX IF NOT EXISTS idx_users_team_user ON users(team_name, user_id);
+    CREATE INDEX IF NOT EXISTS idx_users_username ON users(username, user_id);
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_created ON pull_requests(created_at, pull_request_id);
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_updated ON pull_requests(updated_at, pull_request_id);
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_author ON pull_requests(author_id, created_at, pull_request_id);
     CREATE INDEX IF NOT EXISTS idx_pull_requests_reviewers ON pull_requests USING GIN(assigned_reviewers);
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_name_fts ON pull_requests USING GIN(to_tsvector('simple', pull_request_name));
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_name_trgm ON pull_requests USING GIN(pull_request_name gin_trgm_ops);
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_id_trgm ON pull_requests USING GIN(pull_request_id gin_trgm_ops);
+    CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN(username gin_trgm_ops);
+    CREATE INDEX IF NOT EXISTS idx_users_id_trgm ON users USING GIN(user_id gin_trgm_ops);
+    CREATE INDEX IF NOT EXISTS idx_teams_name_trgm ON teams USING GIN(name gin_trgm_ops);
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_sla_due ON pull_requests(sla_due_at) WHERE status = 'OPEN' AND NOT sla_breached;
+    CREATE INDEX IF NOT EXISTS idx_pull_requests_open_updated ON pull_requests(updated_at) WHERE status = 'OPEN';
+    CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id, id DESC);
+    CREATE INDEX IF NOT EXISTS idx_pr_timeline_pr ON pr_timeline(pull_request_id, id);
+    CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+    CREATE INDEX IF NOT EXISTS idx_sla_escalations_team ON sla_escalations(team_name, id);
+    CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE delivered_at IS NULL;
+    CREATE INDEX IF NOT EXISTS idx_outbox_deliveries_due ON outbox_deliveries(available_at) WHERE delivered_at IS NULL AND dead_at IS NULL;
+    CREATE INDEX IF NOT EXISTS idx_outbox_deliveries_dead ON outbox_deliveries(dead_at) WHERE dead_at IS NOT NULL;
+    CREATE INDEX IF NOT EXISTS idx_outbound_deliveries_due ON webhook_outbound_deliveries(next_attempt_at) WHERE status = 'PENDING';
 
     GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO "$POSTGRES_USER";
     GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public TO "$POSTGRES_USER";
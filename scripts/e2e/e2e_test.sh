@@ -2,9 +2,10 @@
 echo "=== E2E TESTING PR REVIEWER SERVICE ==="
 
 BASE_URL="http://localhost:8080"
+AUTH="Authorization: Bearer ${API_TOKEN:-$AUTH_BOOTSTRAP_TOKEN}"
 
 echo -e "\n1. CREATING TEAMS..."
-curl -X POST $BASE_URL/team/add \
+curl -H "$AUTH" -X POST $BASE_URL/team/add \
   -H "Content-Type: application/json" \
   -d '{
     "team_name": "backend",
@@ -15,7 +16,7 @@ curl -X POST $BASE_URL/team/add \
     ]
   }' && echo -e "\n---"
 
-curl -X POST $BASE_URL/team/add \
+curl -H "$AUTH" -X POST $BASE_URL/team/add \
   -H "Content-Type: application/json" \
   -d '{
     "team_name": "frontend", 
@@ -26,11 +27,11 @@ curl -X POST $BASE_URL/team/add \
   }' && echo -e "\n---"
 
 echo -e "\n2. CHECKING TEAMS..."
-curl -X GET "$BASE_URL/team/get?team_name=backend" && echo -e "\n---"
-curl -X GET "$BASE_URL/team/get?team_name=frontend" && echo -e "\n---"
+curl -H "$AUTH" -X GET "$BASE_URL/team/get?team_name=backend" && echo -e "\n---"
+curl -H "$AUTH" -X GET "$BASE_URL/team/get?team_name=frontend" && echo -e "\n---"
 
 echo -e "\n3. CREATING PR..."
-curl -X POST $BASE_URL/pullRequest/create \
+curl -H "$AUTH" -X POST $BASE_URL/pullRequest/create \
   -H "Content-Type: application/json" \
   -d '{
     "pull_request_id": "pr-1001",
@@ -39,16 +40,16 @@ curl -X POST $BASE_URL/pullRequest/create \
   }' && echo -e "\n---"
 
 echo -e "\n4. CHECKING ASSIGNED REVIEWERS..."
-curl -X GET "$BASE_URL/users/getReview?user_id=u2" && echo -e "\n---"
-curl -X GET "$BASE_URL/users/getReview?user_id=u3" && echo -e "\n---"
+curl -H "$AUTH" -X GET "$BASE_URL/users/getReview?user_id=u2" && echo -e "\n---"
+curl -H "$AUTH" -X GET "$BASE_URL/users/getReview?user_id=u3" && echo -e "\n---"
 
 echo -e "\n5. DEACTIVATING USER..."
-curl -X POST $BASE_URL/users/setIsActive \
+curl -H "$AUTH" -X POST $BASE_URL/users/setIsActive \
   -H "Content-Type: application/json" \
   -d '{"user_id": "u2", "is_active": false}' && echo -e "\n---"
 
 echo -e "\n6. REASSIGNING REVIEWER..."
-curl -X POST $BASE_URL/pullRequest/reassign \
+curl -H "$AUTH" -X POST $BASE_URL/pullRequest/reassign \
   -H "Content-Type: application/json" \
   -d '{
     "pull_request_id": "pr-1001",
@@ -56,12 +57,12 @@ curl -X POST $BASE_URL/pullRequest/reassign \
   }' && echo -e "\n---"
 
 echo -e "\n7. MERGING PR..."
-curl -X POST $BASE_URL/pullRequest/merge \
+curl -H "$AUTH" -X POST $BASE_URL/pullRequest/merge \
   -H "Content-Type: application/json" \
   -d '{"pull_request_id": "pr-1001"}' && echo -e "\n---"
 
 echo -e "\n8. TRYING TO MODIFY MERGED PR (SHOULD FAIL)..."
-curl -X POST $BASE_URL/pullRequest/reassign \
+curl -H "$AUTH" -X POST $BASE_URL/pullRequest/reassign \
   -H "Content-Type: application/json" \
   -d '{
     "pull_request_id": "pr-1001",
@@ -69,6 +70,6 @@ curl -X POST $BASE_URL/pullRequest/reassign \
   }' && echo -e "\n---"
 
 echo -e "\n9. FINAL CHECK..."
-curl -X GET "$BASE_URL/users/getReview?user_id=u3" && echo -e "\n---"
+curl -H "$AUTH" -X GET "$BASE_URL/users/getReview?user_id=u3" && echo -e "\n---"
 
 echo "=== E2E TESTING COMPLETED ==="
\ No newline at end of file